@@ -0,0 +1,41 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+// NullFields selects the null-valued struct fields that are hashed as though they were absent.
+type NullFields int
+
+const (
+	// NoNulls hashes null-valued fields as the Ion Hash specification defines.
+	NoNulls NullFields = iota
+
+	// UntypedNulls treats fields whose value is null, or null.null, as absent, but not those whose value
+	// is a typed null, e.g. null.string.
+	UntypedNulls
+
+	// AllNulls treats fields whose value is any null, typed or not, as absent.
+	AllNulls
+)
+
+// AbsentFields selects the struct fields that are hashed as though they were absent.
+type AbsentFields struct {
+	// Nulls selects the null-valued fields that are absent.
+	Nulls NullFields
+
+	// EmptyContainers treats fields whose value is an empty list, sexp or struct as absent. A container
+	// is empty if none of its values are hashed, so a struct whose fields are all absent is empty too.
+	EmptyContainers bool
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+import (
+	"sort"
+
+	"github.com/amzn/ion-go/ion"
+)
+
+// annotationFilter is a hashValue whose annotations are filtered, and optionally sorted, before they
+// are hashed. Since serializers only see the filtered annotations, they apply to scalars and
+// containers alike. It is reused between values so that filtering doesn't allocate once
+// annotations has grown to fit them.
+type annotationFilter struct {
+	hashValue
+
+	// ignoreAll drops every annotation. Otherwise, if allowed is not nil, only the annotations in it
+	// are kept, and the annotations in ignored are dropped.
+	ignoreAll bool
+	allowed   map[string]bool
+	ignored   map[string]bool
+
+	// unordered sorts the annotations, so that their order doesn't affect the hash.
+	unordered bool
+
+	annotations []ion.SymbolToken
+}
+
+func newAnnotationFilter(opts options) *annotationFilter {
+	if !opts.ignoreAllAnnotations && opts.allowedAnnotations == nil && len(opts.ignoredAnnotations) == 0 &&
+		!opts.unorderedAnnotations {
+		return nil
+	}
+
+	af := &annotationFilter{ignoreAll: opts.ignoreAllAnnotations, unordered: opts.unorderedAnnotations}
+	if opts.allowedAnnotations != nil {
+		af.allowed = stringSet(opts.allowedAnnotations)
+	}
+	if len(opts.ignoredAnnotations) > 0 {
+		af.ignored = stringSet(opts.ignoredAnnotations)
+	}
+
+	return af
+}
+
+func (af *annotationFilter) getAnnotations() ([]ion.SymbolToken, error) {
+	annotations, err := af.hashValue.getAnnotations()
+	if err != nil || len(annotations) == 0 {
+		return annotations, err
+	}
+
+	if af.ignoreAll {
+		return nil, nil
+	}
+
+	af.annotations = af.annotations[:0]
+	for _, annotation := range annotations {
+		if af.keeps(annotation) {
+			af.annotations = append(af.annotations, annotation)
+		}
+	}
+
+	if af.unordered {
+		sort.Slice(af.annotations, func(i, j int) bool {
+			return lessSymbolToken(&af.annotations[i], &af.annotations[j])
+		})
+	}
+
+	return af.annotations, nil
+}
+
+// keeps reports whether an annotation is hashed.
+func (af *annotationFilter) keeps(annotation ion.SymbolToken) bool {
+	if annotation.Text == nil {
+		// An annotation with unknown text can't be allowed by name.
+		return af.allowed == nil
+	}
+
+	if af.allowed != nil && !af.allowed[*annotation.Text] {
+		return false
+	}
+
+	return !af.ignored[*annotation.Text]
+}
+
+// lessSymbolToken orders symbol tokens by their text, followed by those with unknown text
+// ordered by their symbol IDs.
+func lessSymbolToken(a, b *ion.SymbolToken) bool {
+	switch {
+	case a.Text != nil && b.Text != nil:
+		return *a.Text < *b.Text
+	case a.Text != nil:
+		return true
+	case b.Text != nil:
+		return false
+	}
+
+	return a.LocalSID < b.LocalSID
+}
+
+// stringSet returns a set of the given strings.
+func stringSet(strs []string) map[string]bool {
+	set := make(map[string]bool, len(strs))
+	for _, str := range strs {
+		set[str] = true
+	}
+
+	return set
+}
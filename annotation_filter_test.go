@@ -0,0 +1,60 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+import (
+	"testing"
+
+	"github.com/amzn/ion-go/ion"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnnotationFilter(t *testing.T) {
+	b, c := ion.NewSymbolTokenFromString("b"), ion.NewSymbolTokenFromString("c")
+	unknown10 := ion.SymbolToken{LocalSID: 10}
+	unknown2 := ion.SymbolToken{LocalSID: 2}
+	annotations := []ion.SymbolToken{unknown10, c, unknown2, b}
+
+	testCases := []struct {
+		name     string
+		opts     []Option
+		expected []ion.SymbolToken
+	}{
+		{"none", nil, annotations},
+		{"without", []Option{WithoutAnnotations()}, nil},
+		{"allowed", []Option{WithAllowedAnnotations("b")}, []ion.SymbolToken{b}},
+		{"ignored", []Option{WithIgnoredAnnotations("b")}, []ion.SymbolToken{unknown10, c, unknown2}},
+		{"unordered", []Option{WithUnorderedAnnotations()}, []ion.SymbolToken{b, c, unknown2, unknown10}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			af := newAnnotationFilter(newOptions(tc.opts))
+			if tc.opts == nil {
+				assert.Nil(t, af, "Expected no annotation filter without options")
+				return
+			}
+
+			af.hashValue = &testScalar{ionType: ion.IntType, ionValue: int64(1),
+				annotations: append([]ion.SymbolToken{}, annotations...)}
+
+			filtered, err := af.getAnnotations()
+			require.NoError(t, err, "Something went wrong executing af.getAnnotations()")
+			assert.Equal(t, tc.expected, filtered)
+		})
+	}
+}
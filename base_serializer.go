@@ -15,21 +15,19 @@
 
 package ionhash
 
-import (
-	"bytes"
-	"encoding/binary"
-	"fmt"
-	"math"
-	"math/big"
-
-	"github.com/amzn/ion-go/ion"
-)
+import "github.com/amzn/ion-go/ion"
 
 // baseSerializer holds the commonalities between scalar and struct serializers.
 type baseSerializer struct {
 	hashFunction           IonHasher
 	depth                  int
 	hasContainerAnnotation bool
+	limiter                *limiter
+
+	// buf and escaped are reused between writes so that serializing a scalar
+	// doesn't allocate once they have grown to fit it.
+	buf     []byte
+	escaped []byte
 }
 
 func (bs *baseSerializer) stepOut() error {
@@ -67,7 +65,7 @@ func (bs *baseSerializer) stepIn(ionValue hashValue) error {
 		tq = tq | 0x0F
 	}
 
-	err = bs.write([]byte{tq})
+	err = bs.writeByte(tq)
 	if err != nil {
 		return err
 	}
@@ -103,14 +101,17 @@ func (bs *baseSerializer) write(bytes []byte) error {
 	return err
 }
 
+func (bs *baseSerializer) writeByte(b byte) error {
+	bs.buf = append(bs.buf[:0], b)
+	return bs.write(bs.buf)
+}
+
 func (bs *baseSerializer) beginMarker() error {
-	_, err := bs.hashFunction.Write([]byte{beginMarkerByte})
-	return err
+	return bs.writeByte(beginMarkerByte)
 }
 
 func (bs *baseSerializer) endMarker() error {
-	_, err := bs.hashFunction.Write([]byte{endMarkerByte})
-	return err
+	return bs.writeByte(endMarkerByte)
 }
 
 func (bs *baseSerializer) handleAnnotationsBegin(ionValue hashValue, isContainer bool) error {
@@ -129,13 +130,13 @@ func (bs *baseSerializer) handleAnnotationsBegin(ionValue hashValue, isContainer
 			return err
 		}
 
-		err = bs.write([]byte{tqValue})
+		err = bs.writeByte(tqValue)
 		if err != nil {
 			return err
 		}
 
-		for _, annotation := range annotations {
-			err = bs.writeSymbolAsToken(&annotation)
+		for i := range annotations {
+			err = bs.writeSymbolAsToken(&annotations[i])
 			if err != nil {
 				return err
 			}
@@ -184,140 +185,56 @@ func (bs *baseSerializer) writeSymbol(val string) error {
 }
 
 func (bs *baseSerializer) writeSymbolAsToken(symbol *ion.SymbolToken) error {
+	return bs.writeScalar(ion.SymbolType, symbol, false)
+}
+
+// writeScalar writes a scalar value, which is its type qualifier and escaped
+// representation surrounded by begin and end markers.
+func (bs *baseSerializer) writeScalar(ionType ion.Type, ionValue interface{}, isNull bool) error {
 	err := bs.beginMarker()
 	if err != nil {
 		return err
 	}
 
-	scalarBytes, err := bs.getBytes(ion.SymbolType, *symbol, false)
+	// The type qualifier goes in the first byte of the buffer, followed by the representation.
+	tq, buf, err := appendScalar(append(bs.buf[:0], 0), ionType, ionValue, isNull)
 	if err != nil {
 		return err
 	}
 
-	tq, representation, err := bs.scalarOrNullSplitParts(ion.SymbolType, symbol, false, scalarBytes)
+	bs.buf = buf
+	bs.buf[0] = tq
+
+	err = bs.limiter.addValueBytes(len(bs.buf) - 1)
 	if err != nil {
 		return err
 	}
 
-	err = bs.write([]byte{tq})
+	err = bs.write(bs.buf[:1])
 	if err != nil {
 		return err
 	}
 
-	if len(representation) > 0 {
-		err = bs.write(escape(representation))
+	if len(bs.buf) > 1 {
+		err = bs.writeEscaped(bs.buf[1:])
 		if err != nil {
 			return err
 		}
 	}
 
-	err = bs.endMarker()
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func (bs *baseSerializer) getBytes(ionType ion.Type, ionValue interface{}, isNull bool) ([]byte, error) {
-	if isNull {
-		var typeCode byte
-		if ionType <= ion.IntType {
-			// The Ion binary encodings of NoType, NullType, BoolType, and IntType
-			// differ from their enum values by one.
-			typeCode = byte(ionType - 1)
-		} else {
-			typeCode = byte(ionType)
-		}
-
-		return []byte{(typeCode << 4) | 0x0F}, nil
-	} else if ionType == ion.FloatType && ionValue == 0 && int64(ionValue.(float64)) >= 0 {
-		// Value is 0.0, not -0.0.
-		return []byte{0x40}, nil
-	}
-
-	buf := bytes.Buffer{}
-	writer := ion.NewBinaryWriter(&buf)
-
-	err := serializers(ionType, ionValue, writer)
-	if err != nil {
-		return nil, err
-	}
-
-	err = writer.Finish()
-	if err != nil {
-		return nil, err
-	}
-
-	b := buf.Bytes()[4:]
-
-	if ionType == ion.FloatType && len(b) == 5 {
-		// As per the ion-hash spec (https://amzn.github.io/ion-hash/docs/spec.html#4-float),
-		// Floats are to be encoded in 64 bits (8 bytes) but we got back a 32 bit (4 byte) float.
-		// Let's create the data for the equivalent float64 instead.
-		float32bits := binary.BigEndian.Uint32(b[1:])
-		newFloat64 := float64(math.Float32frombits(float32bits))
-		float64Bits := math.Float64bits(newFloat64)
-
-		b = make([]byte, 9)
-		b[0] = 0x48
-
-		binary.BigEndian.PutUint64(b[1:], float64Bits)
-	}
-
-	return b, nil
-}
-
-func (bs *baseSerializer) getLengthFieldLength(bytes []byte) (int, error) {
-	if (bytes[0] & 0x0F) == 0x0E {
-		// Read subsequent byte(s) as the "length" field.
-		for i := 1; i < len(bytes); i++ {
-			if (bytes[i] & 0x80) != 0 {
-				return i, nil
-			}
-		}
-
-		return 0, fmt.Errorf("problem while reading VarUInt")
-	}
-
-	return 0, nil
+	return bs.endMarker()
 }
 
-func (bs *baseSerializer) scalarOrNullSplitParts(
-	ionType ion.Type, symbol *ion.SymbolToken, isNull bool, bytes []byte) (byte, []byte, error) {
-
-	offset, err := bs.getLengthFieldLength(bytes)
-	if err != nil {
-		return byte(0), nil, err
-	}
-	offset++
-
-	if ionType == ion.IntType && len(bytes) > offset {
-		// Ignore sign byte when the magnitude ends at byte boundary.
-		if (bytes[offset] & 0xFF) == 0 {
-			offset++
-		}
-	}
-
-	// The representation is everything after TL (first byte) and length.
-	representation := bytes[offset:]
-	tq := bytes[0]
-
-	if ionType == ion.SymbolType {
-		// Symbols are serialized as strings; use the correct TQ:
-		tq = 0x70
-		if isNull {
-			tq = tq | 0x0F
-		} else if symbol != nil && symbol.Text == nil && symbol.LocalSID == 0 {
-			tq = 0x71
+// writeEscaped writes bytes, escaping any that collide with the marker bytes.
+func (bs *baseSerializer) writeEscaped(bytes []byte) error {
+	for _, b := range bytes {
+		if needsEscape(b) {
+			bs.escaped = appendEscaped(bs.escaped[:0], bytes)
+			return bs.write(bs.escaped)
 		}
-	} else if ionType != ion.BoolType && (tq&0x0F) != 0x0F {
-		// Not a symbol, bool, or null value.
-		// Zero - out the L nibble.
-		tq = tq & 0xF0
 	}
 
-	return tq, representation, nil
+	return bs.write(bytes)
 }
 
 func needsEscape(b byte) bool {
@@ -329,161 +246,17 @@ func needsEscape(b byte) bool {
 	return false
 }
 
-func escape(bytes []byte) []byte {
-	if bytes == nil {
-		return nil
-	}
-
-	for i := 0; i < len(bytes); i++ {
-		b := bytes[i]
+// appendEscaped appends bytes to dst, preceding every marker byte with the escape byte.
+func appendEscaped(dst, bytes []byte) []byte {
+	for _, b := range bytes {
 		if needsEscape(b) {
-			// Found a byte that needs to be escaped; build a new byte array that
-			// escapes that byte as well as any others.
-			var escapedBytes []byte
-
-			for j := 0; j < len(bytes); j++ {
-				c := bytes[j]
-				if needsEscape(c) {
-					escapedBytes = append(escapedBytes, escapeByte)
-				}
-
-				escapedBytes = append(escapedBytes, c)
-			}
-
-			return escapedBytes
-		}
-	}
-
-	return bytes
-}
-
-func serializers(ionType ion.Type, ionValue interface{}, writer ion.Writer) error {
-	switch ionType {
-	case ion.BoolType:
-		if ionBool, ok := ionValue.(bool); ok {
-			return writer.WriteBool(ionBool)
-		}
-
-		if ionBool, ok := ionValue.(*bool); ok {
-			return writer.WriteBool(*ionBool)
-		}
-
-		return &InvalidArgumentError{"ionValue", ionValue}
-	case ion.BlobType:
-		return writer.WriteBlob(ionValue.([]byte))
-	case ion.ClobType:
-		return writer.WriteClob(ionValue.([]byte))
-	case ion.DecimalType:
-		return writer.WriteDecimal(ionValue.(*ion.Decimal))
-	case ion.FloatType:
-		if ionFloat, ok := ionValue.(float64); ok {
-			return writer.WriteFloat(ionFloat)
-		}
-
-		if ionFloat, ok := ionValue.(*float64); ok {
-			return writer.WriteFloat(*ionFloat)
-		}
-
-		if ionFloat, ok := ionValue.(float32); ok {
-			return writer.WriteFloat(float64(ionFloat))
-		}
-
-		if ionFloat, ok := ionValue.(*float32); ok {
-			return writer.WriteFloat(float64(*ionFloat))
-		}
-
-		return &InvalidArgumentError{"ionValue", ionValue}
-	case ion.IntType:
-		if ionValInt, ok := ionValue.(int); ok {
-			return writer.WriteInt(int64(ionValInt))
-		}
-
-		if ionValInt, ok := ionValue.(*int); ok {
-			return writer.WriteInt(int64(*ionValInt))
-		}
-
-		if ionValInt64, ok := ionValue.(int64); ok {
-			return writer.WriteInt(ionValInt64)
-		}
-
-		if ionValInt64, ok := ionValue.(*int64); ok {
-			return writer.WriteInt(*ionValInt64)
-		}
-
-		if ionValUint64, ok := ionValue.(uint64); ok {
-			return writer.WriteUint(ionValUint64)
-		}
-
-		if ionValUint64, ok := ionValue.(*uint64); ok {
-			return writer.WriteUint(*ionValUint64)
-		}
-
-		if ionValInt32, ok := ionValue.(int32); ok {
-			return writer.WriteInt(int64(ionValInt32))
+			dst = append(dst, escapeByte)
 		}
 
-		if ionValInt32, ok := ionValue.(*int32); ok {
-			return writer.WriteInt(int64(*ionValInt32))
-		}
-
-		if ionValUint32, ok := ionValue.(uint32); ok {
-			return writer.WriteUint(uint64(ionValUint32))
-		}
-
-		if ionValUint32, ok := ionValue.(*uint32); ok {
-			return writer.WriteUint(uint64(*ionValUint32))
-		}
-
-		if ionValBigInt, ok := ionValue.(*big.Int); ok {
-			return writer.WriteBigInt(ionValBigInt)
-		}
-
-		return &InvalidArgumentError{"ionValue", ionValue}
-	case ion.StringType:
-		if ionValueStr, ok := ionValue.(string); ok {
-			return writer.WriteString(ionValueStr)
-		}
-
-		if ionValueStr, ok := ionValue.(*string); ok {
-			return writer.WriteString(*ionValueStr)
-		}
-
-		return &InvalidArgumentError{"ionValue", ionValue}
-	case ion.SymbolType:
-		if ionValueSymbol, ok := ionValue.(ion.SymbolToken); ok {
-			if ionValueSymbol.Text != nil {
-				return writer.WriteString(*ionValueSymbol.Text)
-			}
-			return writer.WriteString("")
-		}
-
-		if ionValueSymbol, ok := ionValue.(*ion.SymbolToken); ok {
-			if ionValueSymbol.Text != nil {
-				return writer.WriteString(*ionValueSymbol.Text)
-			}
-			return writer.WriteString("")
-		}
-
-		if ionValueStr, ok := ionValue.(string); ok {
-			return writer.WriteString(ionValueStr)
-		}
-
-		return &InvalidArgumentError{"ionValue", ionValue}
-	case ion.TimestampType:
-		if ionTimestamp, ok := ionValue.(ion.Timestamp); ok {
-			return writer.WriteTimestamp(ionTimestamp)
-		}
-
-		if ionTimestamp, ok := ionValue.(*ion.Timestamp); ok {
-			return writer.WriteTimestamp(*ionTimestamp)
-		}
-
-		return &InvalidArgumentError{"ionValue", ionValue}
-	case ion.NullType:
-		return writer.WriteNull()
+		dst = append(dst, b)
 	}
 
-	return &InvalidIonTypeError{ionType}
+	return dst
 }
 
 func typeQualifier(ionValue hashValue) byte {
@@ -0,0 +1,1120 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+import (
+	"bytes"
+	"math"
+	"time"
+	"unicode/utf8"
+
+	"github.com/amzn/ion-go/ion"
+)
+
+// A BinaryHashReader calculates the hashes of the top-level values in an Ion 1.0 binary stream.
+//
+// Unlike a HashReader, a BinaryHashReader does not decode scalars into Go values. It walks the
+// binary encoding directly and hashes the bytes of each scalar's representation as they appear
+// in the input, resolving symbol IDs using the stream's local symbol tables. The resulting
+// digests are identical to those of a HashReader reading the same stream, e.g.,
+//
+//	hr, err := NewBinaryHashReader(data, NewCryptoHasherProvider(SHA256))
+//	if err != nil {
+//	    return err
+//	}
+//	for hr.Next() {
+//	    digest, err := hr.Sum(nil)
+//	    if err != nil {
+//	        return err
+//	    }
+//	    fmt.Printf("%x\n", digest)
+//	}
+//	if err := hr.Err(); err != nil {
+//	    return err
+//	}
+type BinaryHashReader interface {
+	// Next hashes the next top-level value in the stream, including any values nested within it.
+	// It returns false at the end of the stream or on error, in which case Err returns the error.
+	Next() bool
+
+	// Err returns an error if a previous call to Next failed.
+	Err() error
+
+	// Sum appends the current hash to b and returns the resulting slice.
+	// It resets the Hash to its initial state.
+	Sum(b []byte) ([]byte, error)
+
+	// KeyDigests returns the key digest of the same value as Sum for each KeySpec given with
+	// WithKeyDigests, in the same order, or nil if none were given.
+	KeyDigests() ([][]byte, error)
+
+	// SubtreeDigests returns the digests of the values within the same value as Sum whose paths
+	// match those given with WithSubtrees, in the order the values begin, or nil if there are none.
+	SubtreeDigests() ([]SubtreeDigest, error)
+
+	// Metadata returns a description of the settings that the digests depend on.
+	Metadata() DigestMetadata
+}
+
+// ivm is the Ion 1.0 binary version marker.
+var ivm = []byte{0xE0, 0x01, 0x00, 0xEA}
+
+// Binary type codes (the T nibble of a type descriptor) of the types that need special handling.
+const (
+	binaryTypeNull       = 0x0
+	binaryTypeBool       = 0x1
+	binaryTypeNegInt     = 0x3
+	binaryTypeStruct     = 0xD
+	binaryTypeAnnotation = 0xE
+
+	binaryLengthVarUint = 0x0E
+	binaryLengthNull    = 0x0F
+
+	// maxCachedSymbolID bounds the symbol IDs whose tokens are cached; tokens for larger
+	// IDs are created on each use rather than growing the cache without limit.
+	maxCachedSymbolID = 1 << 16
+
+	// symbolTableSID is the ID of the $ion_symbol_table system symbol.
+	symbolTableSID = 3
+)
+
+// binaryIonTypes maps a binary type code to the corresponding Ion type.
+var binaryIonTypes = [...]ion.Type{
+	ion.NullType, ion.BoolType, ion.IntType, ion.IntType, ion.FloatType, ion.DecimalType, ion.TimestampType,
+	ion.SymbolType, ion.StringType, ion.ClobType, ion.BlobType, ion.ListType, ion.SexpType, ion.StructType,
+}
+
+type binaryContainer struct {
+	end      int
+	isStruct bool
+}
+
+type binaryHashReader struct {
+	data   []byte
+	pos    int
+	hasher hasher
+	err    error
+
+	symbolTable ion.SymbolTable
+	symbols     []*ion.SymbolToken
+	containers  []binaryContainer
+
+	// The value currently being hashed.
+	currentType ion.Type
+	isNull      bool
+	tag         byte
+	valueOffset int
+	start       int
+	end         int
+	fieldName   *ion.SymbolToken
+	annotations []ion.SymbolToken
+	encoded     encodedScalar
+
+	// scratch holds the representations of scalars that are re-encoded rather than hashed
+	// as they appear in the data, and it is reused between them.
+	scratch []byte
+}
+
+// encodedScalar is a scalar whose representation has already been computed, and it is
+// understood by appendScalar as a value of any scalar type.
+type encodedScalar struct {
+	tq             byte
+	representation []byte
+}
+
+// NewBinaryHashReader takes Ion binary data, a hash provider and any options and returns a new
+// BinaryHashReader. The data must begin with the Ion 1.0 binary version marker unless it is empty.
+func NewBinaryHashReader(data []byte, hasherProvider IonHasherProvider, opts ...Option) (BinaryHashReader, error) {
+	if len(data) > 0 && !bytes.HasPrefix(data, ivm) {
+		return nil, &InvalidArgumentError{"data", "data that does not begin with an Ion 1.0 binary version marker"}
+	}
+
+	hashOptions := newOptions(opts)
+	if len(hashOptions.transformers) > 0 {
+		return nil, &InvalidArgumentError{"opts", "WithTransformer, which a BinaryHashReader does not support"}
+	}
+
+	newHasher, err := newHasher(hasherProvider, hashOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return &binaryHashReader{data: data, hasher: *newHasher, symbolTable: ion.V1SystemSymbolTable}, nil
+}
+
+// HashBinary returns the hash of each top-level value in the given Ion binary data.
+func HashBinary(data []byte, hasherProvider IonHasherProvider, opts ...Option) ([][]byte, error) {
+	hr, err := NewBinaryHashReader(data, hasherProvider, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var digests [][]byte
+	for hr.Next() {
+		digest, err := hr.Sum(nil)
+		if err != nil {
+			return nil, err
+		}
+
+		digests = append(digests, digest)
+	}
+
+	if hr.Err() != nil {
+		return nil, hr.Err()
+	}
+
+	return digests, nil
+}
+
+// Next hashes the next top-level value in the stream.
+func (br *binaryHashReader) Next() bool {
+	if br.err != nil {
+		return false
+	}
+
+	for br.pos < len(br.data) {
+		hashed, err := br.hashTopLevelValue()
+		if err != nil {
+			br.err = err
+			return false
+		}
+
+		if hashed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Err returns an error if a previous call to Next failed.
+func (br *binaryHashReader) Err() error {
+	return br.err
+}
+
+// Sum appends the current hash to b and returns the resulting slice.
+// It resets the Hash to its initial state.
+func (br *binaryHashReader) Sum(b []byte) ([]byte, error) {
+	return br.hasher.sum(b)
+}
+
+// KeyDigests returns the key digests of the same value as Sum.
+func (br *binaryHashReader) KeyDigests() ([][]byte, error) {
+	return br.hasher.keyDigests()
+}
+
+// SubtreeDigests returns the subtree digests of the same value as Sum.
+func (br *binaryHashReader) SubtreeDigests() ([]SubtreeDigest, error) {
+	return br.hasher.subtreeDigests()
+}
+
+// Metadata returns a description of the settings that the digests depend on.
+func (br *binaryHashReader) Metadata() DigestMetadata {
+	return br.hasher.metadata()
+}
+
+// hashTopLevelValue consumes the next item at the top level of the stream and hashes it. It
+// returns false if the item was not a user value, i.e. it was a version marker, a local symbol
+// table or padding.
+func (br *binaryHashReader) hashTopLevelValue() (bool, error) {
+	for {
+		if len(br.containers) > 0 {
+			container := br.containers[len(br.containers)-1]
+			if br.pos == container.end {
+				br.containers = br.containers[:len(br.containers)-1]
+
+				err := br.hasher.stepOut()
+				if err != nil {
+					return false, err
+				}
+
+				if len(br.containers) == 0 {
+					return true, nil
+				}
+
+				continue
+			}
+		}
+
+		isValue, err := br.readHeader()
+		if err != nil {
+			return false, err
+		}
+
+		if !isValue {
+			if len(br.containers) == 0 {
+				return false, nil
+			}
+
+			continue
+		}
+
+		if len(br.containers) == 0 && br.currentType == ion.StructType && br.isSymbolTable() {
+			return false, br.readSymbolTable()
+		}
+
+		if ion.IsContainer(br.currentType) && !br.isNull {
+			err = br.hasher.stepIn(br)
+			if err != nil {
+				return false, err
+			}
+
+			br.containers = append(br.containers, binaryContainer{br.end, br.currentType == ion.StructType})
+			br.pos = br.start
+
+			continue
+		}
+
+		err = br.encodeScalar()
+		if err != nil {
+			return false, err
+		}
+
+		err = br.hasher.scalar(br)
+		if err != nil {
+			return false, err
+		}
+
+		br.pos = br.end
+		if len(br.containers) == 0 {
+			return true, nil
+		}
+	}
+}
+
+// readHeader reads the field name, annotations and type descriptor of the next value,
+// leaving the reader positioned at its representation. It returns false if the next item
+// is not a value, in which case it has been skipped.
+func (br *binaryHashReader) readHeader() (bool, error) {
+	limit := br.limit()
+
+	br.fieldName = nil
+	br.annotations = br.annotations[:0]
+
+	if br.IsInStruct() {
+		sid, err := br.readVarUint(limit)
+		if err != nil {
+			return false, err
+		}
+
+		br.fieldName, err = br.symbolToken(sid)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	br.valueOffset = br.pos
+	tagOffset := br.pos
+	tag, length, err := br.readTypeDescriptor(limit)
+	if err != nil {
+		return false, err
+	}
+
+	if tag>>4 == binaryTypeAnnotation {
+		if length == 0 {
+			return false, br.readVersionMarker(tagOffset)
+		}
+
+		err = br.readAnnotations(length)
+		if err != nil {
+			return false, err
+		}
+
+		tagOffset = br.pos
+		tag, length, err = br.readTypeDescriptor(limit)
+		if err != nil {
+			return false, err
+		}
+
+		if tag>>4 == binaryTypeAnnotation {
+			return false, br.malformed(tagOffset, "an annotation wrapper cannot wrap another annotation wrapper")
+		}
+		if tag>>4 == binaryTypeNull && tag&0x0F != binaryLengthNull {
+			return false, br.malformed(tagOffset, "an annotation wrapper cannot wrap padding")
+		}
+	}
+
+	if tag>>4 == binaryTypeNull && tag&0x0F != binaryLengthNull {
+		// Skip over NOP padding, along with any field name that preceded it.
+		br.pos += length
+		return false, nil
+	}
+
+	br.tag = tag
+	br.currentType = binaryIonTypes[tag>>4]
+	br.isNull = tag&0x0F == binaryLengthNull
+	br.start = br.pos
+	br.end = br.pos + length
+	return true, nil
+}
+
+// readTypeDescriptor reads a type descriptor and any length that follows it, returning the
+// type descriptor and the length of the representation that follows.
+func (br *binaryHashReader) readTypeDescriptor(limit int) (byte, int, error) {
+	offset := br.pos
+	if br.pos >= limit {
+		return 0, 0, br.malformed(offset, "unexpected end of data")
+	}
+
+	tag := br.data[br.pos]
+	br.pos++
+
+	typeCode := tag >> 4
+	lengthCode := tag & 0x0F
+
+	if typeCode == 0xF {
+		return 0, 0, br.malformed(offset, "invalid type descriptor")
+	}
+
+	if lengthCode == binaryLengthNull {
+		if typeCode == binaryTypeAnnotation {
+			return 0, 0, br.malformed(offset, "an annotation wrapper cannot be null")
+		}
+
+		return tag, 0, nil
+	}
+
+	switch typeCode {
+	case binaryTypeBool:
+		if lengthCode > 1 {
+			return 0, 0, br.malformed(offset, "invalid bool type descriptor")
+		}
+
+		// The L nibble of a bool is its value, not its length.
+		return tag, 0, nil
+	case binaryTypeAnnotation:
+		if lengthCode == 0 {
+			// A binary version marker, which readVersionMarker will validate.
+			return tag, 0, nil
+		}
+	case binaryTypeStruct:
+		if lengthCode == 1 {
+			// A struct whose fields are sorted by symbol ID; its length always follows.
+			length, err := br.readLength(limit)
+			if err != nil {
+				return 0, 0, err
+			}
+
+			if length == 0 {
+				return 0, 0, br.malformed(offset, "a struct with sorted fields cannot be empty")
+			}
+
+			return tag, length, nil
+		}
+	}
+
+	if lengthCode == binaryLengthVarUint {
+		length, err := br.readLength(limit)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		return tag, length, nil
+	}
+
+	if int(lengthCode) > limit-br.pos {
+		return 0, 0, br.malformed(offset, "value overruns its container")
+	}
+
+	return tag, int(lengthCode), nil
+}
+
+// readLength reads a VarUInt length and checks that the value it measures fits within limit.
+func (br *binaryHashReader) readLength(limit int) (int, error) {
+	offset := br.pos
+	length, err := br.readVarUint(limit)
+	if err != nil {
+		return 0, err
+	}
+
+	if length > uint64(limit-br.pos) {
+		return 0, br.malformed(offset, "value overruns its container")
+	}
+
+	return int(length), nil
+}
+
+// readVersionMarker reads the remainder of a binary version marker and resets the symbol table.
+func (br *binaryHashReader) readVersionMarker(offset int) error {
+	if len(br.containers) > 0 {
+		return br.malformed(offset, "a binary version marker cannot appear within a container")
+	}
+
+	if !bytes.HasPrefix(br.data[offset:], ivm) {
+		return br.malformed(offset, "unsupported Ion version or invalid binary version marker")
+	}
+
+	br.pos = offset + len(ivm)
+	br.setSymbolTable(ion.V1SystemSymbolTable)
+
+	return nil
+}
+
+// readAnnotations reads the annotations of an annotation wrapper with the given length, and
+// checks that what remains of the wrapper is exactly one value.
+func (br *binaryHashReader) readAnnotations(length int) error {
+	wrapperEnd := br.pos + length
+	offset := br.pos
+
+	annotationsLength, err := br.readVarUint(wrapperEnd)
+	if err != nil {
+		return err
+	}
+
+	if annotationsLength == 0 {
+		return br.malformed(offset, "an annotation wrapper must have at least one annotation")
+	}
+	if annotationsLength >= uint64(wrapperEnd-br.pos) {
+		return br.malformed(offset, "an annotation wrapper must wrap a value")
+	}
+
+	annotationsEnd := br.pos + int(annotationsLength)
+	for br.pos < annotationsEnd {
+		sid, err := br.readVarUint(annotationsEnd)
+		if err != nil {
+			return err
+		}
+
+		token, err := br.symbolToken(sid)
+		if err != nil {
+			return err
+		}
+
+		br.annotations = append(br.annotations, *token)
+	}
+
+	valueOffset := br.pos
+	_, valueLength, err := br.readTypeDescriptor(wrapperEnd)
+	if err != nil {
+		return err
+	}
+
+	if br.pos+valueLength != wrapperEnd {
+		return br.malformed(valueOffset, "the length of an annotation wrapper does not match the value it wraps")
+	}
+
+	br.pos = valueOffset
+	return nil
+}
+
+// isSymbolTable returns true if the current top-level struct is a local symbol table.
+func (br *binaryHashReader) isSymbolTable() bool {
+	return len(br.annotations) > 0 && br.annotations[0].Text != nil && *br.annotations[0].Text == "$ion_symbol_table"
+}
+
+// readSymbolTable reads the local symbol table at the current position and makes it the
+// symbol table used to resolve the symbol IDs that follow it.
+func (br *binaryHashReader) readSymbolTable() error {
+	if br.isNull {
+		br.pos = br.end
+		br.setSymbolTable(ion.V1SystemSymbolTable)
+		return nil
+	}
+
+	var imports []ion.SharedSymbolTable
+	var symbols []string
+	foundImports, foundSymbols := false, false
+
+	err := br.forEachChild(func() error {
+		if br.fieldName.Text == nil {
+			return br.malformed(br.start, "a local symbol table field name must have known text")
+		}
+
+		switch *br.fieldName.Text {
+		case "imports":
+			if foundImports {
+				return br.malformed(br.start, "a local symbol table cannot have multiple imports fields")
+			}
+
+			foundImports = true
+			return br.readImports(&imports)
+		case "symbols":
+			if foundSymbols {
+				return br.malformed(br.start, "a local symbol table cannot have multiple symbols fields")
+			}
+
+			foundSymbols = true
+			return br.readSymbols(&symbols)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	br.setSymbolTable(ion.NewLocalSymbolTable(imports, symbols))
+
+	return nil
+}
+
+// readImports reads the imports field of a local symbol table. Shared symbol tables are
+// imported without a catalog, so the text of their symbols is unknown.
+func (br *binaryHashReader) readImports(imports *[]ion.SharedSymbolTable) error {
+	if br.currentType == ion.SymbolType && !br.isNull {
+		sid, err := br.readUint()
+		if err != nil {
+			return err
+		}
+
+		if sid == symbolTableSID && br.symbolTable != ion.V1SystemSymbolTable {
+			// Append to the current local symbol table.
+			*imports = append(br.symbolTable.Imports(), ion.NewSharedSymbolTable("", 0, br.symbolTable.Symbols()))
+		}
+
+		return nil
+	}
+
+	if br.currentType != ion.ListType || br.isNull {
+		return nil
+	}
+
+	return br.forEachChild(func() error {
+		if br.currentType != ion.StructType || br.isNull {
+			return nil
+		}
+
+		name, version, maxID := "", -1, int64(-1)
+		err := br.forEachChild(func() error {
+			if br.fieldName.Text == nil {
+				return br.malformed(br.start, "an import field name must have known text")
+			}
+
+			switch *br.fieldName.Text {
+			case "name":
+				if br.currentType == ion.StringType && !br.isNull {
+					name = string(br.data[br.start:br.end])
+				}
+			case "version":
+				if br.currentType == ion.IntType && !br.isNull {
+					val, err := br.readInt()
+					if err != nil {
+						return err
+					}
+
+					version = int(val)
+				}
+			case "max_id":
+				if br.currentType == ion.IntType {
+					if br.isNull {
+						return br.malformed(br.start, "an import max_id cannot be null")
+					}
+
+					val, err := br.readInt()
+					if err != nil {
+						return err
+					}
+
+					maxID = val
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if name == "" || name == "$ion" {
+			return nil
+		}
+
+		if maxID < 0 {
+			return br.malformed(br.start, "an import of "+name+" lacks a valid max_id and there is no catalog to find it in")
+		}
+
+		if version < 1 {
+			version = 1
+		}
+
+		*imports = append(*imports, ion.NewSharedSymbolTable(name, version, nil).Adjust(uint64(maxID)))
+		return nil
+	})
+}
+
+// readSymbols reads the symbols field of a local symbol table. Anything other than a
+// string declares a symbol with empty text.
+func (br *binaryHashReader) readSymbols(symbols *[]string) error {
+	if br.currentType != ion.ListType || br.isNull {
+		return nil
+	}
+
+	return br.forEachChild(func() error {
+		if br.currentType != ion.StringType || br.isNull {
+			*symbols = append(*symbols, "")
+			return nil
+		}
+
+		text := br.data[br.start:br.end]
+		if !utf8.Valid(text) {
+			return br.malformed(br.start, "a string contains invalid UTF-8")
+		}
+
+		*symbols = append(*symbols, string(text))
+		return nil
+	})
+}
+
+// forEachChild steps into the current container and calls fn positioned on each of its
+// children, then steps out. The children are read but not hashed.
+func (br *binaryHashReader) forEachChild(fn func() error) error {
+	end := br.end
+	br.containers = append(br.containers, binaryContainer{end, br.currentType == ion.StructType})
+	br.pos = br.start
+
+	for br.pos < end {
+		isValue, err := br.readHeader()
+		if err != nil {
+			return err
+		}
+
+		if isValue {
+			valueEnd := br.end
+			err = fn()
+			if err != nil {
+				return err
+			}
+
+			br.pos = valueEnd
+		}
+	}
+
+	br.containers = br.containers[:len(br.containers)-1]
+	return nil
+}
+
+// encodeScalar computes the type qualifier and representation of the current scalar.
+func (br *binaryHashReader) encodeScalar() error {
+	if br.isNull {
+		return nil
+	}
+
+	representation := br.data[br.start:br.end]
+	br.encoded.tq = br.tag & 0xF0
+	br.encoded.representation = representation
+
+	switch br.currentType {
+	case ion.BoolType:
+		br.encoded.tq = br.tag
+	case ion.IntType:
+		magnitude := bytes.TrimLeft(representation, "\x00")
+		if len(magnitude) == 0 {
+			if br.tag>>4 == binaryTypeNegInt {
+				return br.malformed(br.start, "an int cannot be negative zero")
+			}
+
+			br.encoded.tq = tqPosInt
+		}
+
+		br.encoded.representation = magnitude
+	case ion.FloatType:
+		return br.encodeFloat(representation)
+	case ion.DecimalType:
+		return br.encodeDecimal(representation)
+	case ion.TimestampType:
+		return br.encodeTimestamp()
+	case ion.SymbolType:
+		return br.encodeSymbol(representation)
+	case ion.StringType:
+		if !utf8.Valid(representation) {
+			return br.malformed(br.start, "a string contains invalid UTF-8")
+		}
+	}
+
+	return nil
+}
+
+// encodeFloat widens a 32-bit float, since floats are hashed as 64-bit floats.
+func (br *binaryHashReader) encodeFloat(representation []byte) error {
+	var val float64
+	switch len(representation) {
+	case 0:
+		val = 0
+	case 4:
+		val = float64(math.Float32frombits(uint32(readUint(representation))))
+	case 8:
+		val = math.Float64frombits(readUint(representation))
+	default:
+		return br.malformed(br.start, "a float must be 0, 4 or 8 bytes long")
+	}
+
+	var err error
+	br.encoded.tq, br.scratch, err = appendFloat(br.scratch[:0], val)
+	br.encoded.representation = br.scratch
+	return err
+}
+
+// encodeDecimal re-encodes a decimal with its exponent and coefficient as short as possible.
+func (br *binaryHashReader) encodeDecimal(representation []byte) error {
+	if len(representation) == 0 {
+		// 0d0, which has an empty representation.
+		return nil
+	}
+
+	br.pos = br.start
+	exponent, _, err := br.readVarInt(br.end)
+	if err != nil {
+		return err
+	}
+
+	if exponent > math.MaxInt32 || exponent < math.MinInt32 {
+		return br.malformed(br.start, "a decimal exponent must fit in 32 bits")
+	}
+
+	coefficient := br.data[br.pos:br.end]
+	negative := len(coefficient) > 0 && coefficient[0]&0x80 != 0
+
+	// Skip the leading zero bytes of the magnitude, ignoring the sign bit.
+	i := 0
+	if len(coefficient) > 0 && coefficient[0]&0x7F == 0 {
+		for i = 1; i < len(coefficient) && coefficient[i] == 0; i++ {
+		}
+	}
+
+	if i == len(coefficient) {
+		// A zero coefficient, which is negative zero when its sign bit is set.
+		switch {
+		case negative:
+			br.scratch = append(appendVarInt(br.scratch[:0], exponent), 0x80)
+		case exponent == 0:
+			br.scratch = br.scratch[:0]
+		default:
+			br.scratch = appendVarInt(br.scratch[:0], exponent)
+		}
+
+		br.encoded.representation = br.scratch
+		return nil
+	}
+
+	b := appendVarInt(br.scratch[:0], exponent)
+	start := len(b)
+
+	first := coefficient[i]
+	if i == 0 {
+		first &= 0x7F
+	} else if first&0x80 != 0 {
+		// The magnitude fills its leading byte, so the sign needs a byte of its own.
+		b = append(b, 0x00)
+	}
+
+	b = append(b, first)
+	b = append(b, coefficient[i+1:]...)
+	if negative {
+		b[start] |= 0x80
+	}
+
+	br.scratch = b
+	br.encoded.representation = b
+	return nil
+}
+
+// encodeTimestamp re-encodes a timestamp with its offset and components as short as possible.
+func (br *binaryHashReader) encodeTimestamp() error {
+	br.pos = br.start
+
+	offset, negative, err := br.readVarInt(br.end)
+	if err != nil {
+		return err
+	}
+
+	components := [6]int{1, 1, 1, 0, 0, 0}
+	precision := ion.TimestampNoPrecision
+	for i := 0; br.pos < br.end && i < 6 && precision < ion.TimestampPrecisionSecond; i++ {
+		val, err := br.readVarUint(br.end)
+		if err != nil {
+			return err
+		}
+
+		if val > math.MaxInt32 {
+			return br.malformed(br.start, "a timestamp component is out of range")
+		}
+
+		components[i] = int(val)
+
+		if i == 3 {
+			// There is no hour precision; an hour must be followed by a minute.
+			if br.pos == br.end {
+				return br.malformed(br.start, "a timestamp with an hour must have a minute")
+			}
+		} else {
+			precision++
+		}
+	}
+
+	fractionDigits, fraction := uint8(0), 0
+	if br.pos < br.end {
+		exponent, _, err := br.readVarInt(br.end)
+		if err != nil {
+			return err
+		}
+
+		coefficient := br.data[br.pos:br.end]
+		if exponent > 0 || exponent < -9 || len(coefficient) > 4 || (len(coefficient) > 0 && coefficient[0]&0x80 != 0) {
+			// Fractions that need rounding or are invalid are left to the Ion reader.
+			return br.decodeTimestamp()
+		}
+
+		fraction = int(readUint(coefficient))
+		if fraction >= int(math.Pow10(int(-exponent))) {
+			return br.decodeTimestamp()
+		}
+
+		if exponent < 0 {
+			fractionDigits = uint8(-exponent)
+			precision = ion.TimestampPrecisionNanosecond
+		}
+	}
+
+	nanoseconds := fraction * int(math.Pow10(9-int(fractionDigits)))
+	date := time.Date(components[0], time.Month(components[1]), components[2],
+		components[3], components[4], components[5], nanoseconds, time.UTC)
+	if date.Year() != components[0] || date.Month() != time.Month(components[1]) || date.Day() != components[2] {
+		return br.malformed(br.start, "a timestamp must be a valid date")
+	}
+
+	// Dates, and times with an offset of negative zero, have an unknown offset.
+	unknownOffset := precision <= ion.TimestampPrecisionDay || (offset == 0 && negative)
+
+	br.scratch = appendTimestampParts(br.scratch[:0], unknownOffset, int(offset), date, precision, fractionDigits, fraction)
+	br.encoded.representation = br.scratch
+	return nil
+}
+
+// decodeTimestamp decodes the current timestamp with an Ion reader and encodes the result.
+func (br *binaryHashReader) decodeTimestamp() error {
+	data := append(append([]byte{}, ivm...), br.data[br.start-br.headerLength():br.end]...)
+
+	reader := ion.NewReaderBytes(data)
+	if !reader.Next() {
+		if reader.Err() != nil {
+			return reader.Err()
+		}
+
+		return br.malformed(br.start, "invalid timestamp")
+	}
+
+	timestamp, err := reader.TimestampValue()
+	if err != nil {
+		return err
+	}
+
+	br.scratch = appendTimestamp(br.scratch[:0], *timestamp)
+	br.encoded.representation = br.scratch
+	return nil
+}
+
+// headerLength returns the length of the current value's type descriptor and length.
+func (br *binaryHashReader) headerLength() int {
+	if br.tag&0x0F == binaryLengthVarUint {
+		return 1 + varUintLength(uint64(br.end-br.start))
+	}
+
+	return 1
+}
+
+// encodeSymbol encodes a symbol ID as the symbol's text.
+func (br *binaryHashReader) encodeSymbol(representation []byte) error {
+	if len(representation) > 8 {
+		return br.malformed(br.start, "a symbol ID must fit in 64 bits")
+	}
+
+	token, err := br.symbolToken(readUint(representation))
+	if err != nil {
+		return err
+	}
+
+	br.encoded.tq, br.scratch, err = appendSymbol(br.scratch[:0], token)
+	br.encoded.representation = br.scratch
+	return err
+}
+
+// readUint reads the current value's representation as a UInt of at most 8 bytes.
+func (br *binaryHashReader) readUint() (uint64, error) {
+	if br.end-br.start > 8 {
+		return 0, br.malformed(br.start, "an int must fit in 64 bits")
+	}
+
+	return readUint(br.data[br.start:br.end]), nil
+}
+
+// readInt reads the current value as an int that fits in 63 bits.
+func (br *binaryHashReader) readInt() (int64, error) {
+	magnitude, err := br.readUint()
+	if err != nil {
+		return 0, err
+	}
+
+	if magnitude > math.MaxInt64 {
+		return 0, br.malformed(br.start, "an int must fit in 64 bits")
+	}
+
+	if br.tag>>4 == binaryTypeNegInt {
+		return -int64(magnitude), nil
+	}
+
+	return int64(magnitude), nil
+}
+
+// readVarUint reads a VarUInt that ends before limit.
+func (br *binaryHashReader) readVarUint(limit int) (uint64, error) {
+	offset := br.pos
+	val := uint64(0)
+
+	for i := 0; i < 10 && br.pos < limit; i++ {
+		c := br.data[br.pos]
+		br.pos++
+
+		val = (val << 7) | uint64(c&0x7F)
+		if c&0x80 != 0 {
+			return val, nil
+		}
+	}
+
+	return 0, br.malformed(offset, "invalid VarUInt")
+}
+
+// readVarInt reads a VarInt that ends before limit, returning its value and whether its sign bit is set.
+func (br *binaryHashReader) readVarInt(limit int) (int64, bool, error) {
+	offset := br.pos
+	if br.pos >= limit {
+		return 0, false, br.malformed(offset, "invalid VarInt")
+	}
+
+	c := br.data[br.pos]
+	br.pos++
+
+	negative := c&0x40 != 0
+	magnitude := uint64(c & 0x3F)
+
+	for i := 1; c&0x80 == 0; i++ {
+		if i == 10 || br.pos >= limit {
+			return 0, false, br.malformed(offset, "invalid VarInt")
+		}
+
+		c = br.data[br.pos]
+		br.pos++
+
+		magnitude = (magnitude << 7) | uint64(c&0x7F)
+	}
+
+	if magnitude > math.MaxInt64 {
+		return 0, false, br.malformed(offset, "a VarInt must fit in 64 bits")
+	}
+
+	if negative {
+		return -int64(magnitude), true, nil
+	}
+
+	return int64(magnitude), false, nil
+}
+
+// symbolToken returns the token for a symbol ID in the current symbol table.
+func (br *binaryHashReader) symbolToken(sid uint64) (*ion.SymbolToken, error) {
+	if sid > br.symbolTable.MaxID() {
+		return nil, &UnknownSymbolError{int64(sid)}
+	}
+
+	if sid < uint64(len(br.symbols)) && br.symbols[sid] != nil {
+		return br.symbols[sid], nil
+	}
+
+	token := &ion.SymbolToken{LocalSID: int64(sid)}
+	if text, ok := br.symbolTable.FindByID(sid); ok {
+		token.Text = &text
+	}
+
+	if sid < maxCachedSymbolID {
+		for uint64(len(br.symbols)) <= sid {
+			br.symbols = append(br.symbols, nil)
+		}
+
+		br.symbols[sid] = token
+	}
+
+	return token, nil
+}
+
+func (br *binaryHashReader) setSymbolTable(symbolTable ion.SymbolTable) {
+	br.symbolTable = symbolTable
+	br.symbols = br.symbols[:0]
+}
+
+// limit returns the offset that the next value must end before.
+func (br *binaryHashReader) limit() int {
+	if len(br.containers) > 0 {
+		return br.containers[len(br.containers)-1].end
+	}
+
+	return len(br.data)
+}
+
+func (br *binaryHashReader) malformed(offset int, message string) error {
+	return &MalformedBinaryError{offset, message}
+}
+
+// The following implements hashValue interface.
+
+func (br *binaryHashReader) getFieldName() (*ion.SymbolToken, error) {
+	return br.fieldName, nil
+}
+
+func (br *binaryHashReader) getAnnotations() ([]ion.SymbolToken, error) {
+	return br.annotations, nil
+}
+
+// IsNull returns true if the current value is an explicit null.
+func (br *binaryHashReader) IsNull() bool {
+	return br.isNull
+}
+
+// Type returns the type of the value currently being hashed.
+func (br *binaryHashReader) Type() ion.Type {
+	return br.currentType
+}
+
+func (br *binaryHashReader) value() (interface{}, error) {
+	return &br.encoded, nil
+}
+
+// offset returns the offset of the value currently being hashed, including its annotations.
+func (br *binaryHashReader) offset() int64 {
+	return int64(br.valueOffset)
+}
+
+// IsInStruct indicates if the value currently being hashed is inside a struct.
+func (br *binaryHashReader) IsInStruct() bool {
+	return len(br.containers) > 0 && br.containers[len(br.containers)-1].isStruct
+}
+
+// readUint reads bytes as a big-endian unsigned integer of at most 8 bytes.
+func readUint(bytes []byte) uint64 {
+	val := uint64(0)
+	for _, b := range bytes {
+		val = (val << 8) | uint64(b)
+	}
+
+	return val
+}
+
+// varUintLength returns the number of bytes needed to encode v as a VarUInt.
+func varUintLength(v uint64) int {
+	length := 1
+	for v >>= 7; v > 0; v >>= 7 {
+		length++
+	}
+
+	return length
+}
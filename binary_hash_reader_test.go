@@ -0,0 +1,305 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/amzn/ion-go/ion"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var binaryHashReaderTestValues = []string{
+	"null", "null.bool", "null.int", "null.float", "null.decimal", "null.timestamp",
+	"null.symbol", "null.string", "null.clob", "null.blob", "null.list", "null.sexp", "null.struct",
+	"true", "false",
+	"0", "1", "-1", "255", "-256", "9223372036854775807", "-9223372036854775808", "123456789012345678901234567890",
+	"0e0", "-0e0", "1.5e0", "-2.25e0", "1e300", "nan", "+inf", "-inf",
+	"0d0", "-0d0", "0d-3", "-0d5", "1.5", "-1.5", "128d0", "-128d-2", "12345678901234567890.123",
+	"2020T", "2020-06T", "2020-06-15", "2020-06-15T10:20Z", "2020-06-15T10:20:30-00:00",
+	"2020-06-15T10:20:30+05:30", "2020-06-15T10:20:30.000Z", "2020-06-15T10:20:30.123456789-08:00",
+	"2020-06-15T10:20:30.5Z", "2020-06-15T10:20:30.1234567891Z",
+	"abc", "'$ion'", "$0", "''", "\"\"", "\"hello\"", "\"\\x0b\\x0c\\x0e\"", "\"\\u00e9\\U0001F600\"",
+	"{{ aGVsbG8= }}", "{{ CwwO }}", "{{ \"clob\" }}",
+	"[]", "()", "{}", "[1, [2, [3]]]", "(a b c)", "{a: 1, b: {c: [d::2, e]}}",
+	"a::b::1", "a::{b: c::[]}", "{a: b::null, c: null.list, d: []}",
+	"{a: 1, a: 1}", "{'': 0}", "$ion_symbol_table", "[$ion_symbol_table::{symbols: [\"x\"]}]",
+}
+
+// toBinary converts Ion text to Ion binary.
+func toBinary(t testing.TB, text string) []byte {
+	buf := bytes.Buffer{}
+	writer := ion.NewBinaryWriter(&buf)
+
+	reader := ion.NewReaderString(text)
+	for reader.Next() {
+		writeToWriters(t, reader, writer)
+	}
+	require.NoError(t, reader.Err(), "Something went wrong executing reader.Next()")
+	require.NoError(t, writer.Finish(), "Something went wrong executing writer.Finish()")
+
+	return buf.Bytes()
+}
+
+// hashReaderSums returns the sum of each top-level value read from data by a HashReader.
+func hashReaderSums(t *testing.T, data []byte, hasherProvider IonHasherProvider) [][]byte {
+	hr, err := NewHashReader(ion.NewReaderBytes(data), hasherProvider)
+	require.NoError(t, err, "Something went wrong executing NewHashReader()")
+
+	var sums [][]byte
+	for i := 0; hr.Next(); i++ {
+		if i > 0 {
+			sum, err := hr.Sum(nil)
+			require.NoError(t, err, "Something went wrong executing hr.Sum()")
+			sums = append(sums, sum)
+		}
+	}
+	require.NoError(t, hr.Err(), "Something went wrong executing hr.Next()")
+
+	sum, err := hr.Sum(nil)
+	require.NoError(t, err, "Something went wrong executing hr.Sum()")
+
+	return append(sums, sum)
+}
+
+func TestBinaryHashReaderMatchesHashReader(t *testing.T) {
+	for _, value := range binaryHashReaderTestValues {
+		t.Run(value, func(t *testing.T) {
+			data := toBinary(t, value)
+
+			tihp := newTestIonHasherProvider("identity")
+			expected := hashReaderSums(t, data, tihp.getInstance())
+			expectedUpdates := tihp.getUpdateHashLog()
+
+			tihp = newTestIonHasherProvider("identity")
+			sums, err := HashBinary(data, tihp.getInstance())
+			require.NoError(t, err, "Something went wrong executing HashBinary()")
+			assert.Equal(t, expected, sums, "sums did not match expectation")
+			assert.Equal(t, expectedUpdates, tihp.getUpdateHashLog(), "hash updates did not match expectation")
+		})
+	}
+}
+
+func TestBinaryHashReaderTopLevelValues(t *testing.T) {
+	data := toBinary(t, strings.Join(binaryHashReaderTestValues, " "))
+	expected := hashReaderSums(t, data, NewCryptoHasherProvider(SHA256))
+
+	hr, err := NewBinaryHashReader(data, NewCryptoHasherProvider(SHA256))
+	require.NoError(t, err, "Something went wrong executing NewBinaryHashReader()")
+
+	var sums [][]byte
+	for hr.Next() {
+		sum, err := hr.Sum(nil)
+		require.NoError(t, err, "Something went wrong executing hr.Sum()")
+		sums = append(sums, sum)
+	}
+	require.NoError(t, hr.Err(), "Something went wrong executing hr.Next()")
+
+	assert.Equal(t, expected, sums, "sums did not match expectation")
+	assert.False(t, hr.Next())
+}
+
+func TestBinaryHashReaderEmpty(t *testing.T) {
+	for _, data := range [][]byte{nil, ivm, {0xE0, 0x01, 0x00, 0xEA, 0x00, 0xE0, 0x01, 0x00, 0xEA}} {
+		sums, err := HashBinary(data, NewCryptoHasherProvider(SHA256))
+		require.NoError(t, err, "Something went wrong executing HashBinary()")
+		assert.Empty(t, sums)
+	}
+}
+
+func TestBinaryHashReaderSymbolTables(t *testing.T) {
+	data := []byte{
+		0xE0, 0x01, 0x00, 0xEA,
+		// $ion_symbol_table::{symbols: ["a", "b"]}
+		0xE9, 0x81, 0x83, 0xD6, 0x87, 0xB4, 0x81, 'a', 0x81, 'b',
+		// a
+		0x71, 0x0A,
+		// $ion_symbol_table::{imports: $ion_symbol_table, symbols: ["c"]}
+		0xEA, 0x81, 0x83, 0xD7, 0x86, 0x71, 0x03, 0x87, 0xB2, 0x81, 'c',
+		// {b: c}
+		0xD3, 0x8B, 0x71, 0x0C,
+		// A binary version marker resets the symbol table: name
+		0xE0, 0x01, 0x00, 0xEA, 0x71, 0x04,
+	}
+
+	sums, err := HashBinary(data, newTestIonHasherProvider("identity").getInstance())
+	require.NoError(t, err, "Something went wrong executing HashBinary()")
+
+	expected := [][]byte{
+		{0x0B, 0x70, 'a', 0x0E},
+		{0x0B, 0xD0, 0x0C, 0x0B, 0x70, 'b', 0x0C, 0x0E, 0x0C, 0x0B, 0x70, 'c', 0x0C, 0x0E, 0x0E},
+		{0x0B, 0x70, 'n', 'a', 'm', 'e', 0x0E},
+	}
+
+	assert.Equal(t, expected, sums, "sums did not match expectation")
+	assert.Equal(t, hashReaderSums(t, data, newTestIonHasherProvider("identity").getInstance()), sums,
+		"sums did not match those of a HashReader")
+}
+
+func TestBinaryHashReaderAnnotatedBool(t *testing.T) {
+	// name::true, which has no representation after its type descriptor.
+	data := []byte{0xE0, 0x01, 0x00, 0xEA, 0xE3, 0x81, 0x84, 0x11}
+
+	sums, err := HashBinary(data, newTestIonHasherProvider("identity").getInstance())
+	require.NoError(t, err, "Something went wrong executing HashBinary()")
+
+	hw, err := NewHashWriter(ion.NewBinaryWriter(&bytes.Buffer{}), newTestIonHasherProvider("identity").getInstance())
+	require.NoError(t, err, "Something went wrong executing NewHashWriter()")
+	require.NoError(t, hw.Annotation(ion.NewSymbolTokenFromString("name")))
+	require.NoError(t, hw.WriteBool(true))
+
+	expected, err := hw.Sum(nil)
+	require.NoError(t, err, "Something went wrong executing hw.Sum()")
+
+	assert.Equal(t, [][]byte{expected}, sums, "sums did not match expectation")
+}
+
+func TestBinaryHashReaderMalformed(t *testing.T) {
+	testCases := map[string][]byte{
+		"truncated value":                 {0x21},
+		"truncated length":                {0x2E},
+		"overlong VarUInt":                {0x2E, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x81},
+		"invalid type":                    {0xF0},
+		"invalid bool":                    {0x12},
+		"negative zero int":               {0x30},
+		"invalid float length":            {0x42, 0x00, 0x00},
+		"unknown symbol":                  {0x71, 0x7F},
+		"invalid UTF-8":                   {0x81, 0xFF},
+		"child overruns container":        {0xB2, 0x22, 0x01},
+		"empty sorted struct":             {0xD1, 0x80},
+		"unknown field name":              {0xD2, 0xFF, 0x20},
+		"no annotations":                  {0xE3, 0x80, 0x20, 0x00},
+		"annotation wrapper length":       {0xE5, 0x81, 0x84, 0x21, 0x01, 0x00},
+		"nested annotation wrapper":       {0xE6, 0x81, 0x84, 0xE3, 0x81, 0x84, 0x20},
+		"annotated padding":               {0xE3, 0x81, 0x84, 0x00},
+		"version marker in container":     {0xB4, 0xE0, 0x01, 0x00, 0xEA},
+		"unsupported version":             {0xE0, 0x02, 0x00, 0xEA},
+		"invalid date":                    {0x65, 0x80, 0x0F, 0xD0, 0x82, 0x9E},
+		"hour without minute":             {0x66, 0x80, 0x0F, 0xD0, 0x86, 0x8F, 0x8A},
+		"decimal exponent out of range":   {0x57, 0x08, 0x00, 0x00, 0x00, 0x00, 0x80, 0x01},
+		"symbol table field without text": {0xE6, 0x81, 0x83, 0xD3, 0x80, 0x71, 0x03},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			data := append(append([]byte{}, ivm...), testCase...)
+
+			_, err := HashBinary(data, NewCryptoHasherProvider(SHA256))
+			assert.Error(t, err)
+		})
+	}
+}
+
+// nestedLists returns Ion binary data with lists nested to the given depth.
+func nestedLists(depth int) []byte {
+	// lengths[i] is the length of the representation of the list at depth i+1.
+	lengths := make([]int, depth)
+	for i := depth - 2; i >= 0; i-- {
+		lengths[i] = 1 + lengths[i+1]
+		if lengths[i+1] >= 14 {
+			lengths[i] += varUintLength(uint64(lengths[i+1]))
+		}
+	}
+
+	data := append([]byte{}, ivm...)
+	for _, length := range lengths {
+		if length < 14 {
+			data = append(data, 0xB0|byte(length))
+		} else {
+			data = appendVarUint(append(data, 0xBE), uint64(length))
+		}
+	}
+
+	return data
+}
+
+func TestBinaryHashReaderDeeplyNested(t *testing.T) {
+	sums, err := HashBinary(nestedLists(100000), NewCryptoHasherProvider(SHA256))
+	require.NoError(t, err, "Something went wrong executing HashBinary()")
+	assert.Len(t, sums, 1)
+
+	_, err = HashBinary(nestedLists(101), NewCryptoHasherProvider(SHA256), WithMaxDepth(100))
+	assert.True(t, errors.Is(err, ErrLimitExceeded), "Expected HashBinary() to return a LimitExceededError")
+}
+
+func TestBinaryHashReaderRequiresVersionMarker(t *testing.T) {
+	_, err := NewBinaryHashReader([]byte{0x20}, NewCryptoHasherProvider(SHA256))
+	assert.IsType(t, &InvalidArgumentError{}, err)
+}
+
+func TestBinaryHashReaderIonHash(t *testing.T) {
+	parameters := ionHashDataSource(t)
+
+	for i := range parameters {
+		if !bytes.HasPrefix(parameters[i].testCase, ivm) {
+			continue
+		}
+
+		expected := hashReaderSums(t, parameters[i].testCase, NewCryptoHasherProvider(SHA256))
+
+		sums, err := HashBinary(parameters[i].testCase, NewCryptoHasherProvider(SHA256))
+		require.NoError(t, err, "Something went wrong executing HashBinary()")
+		assert.Equal(t, expected, sums, parameters[i].hasherName+" failed")
+	}
+}
+
+// benchmarkBinaryData returns Ion binary data for benchmarking with a mix of containers and scalars.
+func benchmarkBinaryData(b *testing.B) []byte {
+	var text strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&text, `{id: %d, name: "item %d", price: %d.99, tags: [a, b, c], `+
+			`created: 2020-06-15T10:20:%02d.123Z, ratio: %de-3}`, i, i, i, i%60, i)
+	}
+
+	return toBinary(b, text.String())
+}
+
+func BenchmarkBinaryHashReader(b *testing.B) {
+	data := benchmarkBinaryData(b)
+	hasherProvider := NewCryptoHasherProvider(SHA256)
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := HashBinary(data, hasherProvider)
+		require.NoError(b, err, "Something went wrong executing HashBinary()")
+	}
+}
+
+func BenchmarkBinaryHashReaderHashReader(b *testing.B) {
+	data := benchmarkBinaryData(b)
+	hasherProvider := NewCryptoHasherProvider(SHA256)
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		hr, err := NewHashReader(ion.NewReaderBytes(data), hasherProvider)
+		require.NoError(b, err, "Something went wrong executing NewHashReader()")
+
+		for hr.Next() {
+			_, err = hr.Sum(nil)
+			require.NoError(b, err, "Something went wrong executing hr.Sum()")
+		}
+		require.NoError(b, hr.Err(), "Something went wrong executing hr.Next()")
+	}
+}
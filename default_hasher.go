@@ -33,7 +33,8 @@ func newDefaultHasher(algorithm Algorithm, provider *testIonHasherProvider) (Ion
 
 // Write adds more data to the running hash and appends to provider's updateHashlog.
 func (dh *defaultHasher) Write(b []byte) (n int, err error) {
-	dh.provider.updateHashLog = append(dh.provider.updateHashLog, b)
+	// The caller may reuse b once Write returns, so log a copy of it.
+	dh.provider.updateHashLog = append(dh.provider.updateHashLog, append([]byte{}, b...))
 	return dh.cryptoHasher.Write(b)
 }
 
@@ -0,0 +1,387 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package diff
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/amzn/ion-go/ion"
+	"github.com/amzn/ion-hash-go/internal/ionvalue"
+)
+
+// target is the operations of a patch at a path, and at the paths of the values in the value there.
+type target struct {
+	remove   bool
+	replace  []byte
+	children map[PathElement]*target
+
+	// inserts are the values inserted before each index of a list, sexp or stream of top-level values.
+	inserts map[int][][]byte
+
+	// adds are the fields added to a struct.
+	adds []field
+
+	found bool
+}
+
+type field struct {
+	name  string
+	value []byte
+}
+
+// newTarget returns the target at the root of the paths of patch.
+func newTarget(patch Patch) (*target, error) {
+	root := &target{}
+	for _, operation := range patch {
+		if len(operation.Path) == 0 {
+			return nil, &InvalidPatchError{fmt.Sprintf("%v has an empty path", operation.Kind)}
+		}
+
+		parentPath, last := operation.Path[:len(operation.Path)-1], operation.Path[len(operation.Path)-1]
+		parent, err := root.descendant(parentPath)
+		if err != nil {
+			return nil, err
+		}
+
+		switch operation.Kind {
+		case Add:
+			if !last.IsField {
+				return nil, &InvalidPatchError{fmt.Sprintf("add %v has no field name", operation.Path)}
+			}
+
+			parent.adds = append(parent.adds, field{last.Field, operation.Value})
+		case Insert:
+			if last.IsField {
+				return nil, &InvalidPatchError{fmt.Sprintf("insert %v has no index", operation.Path)}
+			}
+
+			if parent.inserts == nil {
+				parent.inserts = make(map[int][][]byte)
+			}
+			parent.inserts[last.Index] = append(parent.inserts[last.Index], operation.Value)
+		case Remove, Replace:
+			t, err := parent.descendant(Path{last})
+			if err != nil {
+				return nil, err
+			}
+
+			if t.remove || t.replace != nil || len(t.children) > 0 || len(t.inserts) > 0 || len(t.adds) > 0 {
+				return nil, &InvalidPatchError{fmt.Sprintf("%v %v conflicts with another operation", operation.Kind, operation.Path)}
+			}
+
+			t.remove = operation.Kind == Remove
+			t.replace = operation.Value
+		default:
+			return nil, &InvalidPatchError{fmt.Sprintf("an operation has no valid kind: %v", operation.Kind)}
+		}
+	}
+
+	return root, nil
+}
+
+// descendant returns the target at path from t, adding the targets that it doesn't have.
+func (t *target) descendant(path Path) (*target, error) {
+	for i, element := range path {
+		if t.children == nil {
+			t.children = make(map[PathElement]*target)
+		}
+
+		child, ok := t.children[element]
+		if !ok {
+			child = &target{}
+			t.children[element] = child
+		}
+		t = child
+
+		if t.remove || t.replace != nil {
+			return nil, &InvalidPatchError{fmt.Sprintf("an operation is in %v, which is removed or replaced", path[:i+1])}
+		}
+	}
+
+	return t, nil
+}
+
+// Apply writes the remaining values of source to out, changed by patch. It returns a MismatchError
+// if patch has an operation at a path that source doesn't have, and an UnknownSymbolError if the
+// text of the name of a field in a value with operations is unknown.
+func Apply(patch Patch, source ion.Reader, out ion.Writer) error {
+	root, err := newTarget(patch)
+	if err != nil {
+		return err
+	}
+
+	err = root.check(nil, false)
+	if err != nil {
+		return err
+	}
+
+	// The values with operations that are being written, from the stream of top-level values down,
+	// each with the index of the next value in it, and path, which is the path of the last of them.
+	type frame struct {
+		t        *target
+		ionType  ion.Type
+		inStruct bool
+		index    int
+	}
+
+	stack := []frame{{t: root}}
+	var path Path
+	for len(stack) > 0 {
+		top := &stack[len(stack)-1]
+		if !source.Next() {
+			if source.Err() != nil {
+				return source.Err()
+			}
+
+			err = top.t.finish(path, top.index, out)
+			if err != nil {
+				return err
+			}
+
+			ionType := top.ionType
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return nil
+			}
+
+			path = path[:len(path)-1]
+			err = source.StepOut()
+			if err != nil {
+				return err
+			}
+
+			err = ionvalue.EndContainer(out, ionType)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		index := top.index
+		top.index++
+		err = writeInserts(out, top.t.inserts[index])
+		if err != nil {
+			return err
+		}
+
+		element := Index(index)
+		if top.inStruct {
+			fieldName, err := source.FieldName()
+			if err != nil {
+				return err
+			}
+
+			text, err := fieldNameText(fieldName)
+			if err != nil {
+				return err
+			}
+
+			element = Field(text)
+		}
+
+		child := top.t.children[element]
+		if child != nil && child.found && top.inStruct && (child.remove || child.replace != nil) {
+			// Every field with the name is removed or replaced, and the replacement is written once.
+			continue
+		}
+
+		path = append(path, element)
+		ionType := source.Type()
+		steppedIn, err := applyValue(child, path, top.inStruct, source, out)
+		if err != nil {
+			return err
+		}
+
+		if !steppedIn {
+			path = path[:len(path)-1]
+			continue
+		}
+
+		inStruct := ionType == ion.StructType
+		err = child.check(path, inStruct)
+		if err != nil {
+			return err
+		}
+
+		stack = append(stack, frame{t: child, ionType: ionType, inStruct: inStruct})
+	}
+
+	return nil
+}
+
+// check returns a MismatchError if t inserts values in a struct, or adds fields to a value that
+// isn't one.
+func (t *target) check(path Path, inStruct bool) error {
+	switch {
+	case inStruct && len(t.inserts) > 0:
+		return &MismatchError{path.clone(), "values are inserted in a struct"}
+	case !inStruct && len(t.adds) > 0:
+		return &MismatchError{path.clone(), "fields are added to a value that isn't a struct"}
+	}
+
+	return nil
+}
+
+// finish writes the values inserted at the end of the value at path, which had length values, and
+// the fields added to it, once the values in it have been written.
+func (t *target) finish(path Path, length int, writer ion.Writer) error {
+	err := writeInserts(writer, t.inserts[length])
+	if err != nil {
+		return err
+	}
+
+	for _, added := range t.adds {
+		err = writer.FieldName(ion.NewSymbolTokenFromString(added.name))
+		if err != nil {
+			return err
+		}
+
+		err = writeEncoded(writer, added.value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return t.checkFound(path, length)
+}
+
+// applyValue writes the current value of reader, at path, changed by the operations of t, which is
+// nil if there are none. If the value is a container with operations in it, it writes the start of
+// the container, steps into it, and returns true.
+func applyValue(t *target, path Path, inStruct bool, reader ion.Reader, writer ion.Writer) (bool, error) {
+	if t == nil {
+		return false, copyField(reader, writer, inStruct)
+	}
+
+	t.found = true
+	switch {
+	case t.remove:
+		return false, nil
+	case t.replace != nil:
+		err := writeFieldName(reader, writer, inStruct)
+		if err != nil {
+			return false, err
+		}
+
+		return false, writeEncoded(writer, t.replace)
+	case !ion.IsContainer(reader.Type()) || reader.IsNull():
+		return false, &MismatchError{path.clone(), fmt.Sprintf("there are changes in a %v", describeType(reader))}
+	}
+
+	err := writeFieldName(reader, writer, inStruct)
+	if err != nil {
+		return false, err
+	}
+
+	annotations, err := reader.Annotations()
+	if err != nil {
+		return false, err
+	}
+
+	err = ionvalue.WriteAnnotations(writer, annotations)
+	if err != nil {
+		return false, err
+	}
+
+	err = ionvalue.BeginContainer(writer, reader.Type())
+	if err != nil {
+		return false, err
+	}
+
+	return true, reader.StepIn()
+}
+
+func copyField(reader ion.Reader, writer ion.Writer, inStruct bool) error {
+	err := writeFieldName(reader, writer, inStruct)
+	if err != nil {
+		return err
+	}
+
+	return ionvalue.Copy(reader, writer)
+}
+
+func writeFieldName(reader ion.Reader, writer ion.Writer, inStruct bool) error {
+	if !inStruct {
+		return nil
+	}
+
+	fieldName, err := reader.FieldName()
+	if err != nil {
+		return err
+	}
+
+	text, err := fieldNameText(fieldName)
+	if err != nil {
+		return err
+	}
+
+	return writer.FieldName(ion.NewSymbolTokenFromString(text))
+}
+
+func writeInserts(writer ion.Writer, values [][]byte) error {
+	for _, value := range values {
+		err := writeEncoded(writer, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkFound returns a MismatchError if t has an operation at a path that wasn't found in the value
+// at path, which had length values.
+func (t *target) checkFound(path Path, length int) error {
+	var missing []PathElement
+	for element, child := range t.children {
+		if !child.found {
+			missing = append(missing, element)
+		}
+	}
+
+	if len(missing) > 0 {
+		// The first of the missing paths is reported, so that the error is the same every time.
+		sort.Slice(missing, func(i, j int) bool {
+			if missing[i].IsField != missing[j].IsField {
+				return !missing[i].IsField
+			}
+			if missing[i].IsField {
+				return missing[i].Field < missing[j].Field
+			}
+			return missing[i].Index < missing[j].Index
+		})
+
+		return &MismatchError{append(path.clone(), missing[0]), "there is no such value"}
+	}
+
+	for index := range t.inserts {
+		if index > length {
+			return &MismatchError{append(path.clone(), Index(index)), fmt.Sprintf("there are only %d values", length)}
+		}
+	}
+
+	return nil
+}
+
+func describeType(reader ion.Reader) string {
+	if reader.IsNull() {
+		return fmt.Sprintf("null %v", reader.Type())
+	}
+
+	return reader.Type().String()
+}
@@ -0,0 +1,397 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+// Package diff computes structural differences between streams of Ion values as patches, and
+// applies them. Values whose Ion Hashes are the same are treated as identical, so their subtrees
+// are never compared, and a patched stream has the same Ion Hash as the stream the patch was made
+// from.
+package diff
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/amzn/ion-go/ion"
+)
+
+// Diff returns a patch that turns the remaining values of a into those of b. It returns an
+// UnknownSymbolError if a field name's text is unknown, since paths name fields by their text.
+func Diff(a, b ion.Reader) (Patch, error) {
+	nodesA, err := readNodes(a)
+	if err != nil {
+		return nil, err
+	}
+
+	nodesB, err := readNodes(b)
+	if err != nil {
+		return nil, err
+	}
+
+	steps, err := diffSequence(nil, nodesA, nodesB)
+	if err != nil {
+		return nil, err
+	}
+
+	// The steps still to take are kept in reverse, so that the operations are appended in the order
+	// of the values that they change.
+	var stack []step
+	var patch Patch
+	for {
+		for i := len(steps) - 1; i >= 0; i-- {
+			stack = append(stack, steps[i])
+		}
+
+		if len(stack) == 0 {
+			return patch, nil
+		}
+
+		next := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		steps = nil
+
+		if next.kind != 0 {
+			err = appendOperation(&patch, next.kind, next.path.path(), next.b)
+		} else {
+			steps, err = diffNode(next.path, next.a, next.b)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// A step is an operation, with the value of b, to append to a patch, or if its kind is zero, values
+// a and b to compare.
+type step struct {
+	kind OperationKind
+	path *stepPath
+	a, b *node
+}
+
+// A stepPath is the path of a step, linked to the path of the value that it is in, so that the steps
+// of the values in a value share its path rather than copy it.
+type stepPath struct {
+	parent  *stepPath
+	element PathElement
+}
+
+// child returns the path of a value in the value at p.
+func (p *stepPath) child(element PathElement) *stepPath {
+	return &stepPath{p, element}
+}
+
+// path returns p as a Path.
+func (p *stepPath) path() Path {
+	length := 0
+	for q := p; q != nil; q = q.parent {
+		length++
+	}
+
+	path := make(Path, length)
+	for q := p; q != nil; q = q.parent {
+		length--
+		path[length] = q.element
+	}
+
+	return path
+}
+
+// diffNode returns the steps that turn a into b, at path.
+func diffNode(path *stepPath, a, b *node) ([]step, error) {
+	if sameHash(a, b) {
+		// Values with the same Ion Hash are identical, so their subtrees aren't compared.
+		return nil, nil
+	}
+
+	if a.ionType != b.ionType || a.isNull || b.isNull || !ion.IsContainer(a.ionType) ||
+		!sameAnnotations(a.annotations, b.annotations) {
+		return []step{{kind: Replace, path: path, b: b}}, nil
+	}
+
+	if a.ionType == ion.StructType {
+		return diffStruct(path, a.children, b.children), nil
+	}
+
+	return diffSequence(path, a.children, b.children)
+}
+
+// diffSequence returns the steps that turn the values of a list, sexp or stream of top-level values
+// at path into those in b. Values that are the same in both are found by their Ion Hashes, and a
+// value that is removed where another is inserted is changed instead.
+func diffSequence(path *stepPath, a, b []*node) ([]step, error) {
+	matches, err := commonSubsequence(a, b)
+	if err != nil {
+		return nil, err
+	}
+
+	var steps []step
+	i, j := 0, 0
+	for _, match := range append(matches, [2]int{len(a), len(b)}) {
+		for ; i < match[0] && j < match[1]; i, j = i+1, j+1 {
+			steps = append(steps, step{path: path.child(Index(i)), a: a[i], b: b[j]})
+		}
+
+		for ; i < match[0]; i++ {
+			steps = append(steps, step{kind: Remove, path: path.child(Index(i))})
+		}
+
+		for ; j < match[1]; j++ {
+			steps = append(steps, step{kind: Insert, path: path.child(Index(match[0])), b: b[j]})
+		}
+
+		i, j = match[0]+1, match[1]+1
+	}
+
+	return steps, nil
+}
+
+// commonSubsequence returns the indexes in a and b of a longest common subsequence of values with
+// the same Ion Hashes. It is found with Myers' linear space algorithm, which takes time proportional
+// to the lengths of a and b times the number of values that differ, and space proportional to their
+// lengths.
+func commonSubsequence(a, b []*node) ([][2]int, error) {
+	m := &matcher{a: digests(a), b: digests(b)}
+	err := m.match(0, len(a), 0, len(b))
+	if err != nil {
+		return nil, err
+	}
+
+	return m.matches, nil
+}
+
+// matcher finds a longest common subsequence of a and b.
+type matcher struct {
+	a, b    []string
+	matches [][2]int
+
+	// forward and backward are reused by middleSnake.
+	forward, backward []int
+}
+
+// match appends the indexes of a longest common subsequence of a[aLo:aHi] and b[bLo:bHi] to matches.
+func (m *matcher) match(aLo, aHi, bLo, bHi int) error {
+	for aLo < aHi && bLo < bHi && m.a[aLo] == m.b[bLo] {
+		m.matches = append(m.matches, [2]int{aLo, bLo})
+		aLo, bLo = aLo+1, bLo+1
+	}
+
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && m.a[aHi-1-suffix] == m.b[bHi-1-suffix] {
+		suffix++
+	}
+	aHi, bHi = aHi-suffix, bHi-suffix
+
+	if aLo < aHi && bLo < bHi {
+		// The values at each end differ, so at least two values differ, and the values on either side
+		// of the middle snake differ by fewer.
+		x, y, u, v, err := m.middleSnake(aLo, aHi, bLo, bHi)
+		if err != nil {
+			return err
+		}
+
+		err = m.match(aLo, x, bLo, y)
+		if err != nil {
+			return err
+		}
+
+		for ; x < u; x, y = x+1, y+1 {
+			m.matches = append(m.matches, [2]int{x, y})
+		}
+
+		err = m.match(u, aHi, v, bHi)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := 0; i < suffix; i++ {
+		m.matches = append(m.matches, [2]int{aHi + i, bHi + i})
+	}
+
+	return nil
+}
+
+// errNoMiddleSnake is returned by middleSnake if it doesn't find a middle snake, which is a bug.
+var errNoMiddleSnake = errors.New("diff: no middle snake")
+
+// middleSnake returns the run of matching values, from (x, y) to (u, v), in the middle of a shortest
+// edit script that turns a[aLo:aHi] into b[bLo:bHi], by searching from both ends at once.
+func (m *matcher) middleSnake(aLo, aHi, bLo, bHi int) (x, y, u, v int, err error) {
+	n, mm := aHi-aLo, bHi-bLo
+	delta := n - mm
+	odd := delta%2 != 0
+
+	// forward[offset+k] is the furthest x reached on diagonal k = x - y from the start, and
+	// backward[offset+k] the furthest reached on diagonal k from the end, in reverse.
+	maxD := (n + mm + 1) / 2
+	offset := maxD + 1
+	size := 2*maxD + 3
+	if cap(m.forward) < size {
+		m.forward, m.backward = make([]int, size), make([]int, size)
+	}
+	forward, backward := m.forward[:size], m.backward[:size]
+	forward[offset+1], backward[offset+1] = 0, 0
+
+	for d := 0; d <= maxD; d++ {
+		for k := -d; k <= d; k += 2 {
+			x := forward[offset+k-1] + 1
+			if k == -d || k != d && forward[offset+k-1] < forward[offset+k+1] {
+				x = forward[offset+k+1]
+			}
+
+			startX := x
+			for x < n && x-k < mm && m.a[aLo+x] == m.b[bLo+x-k] {
+				x++
+			}
+			forward[offset+k] = x
+
+			if odd && delta-k >= -(d-1) && delta-k <= d-1 && x+backward[offset+delta-k] >= n {
+				return aLo + startX, bLo + startX - k, aLo + x, bLo + x - k, nil
+			}
+		}
+
+		for k := -d; k <= d; k += 2 {
+			x := backward[offset+k-1] + 1
+			if k == -d || k != d && backward[offset+k-1] < backward[offset+k+1] {
+				x = backward[offset+k+1]
+			}
+
+			startX := x
+			for x < n && x-k < mm && m.a[aHi-1-x] == m.b[bHi-1-(x-k)] {
+				x++
+			}
+			backward[offset+k] = x
+
+			if !odd && delta-k >= -d && delta-k <= d && x+forward[offset+delta-k] >= n {
+				return aHi - x, bHi - (x - k), aHi - startX, bHi - (startX - k), nil
+			}
+		}
+	}
+
+	// A shortest edit script never has more than maxD edits on each side of its middle.
+	return 0, 0, 0, 0, errNoMiddleSnake
+}
+
+// digests returns the digests of nodes as strings, which can be compared directly.
+func digests(nodes []*node) []string {
+	result := make([]string, len(nodes))
+	for i, n := range nodes {
+		result[i] = string(n.digest)
+	}
+
+	return result
+}
+
+// diffStruct returns the steps that turn the fields of a struct at path into those in b. The fields
+// with a name that appears once in each are compared, and otherwise the fields with a name are
+// removed and added unless they are the same in both.
+func diffStruct(path *stepPath, a, b []*node) []step {
+	namesA, fieldsA := fieldsByName(a)
+	_, fieldsB := fieldsByName(b)
+
+	var steps []step
+	for _, name := range namesA {
+		fieldPath := path.child(Field(name))
+		switch {
+		case len(fieldsB[name]) == 0:
+			steps = append(steps, step{kind: Remove, path: fieldPath})
+			continue
+		case len(fieldsA[name]) == 1 && len(fieldsB[name]) == 1:
+			steps = append(steps, step{path: fieldPath, a: fieldsA[name][0], b: fieldsB[name][0]})
+			continue
+		}
+
+		if sameFields(fieldsA[name], fieldsB[name]) {
+			continue
+		}
+
+		steps = append(steps, step{kind: Remove, path: fieldPath})
+		for _, field := range fieldsB[name] {
+			steps = append(steps, step{kind: Add, path: fieldPath, b: field})
+		}
+	}
+
+	for _, field := range b {
+		if len(fieldsA[field.fieldName]) == 0 {
+			steps = append(steps, step{kind: Add, path: path.child(Field(field.fieldName)), b: field})
+		}
+	}
+
+	return steps
+}
+
+// fieldsByName returns the names of fields in the order in which they first appear, and the fields
+// with each name.
+func fieldsByName(fields []*node) ([]string, map[string][]*node) {
+	var names []string
+	byName := make(map[string][]*node)
+	for _, field := range fields {
+		if len(byName[field.fieldName]) == 0 {
+			names = append(names, field.fieldName)
+		}
+
+		byName[field.fieldName] = append(byName[field.fieldName], field)
+	}
+
+	return names, byName
+}
+
+// sameFields reports whether a and b have the same Ion Hashes, in any order.
+func sameFields(a, b []*node) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	digestsA, digestsB := digests(a), digests(b)
+	sort.Strings(digestsA)
+	sort.Strings(digestsB)
+	for i := range digestsA {
+		if digestsA[i] != digestsB[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func sameAnnotations(a, b []ion.SymbolToken) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if !a[i].Equal(&b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// appendOperation appends an operation to patch, with the value of n, which is nil for Remove.
+func appendOperation(patch *Patch, kind OperationKind, path Path, n *node) error {
+	operation := Operation{Kind: kind, Path: path}
+	if n != nil {
+		encoded, err := n.encoding()
+		if err != nil {
+			return err
+		}
+
+		operation.Value = encoded
+	}
+
+	*patch = append(*patch, operation)
+	return nil
+}
@@ -0,0 +1,337 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package diff
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/amzn/ion-go/ion"
+	ionhash "github.com/amzn/ion-hash-go"
+	"github.com/amzn/ion-hash-go/internal/ionvalue"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffApply(t *testing.T) {
+	testCases := []struct {
+		a, b string
+	}{
+		{``, ``},
+		{``, `1 {a:2}`},
+		{`1 {a:2}`, ``},
+		{`1 2 3`, `1 2 3`},
+		{`1 2 3`, `1 3`},
+		{`1 2 3`, `0 1 2 3 4`},
+		{`1 2 3`, `1 "two" 3`},
+		{`{a:1, b:2}`, `{a:1, b:3}`},
+		{`{a:1, b:2}`, `{b:2, a:1}`},
+		{`{a:1, b:2}`, `{a:1}`},
+		{`{a:1}`, `{a:1, 'first name':"x"}`},
+		{`{a:1, a:2, b:3}`, `{a:2, a:1, b:3}`},
+		{`{a:1, a:2, b:3}`, `{a:1, a:3, b:3}`},
+		{`{a:1, a:2}`, `{a:1}`},
+		{`{items:[{sku:"a", n:1}, {sku:"b", n:2}, {sku:"c", n:3}]}`, `{items:[{sku:"a", n:1}, {sku:"c", n:4}, {sku:"d", n:1}]}`},
+		{`[1, [2, [3, 4]], 5]`, `[1, [2, [3, 5]], 5]`},
+		{`(a (b c) d)`, `(a (b e) d f)`},
+		{`a::[1]`, `b::[1]`},
+		{`a::b::[x::1, 2]`, `a::b::[x::1, y::3]`},
+		{`[1]`, `(1)`},
+		{`null.list`, `[1]`},
+		{`[1]`, `null.list`},
+		{`{a:null}`, `{a:null.int}`},
+		{`{a:{b:{c:1}}}`, `{a:{b:{c:1, d:2}}}`},
+		{`[1, 2, 3, 4, 5, 6]`, `[6, 5, 4, 3, 2, 1]`},
+		{`[a, b, c]`, `[x, y]`},
+		{`2020T {{aGVsbG8=}} {{"clob"}} 1.50 1e0`, `2020-06T {{aGVsbG8=}} {{"clob2"}} 1.5 1e0`},
+	}
+
+	for _, tc := range testCases {
+		patch, err := Diff(ion.NewReaderString(tc.a), ion.NewReaderString(tc.b))
+		require.NoError(t, err, "Something went wrong executing Diff() of %s and %s", tc.a, tc.b)
+
+		patched := applyPatch(t, roundTrip(t, patch), tc.a)
+		assert.Equal(t, hashes(t, toBinary(t, tc.b)), hashes(t, patched),
+			"Expected the patched values of %s to have the Ion Hashes of %s", tc.a, tc.b)
+	}
+}
+
+func TestDiffSkipsIdenticalValues(t *testing.T) {
+	a := `{id:1, items:[{sku:"a"}, {sku:"b"}], meta:{big:[1, 2, 3, 4]}}`
+	b := `{id:1, items:[{sku:"a"}, {sku:"c"}], meta:{big:[1, 2, 3, 4]}}`
+
+	patch, err := Diff(ion.NewReaderString(a), ion.NewReaderString(b))
+	require.NoError(t, err, "Something went wrong executing Diff()")
+
+	require.Len(t, patch, 1)
+	assert.Equal(t, Replace, patch[0].Kind)
+	assert.Equal(t, Path{Index(0), Field("items"), Index(1), Field("sku")}, patch[0].Path)
+	assert.Equal(t, "[0].items[1].sku", patch[0].Path.String())
+	assert.Equal(t, "\"c\"\n", toText(t, patch[0].Value))
+}
+
+func TestDigestsAreSameExactlyWhenIonHashesAre(t *testing.T) {
+	values := []string{
+		`1`, `a::1`, `[1, 2]`, `[2, 1]`, `(1 2)`, `a::[1, 2]`, `b::a::[1, 2]`, `[{{AQ==}}]`, `[[1]]`,
+		`{a:1, b:2}`, `{b:2, a:1}`, `{a:1, a:1}`, `{a:1}`, `{a:{{AQ==}}}`, `{b:1}`, `null.list`, `null.struct`,
+		`[]`, `{}`, `()`, `{a:[1, {b:(c)}]}`, `{a:[1, {b:(d)}]}`,
+	}
+
+	var nodes []*node
+	for _, value := range values {
+		read, err := readNodes(ion.NewReaderString(value))
+		require.NoError(t, err, "Something went wrong reading %s", value)
+		nodes = append(nodes, read...)
+	}
+
+	for i := range values {
+		for j := range values {
+			sameIonHash := bytes.Equal(hashes(t, toBinary(t, values[i]))[0], hashes(t, toBinary(t, values[j]))[0])
+			assert.Equal(t, sameIonHash, sameHash(nodes[i], nodes[j]), "Expected the digests of %s and %s to be the same as their Ion Hashes are", values[i], values[j])
+		}
+	}
+}
+
+func TestDiffSequenceOperations(t *testing.T) {
+	patch, err := Diff(ion.NewReaderString(`[1, 2, 3]`), ion.NewReaderString(`[0, 1, 3, 4]`))
+	require.NoError(t, err, "Something went wrong executing Diff()")
+
+	var text bytes.Buffer
+	writer := ion.NewTextWriter(&text)
+	require.NoError(t, patch.Write(writer))
+	require.NoError(t, writer.Finish())
+
+	assert.Equal(t, "{op:insert,path:[0,0],value:0}\n{op:remove,path:[0,1]}\n{op:insert,path:[0,3],value:4}\n",
+		text.String())
+}
+
+func TestMatcher(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for n := 0; n < 2000; n++ {
+		a, b := make([]string, random.Intn(20)), make([]string, random.Intn(20))
+		for i := range a {
+			a[i] = string(rune('a' + random.Intn(4)))
+		}
+		for i := range b {
+			b[i] = string(rune('a' + random.Intn(4)))
+		}
+
+		m := &matcher{a: a, b: b}
+		require.NoError(t, m.match(0, len(a), 0, len(b)), "Something went wrong matching %v and %v", a, b)
+
+		// The matches are of equal values, in order, and as many as in a longest common subsequence.
+		for i, match := range m.matches {
+			require.Equal(t, a[match[0]], b[match[1]])
+			if i > 0 {
+				require.Less(t, m.matches[i-1][0], match[0])
+				require.Less(t, m.matches[i-1][1], match[1])
+			}
+		}
+		require.Equal(t, lcsLength(a, b), len(m.matches), "Expected a longest common subsequence of %v and %v", a, b)
+	}
+}
+
+// lcsLength returns the length of a longest common subsequence of a and b.
+func lcsLength(a, b []string) int {
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			}
+		}
+	}
+
+	return lengths[0][0]
+}
+
+func TestDiffLongLists(t *testing.T) {
+	// A table of the lists' lengths squared wouldn't fit in memory.
+	var a, b strings.Builder
+	a.WriteString("[first")
+	b.WriteString("[other")
+	for i := 0; i < 100000; i++ {
+		fmt.Fprintf(&a, ", %d", i)
+		fmt.Fprintf(&b, ", %d", i)
+	}
+	a.WriteString(", last]")
+	b.WriteString(", end]")
+
+	patch, err := Diff(ion.NewReaderString(a.String()), ion.NewReaderString(b.String()))
+	require.NoError(t, err, "Something went wrong executing Diff()")
+	assert.Equal(t, Patch{
+		{Replace, Path{Index(0), Index(0)}, toBinary(t, "other")},
+		{Replace, Path{Index(0), Index(100001)}, toBinary(t, "end")},
+	}, patch)
+}
+
+func TestDiffApplyDeeplyNestedValues(t *testing.T) {
+	depth := 100000
+	a := strings.Repeat("[", depth) + "1" + strings.Repeat("]", depth)
+	b := strings.Repeat("[", depth) + "2" + strings.Repeat("]", depth)
+
+	patch, err := Diff(ion.NewReaderString(a), ion.NewReaderString(b))
+	require.NoError(t, err, "Something went wrong executing Diff()")
+	require.Len(t, patch, 1)
+	assert.Len(t, patch[0].Path, depth+1)
+
+	assert.Equal(t, hashes(t, toBinary(t, b)), hashes(t, applyPatch(t, patch, a)))
+}
+
+func TestReadPatchInvalid(t *testing.T) {
+	testCases := []string{
+		`1`,
+		`{path:[0]}`,
+		`{op:move, path:[0]}`,
+		`{op:remove}`,
+		`{op:remove, path:["a"]}`,
+		`{op:remove, path:[0, 1.5]}`,
+		`{op:remove, path:[0, -1]}`,
+		`{op:remove, path:[0], value:1}`,
+		`{op:replace, path:[0]}`,
+		`{op:add, path:[0, 1], value:1}`,
+		`{op:insert, path:[0, "a"], value:1}`,
+	}
+
+	for _, tc := range testCases {
+		_, err := ReadPatch(ion.NewReaderString(tc))
+		assert.True(t, errors.Is(err, ErrInvalidPatch), "Expected an InvalidPatchError reading %s, got %v", tc, err)
+	}
+}
+
+func TestApplyInvalidPatch(t *testing.T) {
+	testCases := []string{
+		`{op:remove, path:[0]} {op:replace, path:[0], value:1}`,
+		`{op:remove, path:[0]} {op:remove, path:[0, "a"]}`,
+	}
+
+	for _, tc := range testCases {
+		patch, err := ReadPatch(ion.NewReaderString(tc))
+		require.NoError(t, err, "Something went wrong executing ReadPatch() of %s", tc)
+
+		err = Apply(patch, ion.NewReaderString(`{a:1}`), ion.NewTextWriter(&bytes.Buffer{}))
+		assert.True(t, errors.Is(err, ErrInvalidPatch), "Expected an InvalidPatchError applying %s, got %v", tc, err)
+	}
+}
+
+func TestApplyMismatch(t *testing.T) {
+	testCases := []struct {
+		patch, source string
+		path          Path
+	}{
+		{`{op:remove, path:[1]}`, `1`, Path{Index(1)}},
+		{`{op:insert, path:[2], value:1}`, `1`, Path{Index(2)}},
+		{`{op:replace, path:[0, "b"], value:1}`, `{a:1}`, Path{Index(0), Field("b")}},
+		{`{op:replace, path:[0, 0], value:1}`, `{a:1}`, Path{Index(0), Index(0)}},
+		{`{op:remove, path:[0, 0, 0]}`, `[1]`, Path{Index(0), Index(0)}},
+		{`{op:add, path:[0, "a"], value:1}`, `[1]`, Path{Index(0)}},
+		{`{op:insert, path:[0, 0], value:1}`, `{a:1}`, Path{Index(0)}},
+		{`{op:remove, path:[0, 0]}`, `null.list`, Path{Index(0)}},
+	}
+
+	for _, tc := range testCases {
+		patch, err := ReadPatch(ion.NewReaderString(tc.patch))
+		require.NoError(t, err, "Something went wrong executing ReadPatch() of %s", tc.patch)
+
+		err = Apply(patch, ion.NewReaderString(tc.source), ion.NewTextWriter(&bytes.Buffer{}))
+		var mismatch *MismatchError
+		if assert.True(t, errors.As(err, &mismatch), "Expected a MismatchError applying %s to %s, got %v", tc.patch, tc.source, err) {
+			assert.True(t, errors.Is(err, ErrMismatch))
+			assert.Equal(t, tc.path, mismatch.Path, "Expected the mismatch applying %s to %s at %v", tc.patch, tc.source, tc.path)
+		}
+	}
+}
+
+func TestUnknownFieldName(t *testing.T) {
+	// The text of $12 is in a shared symbol table that isn't in the catalog.
+	unknown := `$ion_symbol_table::{imports:[{name:"x", version:1, max_id:5}]} {a:1, $12:2}`
+
+	_, err := Diff(ion.NewReaderString(unknown), ion.NewReaderString(`{a:1}`))
+	var unknownSymbolError *ionhash.UnknownSymbolError
+	require.True(t, errors.As(err, &unknownSymbolError), "Expected Diff() to return an UnknownSymbolError, got %v", err)
+	assert.Equal(t, int64(12), unknownSymbolError.SID)
+
+	patch, err := ReadPatch(ion.NewReaderString(`{op:replace, path:[0, "a"], value:2}`))
+	require.NoError(t, err, "Something went wrong executing ReadPatch()")
+
+	err = Apply(patch, ion.NewReaderString(unknown), ion.NewTextWriter(&bytes.Buffer{}))
+	assert.True(t, errors.Is(err, ionhash.ErrUnknownSymbol), "Expected Apply() to return an UnknownSymbolError, got %v", err)
+}
+
+func TestPathString(t *testing.T) {
+	assert.Equal(t, "", Path{}.String())
+	assert.Equal(t, "[2]", Path{Index(2)}.String())
+	assert.Equal(t, `[0].a[1].'first name'.'it\'s'`,
+		Path{Index(0), Field("a"), Index(1), Field("first name"), Field("it's")}.String())
+}
+
+// roundTrip returns patch after it is written as Ion binary and read again.
+func roundTrip(t *testing.T, patch Patch) Patch {
+	var buf bytes.Buffer
+	writer := ion.NewBinaryWriter(&buf)
+	require.NoError(t, patch.Write(writer), "Something went wrong writing the patch")
+	require.NoError(t, writer.Finish())
+
+	read, err := ReadPatch(ion.NewReaderBytes(buf.Bytes()))
+	require.NoError(t, err, "Something went wrong executing ReadPatch()")
+	require.Len(t, read, len(patch))
+	return read
+}
+
+// applyPatch returns the values of source, written as Ion binary and changed by patch, as Ion binary.
+func applyPatch(t *testing.T, patch Patch, source string) []byte {
+	var buf bytes.Buffer
+	writer := ion.NewBinaryWriter(&buf)
+	require.NoError(t, Apply(patch, ion.NewReaderBytes(toBinary(t, source)), writer), "Something went wrong executing Apply() to %s", source)
+	require.NoError(t, writer.Finish())
+	return buf.Bytes()
+}
+
+// toBinary returns the values of text as Ion binary.
+func toBinary(t *testing.T, text string) []byte {
+	var buf bytes.Buffer
+	writer := ion.NewBinaryWriter(&buf)
+	reader := ion.NewReaderString(text)
+	for reader.Next() {
+		require.NoError(t, ionvalue.Copy(reader, writer))
+	}
+	require.NoError(t, reader.Err())
+	require.NoError(t, writer.Finish())
+	return buf.Bytes()
+}
+
+func hashes(t *testing.T, data []byte) [][]byte {
+	digests, err := ionhash.HashBinary(data, ionhash.NewCryptoHasherProvider(ionhash.SHA256))
+	require.NoError(t, err, "Something went wrong executing HashBinary()")
+	return digests
+}
+
+func toText(t *testing.T, data []byte) string {
+	var text bytes.Buffer
+	writer := ion.NewTextWriter(&text)
+	require.NoError(t, writeEncoded(writer, data))
+	require.NoError(t, writer.Finish())
+	return text.String()
+}
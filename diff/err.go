@@ -0,0 +1,57 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package diff
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors that the errors returned by this package match with errors.Is.
+var (
+	ErrInvalidPatch = errors.New("diff: invalid patch")
+	ErrMismatch     = errors.New("diff: patch does not match the data")
+)
+
+// An InvalidPatchError is returned when an Ion-encoded patch is not a valid patch.
+type InvalidPatchError struct {
+	Message string
+}
+
+func (e *InvalidPatchError) Error() string {
+	return fmt.Sprintf("diff: Invalid patch: %s", e.Message)
+}
+
+// Is reports whether target is ErrInvalidPatch.
+func (e *InvalidPatchError) Is(target error) bool {
+	return target == ErrInvalidPatch
+}
+
+// A MismatchError is returned when a patch is applied to data that it wasn't made for, e.g. when
+// an operation's path doesn't exist in the data.
+type MismatchError struct {
+	Path    Path
+	Message string
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("diff: Patch does not match the data at %v: %s", e.Path, e.Message)
+}
+
+// Is reports whether target is ErrMismatch.
+func (e *MismatchError) Is(target error) bool {
+	return target == ErrMismatch
+}
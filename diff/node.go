@@ -0,0 +1,322 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package diff
+
+import (
+	"bytes"
+
+	"github.com/amzn/ion-go/ion"
+	ionhash "github.com/amzn/ion-hash-go"
+	"github.com/amzn/ion-hash-go/internal/ionvalue"
+)
+
+// node is an Ion value held in memory, with a digest that is the same as that of another node
+// exactly when their Ion Hashes are.
+type node struct {
+	ionType     ion.Type
+	isNull      bool
+	fieldName   string
+	annotations []ion.SymbolToken
+	val         interface{}
+	children    []*node
+
+	digest []byte
+}
+
+// readNode reads the current value of reader and the values in it.
+func readNode(reader ion.Reader) (*node, error) {
+	root, err := readNodeStart(reader)
+	if err != nil || root.isNull || !ion.IsContainer(root.ionType) {
+		return root, err
+	}
+
+	// The containers being read.
+	stack := []*node{root}
+	for len(stack) > 0 {
+		if !reader.Next() {
+			if reader.Err() != nil {
+				return nil, reader.Err()
+			}
+
+			err = reader.StepOut()
+			if err != nil {
+				return nil, err
+			}
+
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		child, err := readNodeStart(reader)
+		if err != nil {
+			return nil, err
+		}
+
+		parent := stack[len(stack)-1]
+		parent.children = append(parent.children, child)
+		if !child.isNull && ion.IsContainer(child.ionType) {
+			stack = append(stack, child)
+		}
+	}
+
+	return root, nil
+}
+
+// readNodeStart reads the current value of reader if it is a scalar or a null, or steps into the
+// container that it is otherwise.
+func readNodeStart(reader ion.Reader) (*node, error) {
+	n := &node{ionType: reader.Type(), isNull: reader.IsNull()}
+
+	if reader.IsInStruct() {
+		fieldName, err := reader.FieldName()
+		if err != nil {
+			return nil, err
+		}
+
+		n.fieldName, err = fieldNameText(fieldName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	annotations, err := reader.Annotations()
+	if err != nil {
+		return nil, err
+	}
+	for _, annotation := range annotations {
+		n.annotations = append(n.annotations, ionvalue.PortableSymbol(annotation))
+	}
+
+	switch {
+	case n.isNull:
+		return n, nil
+	case !ion.IsContainer(n.ionType):
+		n.val, err = ionvalue.ReadScalar(reader)
+		return n, err
+	}
+
+	return n, reader.StepIn()
+}
+
+// readNodes reads the remaining values at the current depth of reader, and computes their digests.
+func readNodes(reader ion.Reader) ([]*node, error) {
+	var nodes []*node
+	for reader.Next() {
+		n, err := readNode(reader)
+		if err != nil {
+			return nil, err
+		}
+
+		err = digestTree(n)
+		if err != nil {
+			return nil, err
+		}
+
+		nodes = append(nodes, n)
+	}
+
+	return nodes, reader.Err()
+}
+
+// fieldNameText returns the text of a field name. Paths name fields by their text, so it returns an
+// UnknownSymbolError if the text is unknown.
+func fieldNameText(fieldName *ion.SymbolToken) (string, error) {
+	if fieldName.Text == nil {
+		return "", &ionhash.UnknownSymbolError{SID: fieldName.LocalSID}
+	}
+
+	return *fieldName.Text, nil
+}
+
+// encoding returns n, without its field name, as Ion binary.
+func (n *node) encoding() ([]byte, error) {
+	var buf bytes.Buffer
+	writer := ion.NewBinaryWriter(&buf)
+	err := n.write(writer)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writer.Finish()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// digestTree computes the digest of every value in the tree of root from the bottom up, so that
+// each value is hashed once.
+func digestTree(root *node) error {
+	type frame struct {
+		n    *node
+		next int
+	}
+
+	stack := []frame{{n: root}}
+	for len(stack) > 0 {
+		top := &stack[len(stack)-1]
+		if top.next < len(top.n.children) {
+			// The digests of the values in a container are computed before its own.
+			top.next++
+			stack = append(stack, frame{n: top.n.children[top.next-1]})
+			continue
+		}
+
+		stack = stack[:len(stack)-1]
+		digest, err := nodeDigest(top.n)
+		if err != nil {
+			return err
+		}
+
+		top.n.digest = digest
+	}
+
+	return nil
+}
+
+// nodeDigest returns the digest of n, given the digests of the values in it. The digest of a scalar,
+// or of a null, is its Ion Hash. The digest of a container is the Ion Hash of a container of the same
+// type and annotations that holds the digests of its values as blobs, with the same field names, so
+// that two containers have the same digest exactly when they have the same Ion Hash.
+func nodeDigest(n *node) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := ion.NewBinaryWriter(&buf)
+
+	err := ionvalue.WriteAnnotations(writer, n.annotations)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case n.isNull:
+		err = ionvalue.WriteNull(writer, n.ionType)
+	case !ion.IsContainer(n.ionType):
+		err = ionvalue.WriteScalar(writer, n.ionType, n.val)
+	default:
+		err = ionvalue.BeginContainer(writer, n.ionType)
+		for _, child := range n.children {
+			if err == nil && n.ionType == ion.StructType {
+				err = writer.FieldName(ion.NewSymbolTokenFromString(child.fieldName))
+			}
+			if err == nil {
+				err = writer.WriteBlob(child.digest)
+			}
+		}
+		if err == nil {
+			err = ionvalue.EndContainer(writer, n.ionType)
+		}
+	}
+	if err == nil {
+		err = writer.Finish()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	digests, err := ionhash.HashBinary(buf.Bytes(), ionhash.NewCryptoHasherProvider(ionhash.SHA256))
+	if err != nil {
+		return nil, err
+	}
+
+	return digests[0], nil
+}
+
+// sameHash reports whether a and b have the same Ion Hash.
+func sameHash(a, b *node) bool {
+	return bytes.Equal(a.digest, b.digest)
+}
+
+// write writes n, without its field name, and the values in it.
+func (n *node) write(writer ion.Writer) error {
+	err := n.writeStart(writer)
+	if err != nil || n.isNull || !ion.IsContainer(n.ionType) {
+		return err
+	}
+
+	// The containers being written, and the index of the next value to write in each.
+	type frame struct {
+		n    *node
+		next int
+	}
+
+	stack := []frame{{n: n}}
+	for len(stack) > 0 {
+		top := &stack[len(stack)-1]
+		if top.next == len(top.n.children) {
+			stack = stack[:len(stack)-1]
+			err = ionvalue.EndContainer(writer, top.n.ionType)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		child := top.n.children[top.next]
+		top.next++
+
+		if top.n.ionType == ion.StructType {
+			err = writer.FieldName(ion.NewSymbolTokenFromString(child.fieldName))
+			if err != nil {
+				return err
+			}
+		}
+
+		err = child.writeStart(writer)
+		if err != nil {
+			return err
+		}
+
+		if !child.isNull && ion.IsContainer(child.ionType) {
+			stack = append(stack, frame{n: child})
+		}
+	}
+
+	return nil
+}
+
+// writeStart writes the annotations of n and then n if it is a scalar or a null, or the start of the
+// container that it is otherwise.
+func (n *node) writeStart(writer ion.Writer) error {
+	err := ionvalue.WriteAnnotations(writer, n.annotations)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case n.isNull:
+		return ionvalue.WriteNull(writer, n.ionType)
+	case !ion.IsContainer(n.ionType):
+		return ionvalue.WriteScalar(writer, n.ionType, n.val)
+	}
+
+	return ionvalue.BeginContainer(writer, n.ionType)
+}
+
+// writeEncoded writes the value encoded as Ion in encoded.
+func writeEncoded(writer ion.Writer, encoded []byte) error {
+	reader := ion.NewReaderBytes(encoded)
+	if !reader.Next() {
+		if reader.Err() != nil {
+			return reader.Err()
+		}
+
+		return &InvalidPatchError{"an operation's value is empty"}
+	}
+
+	return ionvalue.Copy(reader, writer)
+}
@@ -0,0 +1,286 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package diff
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/amzn/ion-go/ion"
+)
+
+// OperationKind is the kind of change that an Operation makes.
+type OperationKind int
+
+const (
+	// Add adds a field, whose name is the last element of the operation's path, to the end of a struct.
+	Add OperationKind = iota + 1
+
+	// Insert inserts a value before the value at the operation's path in a list, sexp or stream of
+	// top-level values, or at the end of it if the path's last index is its length.
+	Insert
+
+	// Remove removes the value at the operation's path. Removing a field removes every field with
+	// its name.
+	Remove
+
+	// Replace replaces the value at the operation's path. Replacing a field replaces every field
+	// with its name.
+	Replace
+)
+
+var operationKindNames = map[OperationKind]string{Add: "add", Insert: "insert", Remove: "remove", Replace: "replace"}
+
+func (k OperationKind) String() string {
+	if name, ok := operationKindNames[k]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("<unknown operation %d>", int(k))
+}
+
+// A PathElement is a field name, or the index of a value in a list, sexp or stream of top-level values.
+type PathElement struct {
+	Field   string
+	Index   int
+	IsField bool
+}
+
+// Field returns the PathElement of the field with the given name.
+func Field(name string) PathElement {
+	return PathElement{Field: name, IsField: true}
+}
+
+// Index returns the PathElement of the value with the given index.
+func Index(index int) PathElement {
+	return PathElement{Index: index}
+}
+
+// A Path is the location of a value, starting with the index of the top-level value that it is in.
+type Path []PathElement
+
+// identifierPattern matches the field names that can appear in a path without quotes.
+var identifierPattern = regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z0-9_$]*$`)
+
+// String returns the path written like the paths of ionhash.HashErrors, after the index of the
+// top-level value, e.g. [0].items[3].sku.
+func (p Path) String() string {
+	var path strings.Builder
+	for _, element := range p {
+		switch {
+		case !element.IsField:
+			path.WriteString("[" + strconv.Itoa(element.Index) + "]")
+		case identifierPattern.MatchString(element.Field):
+			path.WriteString("." + element.Field)
+		default:
+			path.WriteString(".'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(element.Field) + "'")
+		}
+	}
+
+	return path.String()
+}
+
+// clone returns a copy of p.
+func (p Path) clone() Path {
+	return append(Path{}, p...)
+}
+
+// An Operation is one change that a Patch makes.
+type Operation struct {
+	Kind OperationKind
+	Path Path
+
+	// Value is the value that is added, inserted or replaced with, as Ion binary, or nil for Remove.
+	Value []byte
+}
+
+// A Patch is the changes that turn one stream of Ion values into another. Each operation's path
+// locates a value in the stream that the patch is applied to, as it is before any of the patch is
+// applied, and the operations at the same location are applied in order.
+//
+// As Ion, a patch is a stream of structs, one for each operation, e.g.
+//
+//	{op:replace, path:[0, "items", 3], value:{sku:"a", quantity:2}}
+//	{op:remove, path:[1]}
+type Patch []Operation
+
+// Write writes the patch as Ion to writer.
+func (p Patch) Write(writer ion.Writer) error {
+	for _, operation := range p {
+		err := operation.write(writer)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (o *Operation) write(writer ion.Writer) error {
+	err := writer.BeginStruct()
+	if err != nil {
+		return err
+	}
+
+	err = writer.FieldName(ion.NewSymbolTokenFromString("op"))
+	if err != nil {
+		return err
+	}
+
+	err = writer.WriteSymbolFromString(o.Kind.String())
+	if err != nil {
+		return err
+	}
+
+	err = writer.FieldName(ion.NewSymbolTokenFromString("path"))
+	if err != nil {
+		return err
+	}
+
+	err = writer.BeginList()
+	if err != nil {
+		return err
+	}
+
+	for _, element := range o.Path {
+		if element.IsField {
+			err = writer.WriteString(element.Field)
+		} else {
+			err = writer.WriteInt(int64(element.Index))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	err = writer.EndList()
+	if err != nil {
+		return err
+	}
+
+	if o.Value != nil {
+		err = writer.FieldName(ion.NewSymbolTokenFromString("value"))
+		if err != nil {
+			return err
+		}
+
+		err = writeEncoded(writer, o.Value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writer.EndStruct()
+}
+
+// ReadPatch reads a patch written as Ion, e.g. by Patch.Write, from reader.
+func ReadPatch(reader ion.Reader) (Patch, error) {
+	nodes, err := readNodes(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	patch := make(Patch, 0, len(nodes))
+	for _, n := range nodes {
+		operation, err := readOperation(n)
+		if err != nil {
+			return nil, err
+		}
+
+		patch = append(patch, operation)
+	}
+
+	return patch, nil
+}
+
+func readOperation(n *node) (Operation, error) {
+	var operation Operation
+	if n.ionType != ion.StructType || n.isNull {
+		return operation, &InvalidPatchError{fmt.Sprintf("an operation is a %v rather than a struct", n.ionType)}
+	}
+
+	var hasPath bool
+	for _, field := range n.children {
+		switch field.fieldName {
+		case "op":
+			if field.ionType != ion.SymbolType || field.isNull {
+				return operation, &InvalidPatchError{"an operation's op is not a symbol"}
+			}
+
+			symbol := field.val.(*ion.SymbolToken)
+			for kind, name := range operationKindNames {
+				if symbol.Text != nil && *symbol.Text == name {
+					operation.Kind = kind
+				}
+			}
+		case "path":
+			path, err := readPath(field)
+			if err != nil {
+				return operation, err
+			}
+
+			operation.Path = path
+			hasPath = true
+		case "value":
+			value, err := field.encoding()
+			if err != nil {
+				return operation, err
+			}
+
+			operation.Value = value
+		}
+	}
+
+	switch {
+	case operation.Kind == 0:
+		return operation, &InvalidPatchError{"an operation has no valid op"}
+	case !hasPath || len(operation.Path) == 0 || operation.Path[0].IsField:
+		return operation, &InvalidPatchError{fmt.Sprintf("%v has no path to a top-level value", operation.Kind)}
+	case (operation.Value == nil) != (operation.Kind == Remove):
+		return operation, &InvalidPatchError{fmt.Sprintf("%v %v has the wrong value", operation.Kind, operation.Path)}
+	case operation.Kind == Add && !operation.Path[len(operation.Path)-1].IsField:
+		return operation, &InvalidPatchError{fmt.Sprintf("add %v has no field name", operation.Path)}
+	case operation.Kind == Insert && operation.Path[len(operation.Path)-1].IsField:
+		return operation, &InvalidPatchError{fmt.Sprintf("insert %v has no index", operation.Path)}
+	}
+
+	return operation, nil
+}
+
+func readPath(n *node) (Path, error) {
+	if n.ionType != ion.ListType || n.isNull {
+		return nil, &InvalidPatchError{"an operation's path is not a list"}
+	}
+
+	path := make(Path, 0, len(n.children))
+	for _, element := range n.children {
+		switch {
+		case element.isNull:
+			return nil, &InvalidPatchError{"an operation's path has a null"}
+		case element.ionType == ion.StringType:
+			path = append(path, Field(*element.val.(*string)))
+		case element.ionType == ion.IntType && element.val.(*big.Int).IsInt64() && element.val.(*big.Int).Sign() >= 0:
+			path = append(path, Index(int(element.val.(*big.Int).Int64())))
+		default:
+			return nil, &InvalidPatchError{"an operation's path has a value that is neither a field name nor an index"}
+		}
+	}
+
+	return path, nil
+}
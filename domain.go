@@ -0,0 +1,70 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+import "encoding/binary"
+
+// DomainHasherProvider provides the IonHashers of another IonHasherProvider, bound to a domain: a
+// string naming the context that digests are computed for, e.g. cache-key or signature. Every
+// IonHasher that it provides, for top-level values, struct fields and the values within them
+// alike, begins with a tag holding the length and bytes of the domain, so that a digest computed
+// in one domain is never that of a value in another, or in none.
+type DomainHasherProvider struct {
+	hasherProvider IonHasherProvider
+	domain         string
+	tag            []byte
+}
+
+// NewDomainHasherProvider returns a new DomainHasherProvider that binds domain into the IonHashers
+// provided by hasherProvider. The domain is recorded by DigestMetadata.
+func NewDomainHasherProvider(hasherProvider IonHasherProvider, domain string) *DomainHasherProvider {
+	// The tag begins with the length of the domain as 8 big-endian bytes, so it never begins with the
+	// begin marker that every serialization of a value or struct field does.
+	tag := binary.BigEndian.AppendUint64(nil, uint64(len(domain)))
+	tag = append(tag, domain...)
+
+	return &DomainHasherProvider{hasherProvider: hasherProvider, domain: domain, tag: tag}
+}
+
+// NewHasher returns a new IonHasher of the underlying provider that has been written the domain's tag.
+func (dhp *DomainHasherProvider) NewHasher() (IonHasher, error) {
+	ionHasher, err := dhp.hasherProvider.NewHasher()
+	if err != nil {
+		return nil, err
+	}
+
+	dh := &domainHasher{IonHasher: ionHasher, tag: dhp.tag}
+	_, err = dh.IonHasher.Write(dh.tag)
+	if err != nil {
+		return nil, err
+	}
+
+	return dh, nil
+}
+
+// domainHasher is an IonHasher that writes the tag of its domain whenever it is reset, so that the
+// tag begins every digest that it computes.
+type domainHasher struct {
+	IonHasher
+	tag []byte
+}
+
+func (dh *domainHasher) Reset() {
+	dh.IonHasher.Reset()
+
+	// The hashes of the standard library, like the io.Writer of an IonHasher, never return errors.
+	_, _ = dh.IonHasher.Write(dh.tag)
+}
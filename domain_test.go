@@ -0,0 +1,136 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+import (
+	"crypto/sha256"
+	"strings"
+	"testing"
+
+	"github.com/amzn/ion-go/ion"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// domainDigests returns the digests of the top-level values of text, computed in domain, failing
+// unless a HashReader and a BinaryHashReader agree.
+func domainDigests(t *testing.T, text, domain string) [][]byte {
+	hasherProvider := NewDomainHasherProvider(NewCryptoHasherProvider(SHA256), domain)
+
+	ionHashReader, err := NewHashReader(ion.NewReaderString(text), hasherProvider)
+	require.NoError(t, err, "Expected NewHashReader() to successfully create a HashReader")
+
+	var readerSums [][]byte
+	for ionHashReader.Next() {
+		sum, err := ionHashReader.Sum(nil)
+		require.NoError(t, err, "Something went wrong executing ionHashReader.Sum(nil)")
+		readerSums = append(readerSums, sum)
+	}
+	require.NoError(t, ionHashReader.Err(), "Something went wrong executing ionHashReader.Next()")
+
+	// A HashReader's sum is that of the value before the current one.
+	sum, err := ionHashReader.Sum(nil)
+	require.NoError(t, err, "Something went wrong executing ionHashReader.Sum(nil)")
+	readerSums = append(readerSums[1:], sum)
+
+	binarySums, err := HashBinary(toBinary(t, text), hasherProvider)
+	require.NoError(t, err, "Something went wrong executing HashBinary()")
+	assert.Equal(t, binarySums, readerSums, "HashBinary sums did not match HashReader sums of %s", text)
+
+	return binarySums
+}
+
+func TestDomainHasherProvider(t *testing.T) {
+	// The int 1 is serialized as its begin marker, type qualifier, representation and end marker.
+	serialization := []byte{beginMarkerByte, 0x20, 0x01, endMarkerByte}
+	plain := sha256.Sum256(serialization)
+	assert.Equal(t, [][]byte{plain[:]}, hashValues(t, `1`))
+
+	tag := append([]byte{0, 0, 0, 0, 0, 0, 0, 5}, "cache"...)
+	domain := sha256.Sum256(append(tag, serialization...))
+	assert.Equal(t, [][]byte{domain[:]}, domainDigests(t, `1`, "cache"))
+}
+
+func TestDomainHasherProviderSeparatesDigests(t *testing.T) {
+	for _, text := range []string{`1`, `{a:1, b:[2, {c:3}]}`, `a::(b {c:d})`, `null`} {
+		t.Run(text, func(t *testing.T) {
+			cache := domainDigests(t, text, "cache")
+			assert.Equal(t, cache, domainDigests(t, text, "cache"))
+			assert.NotEqual(t, cache, domainDigests(t, text, "signature"))
+			assert.NotEqual(t, cache, domainDigests(t, text, ""))
+			assert.NotEqual(t, cache, hashValues(t, text))
+		})
+	}
+}
+
+func TestDomainHasherProviderFieldHashes(t *testing.T) {
+	text := `{order:{id:1, status:"paid"}, x:2}`
+	cache := NewDomainHasherProvider(NewCryptoHasherProvider(SHA256), "cache")
+	signature := NewDomainHasherProvider(NewCryptoHasherProvider(SHA256), "signature")
+
+	// The digests of struct fields are bound to the domain too, so a proof made in one domain
+	// doesn't verify in another.
+	proof, err := NewProof(ion.NewReaderString(text), cache, "order.status")
+	require.NoError(t, err, "Something went wrong executing NewProof()")
+
+	verified, err := Verify(proof, domainDigests(t, text, "cache")[0], cache)
+	require.NoError(t, err, "Something went wrong executing Verify()")
+	assert.True(t, verified)
+
+	verified, err = Verify(proof, domainDigests(t, text, "signature")[0], signature)
+	require.NoError(t, err, "Something went wrong executing Verify()")
+	assert.False(t, verified)
+
+	// Neither do the placeholders of fields redacted in one domain stand for them in another.
+	var redacted strings.Builder
+	writer := ion.NewTextWriter(&redacted)
+	require.NoError(t, Redact(ion.NewReaderString(text), writer, cache, []string{"order.status"}))
+	require.NoError(t, writer.Finish())
+
+	digests, err := HashBinary(toBinary(t, redacted.String()), cache, WithRedactions())
+	require.NoError(t, err, "Something went wrong executing HashBinary()")
+	assert.Equal(t, domainDigests(t, text, "cache"), digests)
+
+	digests, err = HashBinary(toBinary(t, redacted.String()), signature, WithRedactions())
+	require.NoError(t, err, "Something went wrong executing HashBinary()")
+	assert.NotEqual(t, domainDigests(t, text, "signature"), digests)
+}
+
+func TestDomainHasherReset(t *testing.T) {
+	hasherProvider := NewDomainHasherProvider(NewCryptoHasherProvider(SHA256), "cache")
+	ionHasher, err := hasherProvider.NewHasher()
+	require.NoError(t, err, "Something went wrong executing NewHasher()")
+	empty := ionHasher.Sum(nil)
+
+	_, err = ionHasher.Write([]byte{1, 2, 3})
+	require.NoError(t, err)
+	assert.NotEqual(t, empty, ionHasher.Sum(nil))
+
+	ionHasher.Reset()
+	assert.Equal(t, empty, ionHasher.Sum(nil))
+}
+
+func TestDigestMetadataDomain(t *testing.T) {
+	hasherProvider := NewDomainHasherProvider(NewCryptoHasherProvider(SHA256), "cache")
+
+	ionHashReader, err := NewHashReader(ion.NewReaderString("1"), hasherProvider, WithStats(&Stats{}))
+	require.NoError(t, err, "Expected NewHashReader() to successfully create a HashReader")
+	assert.Equal(t, DigestMetadata{Domain: "cache"}, ionHashReader.Metadata())
+
+	ionHashWriter, err := NewHashWriter(ion.NewTextWriter(&strings.Builder{}), hasherProvider)
+	require.NoError(t, err, "Expected NewHashWriter() to successfully create a HashWriter")
+	assert.Equal(t, DigestMetadata{Domain: "cache"}, ionHashWriter.Metadata())
+}
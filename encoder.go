@@ -0,0 +1,416 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+import (
+	"encoding/binary"
+	"math"
+	"math/big"
+	"math/bits"
+	"time"
+
+	"github.com/amzn/ion-go/ion"
+)
+
+// Type qualifiers (the T nibble of an Ion binary type descriptor) of the scalar types.
+const (
+	tqBool      = 0x10
+	tqPosInt    = 0x20
+	tqNegInt    = 0x30
+	tqFloat     = 0x40
+	tqDecimal   = 0x50
+	tqTimestamp = 0x60
+	tqSymbol    = 0x70
+	tqString    = 0x80
+	tqClob      = 0x90
+	tqBlob      = 0xA0
+
+	// nullLengthNibble is the L nibble that marks a typed null.
+	nullLengthNibble = 0x0F
+)
+
+// appendScalar appends the representation of a scalar value to b, as defined by
+// https://amzn.github.io/ion-hash/docs/spec.html#3-serialization, and returns the
+// type qualifier that goes with it. The representation is the Ion binary encoding of
+// the value without its type descriptor and length, and it is returned unescaped.
+func appendScalar(b []byte, ionType ion.Type, ionValue interface{}, isNull bool) (byte, []byte, error) {
+	if isNull {
+		return nullTypeQualifier(ionType), b, nil
+	}
+
+	if encoded, ok := ionValue.(*encodedScalar); ok {
+		return encoded.tq, append(b, encoded.representation...), nil
+	}
+
+	switch ionType {
+	case ion.NullType:
+		return nullTypeQualifier(ion.NullType), b, nil
+	case ion.BoolType:
+		return appendBool(b, ionValue)
+	case ion.IntType:
+		return appendInt(b, ionValue)
+	case ion.FloatType:
+		return appendFloat(b, ionValue)
+	case ion.DecimalType:
+		if ionDecimal, ok := ionValue.(*ion.Decimal); ok && ionDecimal != nil {
+			return tqDecimal, appendDecimal(b, ionDecimal), nil
+		}
+
+		return 0, b, &InvalidArgumentError{"ionValue", ionValue}
+	case ion.TimestampType:
+		if ionTimestamp, ok := ionValue.(ion.Timestamp); ok {
+			return tqTimestamp, appendTimestamp(b, ionTimestamp), nil
+		}
+
+		if ionTimestamp, ok := ionValue.(*ion.Timestamp); ok {
+			return tqTimestamp, appendTimestamp(b, *ionTimestamp), nil
+		}
+
+		return 0, b, &InvalidArgumentError{"ionValue", ionValue}
+	case ion.SymbolType:
+		return appendSymbol(b, ionValue)
+	case ion.StringType:
+		if ionValueStr, ok := ionValue.(string); ok {
+			return tqString, append(b, ionValueStr...), nil
+		}
+
+		if ionValueStr, ok := ionValue.(*string); ok {
+			return tqString, append(b, *ionValueStr...), nil
+		}
+
+		return 0, b, &InvalidArgumentError{"ionValue", ionValue}
+	case ion.ClobType:
+		if ionClob, ok := ionValue.([]byte); ok {
+			return tqClob, append(b, ionClob...), nil
+		}
+
+		return 0, b, &InvalidArgumentError{"ionValue", ionValue}
+	case ion.BlobType:
+		if ionBlob, ok := ionValue.([]byte); ok {
+			return tqBlob, append(b, ionBlob...), nil
+		}
+
+		return 0, b, &InvalidArgumentError{"ionValue", ionValue}
+	}
+
+	return 0, b, &InvalidIonTypeError{ionType}
+}
+
+// nullTypeQualifier returns the type qualifier of a null of the given type, e.g. 0x2F for null.int.
+func nullTypeQualifier(ionType ion.Type) byte {
+	var typeCode byte
+	if ionType <= ion.IntType {
+		// The Ion binary encodings of NoType, NullType, BoolType, and IntType
+		// differ from their enum values by one.
+		typeCode = byte(ionType - 1)
+	} else {
+		typeCode = byte(ionType)
+	}
+
+	return (typeCode << 4) | nullLengthNibble
+}
+
+// appendBool returns the type qualifier of a bool. Bools have no representation;
+// the value is carried in the L nibble of the type qualifier.
+func appendBool(b []byte, ionValue interface{}) (byte, []byte, error) {
+	var val bool
+	switch v := ionValue.(type) {
+	case bool:
+		val = v
+	case *bool:
+		val = *v
+	default:
+		return 0, b, &InvalidArgumentError{"ionValue", ionValue}
+	}
+
+	if val {
+		return tqBool | 0x01, b, nil
+	}
+
+	return tqBool, b, nil
+}
+
+// appendInt appends the magnitude of an int. The sign is carried in the type qualifier.
+func appendInt(b []byte, ionValue interface{}) (byte, []byte, error) {
+	var val int64
+	switch v := ionValue.(type) {
+	case int:
+		val = int64(v)
+	case *int:
+		val = int64(*v)
+	case int64:
+		val = v
+	case *int64:
+		val = *v
+	case int32:
+		val = int64(v)
+	case *int32:
+		val = int64(*v)
+	case uint32:
+		val = int64(v)
+	case *uint32:
+		val = int64(*v)
+	case uint64:
+		return tqPosInt, appendUint(b, v), nil
+	case *uint64:
+		return tqPosInt, appendUint(b, *v), nil
+	case *big.Int:
+		return appendBigInt(b, v)
+	default:
+		return 0, b, &InvalidArgumentError{"ionValue", ionValue}
+	}
+
+	if val < 0 {
+		// Negating math.MinInt64 overflows back to itself, which is still the right magnitude as a uint64.
+		return tqNegInt, appendUint(b, uint64(-val)), nil
+	}
+
+	return tqPosInt, appendUint(b, uint64(val)), nil
+}
+
+func appendBigInt(b []byte, val *big.Int) (byte, []byte, error) {
+	if val.Sign() < 0 {
+		return tqNegInt, appendMagnitude(b, val), nil
+	}
+
+	return tqPosInt, appendMagnitude(b, val), nil
+}
+
+// appendFloat appends the 64-bit big-endian IEEE-754 encoding of a float. Positive zero
+// has an empty representation, and every NaN is encoded as the canonical quiet NaN.
+func appendFloat(b []byte, ionValue interface{}) (byte, []byte, error) {
+	var val float64
+	switch v := ionValue.(type) {
+	case float64:
+		val = v
+	case *float64:
+		val = *v
+	case float32:
+		val = float64(v)
+	case *float32:
+		val = float64(*v)
+	default:
+		return 0, b, &InvalidArgumentError{"ionValue", ionValue}
+	}
+
+	switch {
+	case val == 0 && !math.Signbit(val):
+		return tqFloat, b, nil
+	case math.IsNaN(val):
+		return tqFloat, binary.BigEndian.AppendUint64(b, canonicalNaNBits), nil
+	}
+
+	return tqFloat, binary.BigEndian.AppendUint64(b, math.Float64bits(val)), nil
+}
+
+// canonicalNaNBits is the quiet NaN that all NaN values are hashed as.
+const canonicalNaNBits = 0x7FF8000000000000
+
+// appendDecimal appends the exponent of a decimal as a VarInt followed by its
+// coefficient as a signed Int. The decimal 0d0 has an empty representation.
+func appendDecimal(b []byte, val *ion.Decimal) []byte {
+	coefficient, exponent := val.CoEx()
+
+	if coefficient.Sign() == 0 {
+		// ion.Decimal does not expose its negative zero flag, but its text form does.
+		negZero := val.String()[0] == '-'
+		if exponent == 0 && !negZero {
+			return b
+		}
+
+		b = appendVarInt(b, int64(exponent))
+		if negZero {
+			b = append(b, 0x80)
+		}
+
+		return b
+	}
+
+	b = appendVarInt(b, int64(exponent))
+
+	// A signed Int needs a spare high bit for the sign, so a magnitude that fills its
+	// leading byte gets an extra byte in front of it.
+	start := len(b)
+	if coefficient.BitLen()%8 == 0 {
+		b = append(b, 0x00)
+	}
+
+	b = appendMagnitude(b, coefficient)
+	if coefficient.Sign() < 0 {
+		b[start] |= 0x80
+	}
+
+	return b
+}
+
+// appendTimestamp appends the offset in minutes as a VarInt (or negative zero for an unknown
+// offset) followed by the UTC components that the timestamp's precision calls for.
+func appendTimestamp(b []byte, val ion.Timestamp) []byte {
+	dateTime := val.GetDateTime()
+	_, offset := dateTime.Zone()
+
+	return appendTimestampParts(b, val.GetTimezoneKind() == ion.TimezoneUnspecified, offset/60, dateTime.In(time.UTC),
+		val.GetPrecision(), val.GetNumberOfFractionalSeconds(), val.TruncatedNanoseconds())
+}
+
+// appendTimestampParts appends the representation of a timestamp given its components, where fraction
+// holds the fractional seconds as an integer with the given number of digits.
+func appendTimestampParts(b []byte, unknownOffset bool, offset int, utc time.Time,
+	precision ion.TimestampPrecision, fractionDigits uint8, fraction int) []byte {
+
+	if unknownOffset {
+		b = append(b, 0xC0)
+	} else {
+		b = appendVarInt(b, int64(offset))
+	}
+
+	b = appendVarUint(b, uint64(utc.Year()))
+
+	if precision >= ion.TimestampPrecisionMonth {
+		b = appendVarUint(b, uint64(utc.Month()))
+	}
+	if precision >= ion.TimestampPrecisionDay {
+		b = appendVarUint(b, uint64(utc.Day()))
+	}
+	if precision >= ion.TimestampPrecisionMinute {
+		b = appendVarUint(b, uint64(utc.Hour()))
+		b = appendVarUint(b, uint64(utc.Minute()))
+	}
+	if precision >= ion.TimestampPrecisionSecond {
+		b = appendVarUint(b, uint64(utc.Second()))
+	}
+
+	if precision == ion.TimestampPrecisionNanosecond && fractionDigits > 0 {
+		// The fractional seconds are a decimal with a negative exponent.
+		b = appendVarInt(b, -int64(fractionDigits))
+
+		if fraction > 0 {
+			b = appendSignedUint(b, uint64(fraction))
+		}
+	}
+
+	return b
+}
+
+// appendSymbol appends the text of a symbol. Symbols are serialized as strings, apart from
+// the type qualifier, which is 0x71 for a symbol with unknown text and SID 0.
+func appendSymbol(b []byte, ionValue interface{}) (byte, []byte, error) {
+	var token *ion.SymbolToken
+	switch v := ionValue.(type) {
+	case string:
+		return tqSymbol, append(b, v...), nil
+	case *string:
+		return tqSymbol, append(b, *v...), nil
+	case ion.SymbolToken:
+		token = &v
+	case *ion.SymbolToken:
+		token = v
+	default:
+		return 0, b, &InvalidArgumentError{"ionValue", ionValue}
+	}
+
+	if token.Text != nil {
+		return tqSymbol, append(b, *token.Text...), nil
+	}
+
+	if token.LocalSID == 0 {
+		return tqSymbol | 0x01, b, nil
+	}
+
+	return tqSymbol, b, nil
+}
+
+// appendUint appends v as a big-endian UInt using as few bytes as possible.
+// Zero has an empty encoding.
+func appendUint(b []byte, v uint64) []byte {
+	for shift := (bits.Len64(v) + 7) / 8 * 8; shift > 0; shift -= 8 {
+		b = append(b, byte(v>>(shift-8)))
+	}
+
+	return b
+}
+
+// appendSignedUint appends the non-negative value v as a big-endian signed Int
+// using as few bytes as possible.
+func appendSignedUint(b []byte, v uint64) []byte {
+	if bits.Len64(v)%8 == 0 {
+		b = append(b, 0x00)
+	}
+
+	return appendUint(b, v)
+}
+
+// appendMagnitude appends the absolute value of v as a big-endian UInt using as few bytes as possible.
+func appendMagnitude(b []byte, v *big.Int) []byte {
+	if v.IsUint64() {
+		return appendUint(b, v.Uint64())
+	}
+
+	n := (v.BitLen() + 7) / 8
+	start := len(b)
+	for i := 0; i < n; i++ {
+		b = append(b, 0)
+	}
+
+	v.FillBytes(b[start:])
+	return b
+}
+
+// appendVarUint appends v as a VarUInt: seven bits per byte, most significant group first,
+// with the high bit set on the last byte.
+func appendVarUint(b []byte, v uint64) []byte {
+	groups := 1
+	for rest := v >> 7; rest > 0; rest >>= 7 {
+		groups++
+	}
+
+	for i := groups - 1; i > 0; i-- {
+		b = append(b, byte(v>>(7*i))&0x7F)
+	}
+
+	return append(b, byte(v&0x7F)|0x80)
+}
+
+// appendVarInt appends v as a VarInt, which is a VarUInt of the magnitude whose
+// first byte reserves its second-highest bit for the sign.
+func appendVarInt(b []byte, v int64) []byte {
+	sign := byte(0)
+	magnitude := uint64(v)
+	if v < 0 {
+		sign = 0x40
+		magnitude = uint64(-v)
+	}
+
+	groups := 1
+	for rest := magnitude >> 6; rest > 0; rest >>= 7 {
+		groups++
+	}
+
+	last := groups - 1
+	for i := last; i >= 0; i-- {
+		group := byte(magnitude>>(7*i)) & 0x7F
+		if i == last {
+			group = (group & 0x3F) | sign
+		}
+		if i == 0 {
+			group |= 0x80
+		}
+
+		b = append(b, group)
+	}
+
+	return b
+}
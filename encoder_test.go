@@ -0,0 +1,194 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/amzn/ion-go/ion"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type floatTest struct {
+	name           string
+	value          float64
+	representation []byte
+}
+
+// float64Bits returns the big-endian IEEE-754 binary64 representation of val.
+func float64Bits(val float64) []byte {
+	return binary.BigEndian.AppendUint64(nil, math.Float64bits(val))
+}
+
+func floatTests() []floatTest {
+	tests := []floatTest{
+		{"positive zero", 0, nil},
+		{"negative zero", math.Copysign(0, -1), []byte{0x80, 0, 0, 0, 0, 0, 0, 0}},
+		{"NaN", math.NaN(), []byte{0x7F, 0xF8, 0, 0, 0, 0, 0, 0}},
+		{"negative NaN", math.Float64frombits(0xFFF8000000000000), []byte{0x7F, 0xF8, 0, 0, 0, 0, 0, 0}},
+		{"signalling NaN", math.Float64frombits(0x7FF0000000000001), []byte{0x7F, 0xF8, 0, 0, 0, 0, 0, 0}},
+		{"NaN with payload", math.Float64frombits(0x7FFFFFFFFFFFFFFF), []byte{0x7F, 0xF8, 0, 0, 0, 0, 0, 0}},
+		{"positive infinity", math.Inf(1), []byte{0x7F, 0xF0, 0, 0, 0, 0, 0, 0}},
+		{"negative infinity", math.Inf(-1), []byte{0xFF, 0xF0, 0, 0, 0, 0, 0, 0}},
+		{"one", 1, []byte{0x3F, 0xF0, 0, 0, 0, 0, 0, 0}},
+		{"negative one", -1, []byte{0xBF, 0xF0, 0, 0, 0, 0, 0, 0}},
+	}
+
+	float64s := []float64{
+		0.1, -0.1, 1.5, 3.141592653589793, 1e300, -1e-300,
+		math.MaxFloat64, -math.MaxFloat64, math.SmallestNonzeroFloat64, -math.SmallestNonzeroFloat64,
+		0x1p-1022, math.Nextafter(1, 2), math.MaxInt64, math.MinInt64,
+	}
+	for _, val := range float64s {
+		tests = append(tests, floatTest{fmt.Sprintf("float64 %g", val), val, float64Bits(val)})
+	}
+
+	// float32 values are hashed as the float64 that they widen to, not as binary32.
+	float32s := []float32{
+		0.1, -0.1, 1.5, 3.1415927, 1e30, -1e-30,
+		math.MaxFloat32, -math.MaxFloat32, math.SmallestNonzeroFloat32, 0x1p-126, 16777216,
+	}
+	for _, val := range float32s {
+		tests = append(tests, floatTest{fmt.Sprintf("float32 %g", val), float64(val), float64Bits(float64(val))})
+	}
+
+	return tests
+}
+
+func expectedFloatSum(representation []byte) []byte {
+	sum := []byte{beginMarkerByte, tqFloat}
+	sum = appendEscaped(sum, representation)
+	return append(sum, endMarkerByte)
+}
+
+func TestAppendFloat(t *testing.T) {
+	for _, test := range floatTests() {
+		t.Run(test.name, func(t *testing.T) {
+			tq, representation, err := appendFloat(nil, test.value)
+			require.NoError(t, err, "Something went wrong executing appendFloat()")
+			assert.Equal(t, byte(tqFloat), tq)
+			assert.Equal(t, test.representation, representation)
+
+			_, representation, err = appendFloat(nil, &test.value)
+			require.NoError(t, err, "Something went wrong executing appendFloat()")
+			assert.Equal(t, test.representation, representation)
+
+			if float64(float32(test.value)) == test.value || math.IsNaN(test.value) {
+				_, representation, err = appendFloat(nil, float32(test.value))
+				require.NoError(t, err, "Something went wrong executing appendFloat()")
+				assert.Equal(t, test.representation, representation)
+			}
+		})
+	}
+
+	_, _, err := appendFloat(nil, "1.5")
+	assert.IsType(t, &InvalidArgumentError{}, err)
+}
+
+func TestHashWriterFloats(t *testing.T) {
+	tihp := newTestIonHasherProvider("identity")
+
+	for _, test := range floatTests() {
+		t.Run(test.name, func(t *testing.T) {
+			ionHashWriter, err := NewHashWriter(ion.NewBinaryWriter(&bytes.Buffer{}), tihp.getInstance())
+			require.NoError(t, err, "Expected NewHashWriter() to successfully create a HashWriter")
+
+			require.NoError(t, ionHashWriter.WriteFloat(test.value),
+				"Something went wrong executing ionHashWriter.WriteFloat()")
+
+			sum, err := ionHashWriter.Sum(nil)
+			require.NoError(t, err, "Something went wrong executing ionHashWriter.Sum(nil)")
+			assert.Equal(t, expectedFloatSum(test.representation), sum, "sum did not match expectation")
+		})
+	}
+}
+
+// binaryFloats returns the Ion binary encodings of val: an empty float for positive zero,
+// binary64, and binary32 if val can be represented exactly in 32 bits.
+func binaryFloats(val float64) map[string][]byte {
+	ivm := []byte{0xE0, 0x01, 0x00, 0xEA}
+	encodings := map[string][]byte{
+		"binary64": append(append(ivm, 0x48), float64Bits(val)...),
+	}
+
+	if val == 0 && !math.Signbit(val) {
+		encodings["empty"] = append(ivm, 0x40)
+	}
+	if float64(float32(val)) == val || math.IsNaN(val) {
+		bits32 := binary.BigEndian.AppendUint32(nil, math.Float32bits(float32(val)))
+		encodings["binary32"] = append(append(ivm, 0x44), bits32...)
+	}
+
+	return encodings
+}
+
+// textFloat returns val in Ion text.
+func textFloat(val float64) string {
+	switch {
+	case math.IsNaN(val):
+		return "nan"
+	case math.IsInf(val, 1):
+		return "+inf"
+	case math.IsInf(val, -1):
+		return "-inf"
+	}
+
+	text := fmt.Sprintf("%g", val)
+	if !strings.ContainsAny(text, "e") {
+		text += "e0"
+	}
+
+	return text
+}
+
+func TestHashReaderFloats(t *testing.T) {
+	tihp := newTestIonHasherProvider("identity")
+
+	for _, test := range floatTests() {
+		t.Run(test.name, func(t *testing.T) {
+			expected := expectedFloatSum(test.representation)
+
+			inputs := map[string]ion.Reader{"text": ion.NewReaderString(textFloat(test.value))}
+			for encoding, data := range binaryFloats(test.value) {
+				inputs[encoding] = ion.NewReaderBytes(data)
+
+				sums, err := HashBinary(data, tihp.getInstance())
+				require.NoError(t, err, "Something went wrong executing HashBinary() on %s", encoding)
+				assert.Equal(t, [][]byte{expected}, sums, "HashBinary() sum of %s did not match expectation", encoding)
+			}
+
+			for name, reader := range inputs {
+				ionHashReader, err := NewHashReader(reader, tihp.getInstance())
+				require.NoError(t, err, "Expected NewHashReader() to successfully create a HashReader")
+
+				require.True(t, ionHashReader.Next(), "Expected a value reading %s", name)
+				require.False(t, ionHashReader.IsNull(), "Expected a non-null value reading %s", name)
+				assert.False(t, ionHashReader.Next())
+				require.NoError(t, ionHashReader.Err(), "Something went wrong reading %s", name)
+
+				sum, err := ionHashReader.Sum(nil)
+				require.NoError(t, err, "Something went wrong executing ionHashReader.Sum(nil)")
+				assert.Equal(t, expected, sum, "sum of %s did not match expectation", name)
+			}
+		})
+	}
+}
@@ -0,0 +1,616 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/amzn/ion-go/ion"
+	"github.com/amzn/ion-hash-go/internal/ionvalue"
+)
+
+// A Difference is the first difference between two streams of Ion values that aren't equivalent.
+type Difference struct {
+	// Index is the index of the top-level value that differs, starting from zero.
+	Index int
+
+	// Path is the location of the differing values within the top-level value, written like the
+	// paths of HashErrors, or empty for the top-level values themselves.
+	Path string
+
+	// AType and BType are the types of the differing values, or NoType if there is no value there.
+	AType ion.Type
+	BType ion.Type
+
+	// A and B are the differing values, written as Ion text, or empty if there is no value there.
+	A string
+	B string
+}
+
+// String returns a description of the difference, e.g.
+// top-level value 0, path items[1].sku: a has string "x", b has symbol x.
+func (d *Difference) String() string {
+	describe := func(ionType ion.Type, val string) string {
+		if ionType == ion.NoType {
+			return "no value"
+		}
+
+		return ionType.String() + " " + val
+	}
+
+	location := fmt.Sprintf("top-level value %d", d.Index)
+	if d.Path != "" {
+		location += ", path " + d.Path
+	}
+
+	return fmt.Sprintf("%s: a has %s, b has %s", location, describe(d.AType, d.A), describe(d.BType, d.B))
+}
+
+// Equivalent reports whether the streams of Ion values that a and b read are equivalent under the
+// Ion data model, e.g. the same data written as Ion text and as Ion binary, or structs with the
+// same fields in different orders. It compares digests of each pair of top-level values, and if they
+// differ, compares the digests of their fields or elements to find the first subtree that differs,
+// which it returns as a Difference. The digest of every subtree is computed once, from those of the
+// values in it, rather than as the Ion Hash of the subtree. Each pair of top-level values is held in
+// memory while it is compared.
+func Equivalent(a, b ion.Reader) (bool, *Difference, error) {
+	c := &comparison{hasherProvider: NewCryptoHasherProvider(SHA256)}
+
+	for index := 0; ; index++ {
+		nodeA, err := readTopLevelNode(a)
+		if err != nil {
+			return false, nil, err
+		}
+
+		nodeB, err := readTopLevelNode(b)
+		if err != nil {
+			return false, nil, err
+		}
+
+		if nodeA == nil && nodeB == nil {
+			return true, nil, nil
+		}
+
+		err = c.digestTree(nodeA)
+		if err != nil {
+			return false, nil, err
+		}
+
+		err = c.digestTree(nodeB)
+		if err != nil {
+			return false, nil, err
+		}
+
+		difference, err := c.difference(nodeA, nodeB)
+		if err != nil {
+			return false, nil, err
+		}
+
+		if difference != nil {
+			difference.Index = index
+			return false, difference, nil
+		}
+	}
+}
+
+// comparison finds the first difference between two values from the digests of their subtrees.
+type comparison struct {
+	hasherProvider IonHasherProvider
+}
+
+// difference returns the first difference between a and b, or nil if they are equivalent. Either
+// may be nil if there is no value there.
+func (c *comparison) difference(a, b *node) (*Difference, error) {
+	if a != nil && b != nil && bytes.Equal(a.digest, b.digest) {
+		return nil, nil
+	}
+
+	// The values differ, so descend through the containers that they are until the values in them
+	// that differ can't be told apart any further.
+	var path strings.Builder
+	for {
+		switch {
+		case a == nil || b == nil:
+			return newDifference(a, b, path.String())
+		case a.ionType != b.ionType || a.isNull || b.isNull || !ion.IsContainer(a.ionType):
+			return newDifference(a, b, path.String())
+		case !sameSymbols(a.annotations, b.annotations):
+			return newDifference(a, b, path.String())
+		}
+
+		var childA, childB *node
+		if a.ionType == ion.StructType {
+			childA, childB = fieldDifference(a, b)
+			if childA == nil && childB == nil {
+				return newDifference(a, b, path.String())
+			}
+
+			if childA != nil {
+				appendPathField(&path, childA.fieldName)
+			} else {
+				appendPathField(&path, childB.fieldName)
+			}
+		} else {
+			index := elementDifference(a, b)
+			if index < 0 {
+				return newDifference(a, b, path.String())
+			}
+
+			if index < len(a.children) {
+				childA = a.children[index]
+			}
+			if index < len(b.children) {
+				childB = b.children[index]
+			}
+			fmt.Fprintf(&path, "[%d]", index)
+		}
+
+		a, b = childA, childB
+	}
+}
+
+// elementDifference returns the index of the first element of the sequences a and b that differs, or
+// -1 if none does.
+func elementDifference(a, b *node) int {
+	for i := 0; i < len(a.children) || i < len(b.children); i++ {
+		if i >= len(a.children) || i >= len(b.children) || !bytes.Equal(a.children[i].digest, b.children[i].digest) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// fieldDifference returns the first pair of fields of the structs a and b that differ, either of
+// which is nil if there is no field there, or two nils if none do. Fields that are equivalent are
+// paired off, after which the first remaining field of a is paired with the first remaining field
+// of b with the same name, if any.
+func fieldDifference(a, b *node) (*node, *node) {
+	paired := make([]bool, len(b.children))
+	var unpaired []*node
+	for _, fieldA := range a.children {
+		match := -1
+		for j, fieldB := range b.children {
+			if !paired[j] && sameSymbol(fieldA.fieldName, fieldB.fieldName) && bytes.Equal(fieldA.digest, fieldB.digest) {
+				match = j
+				break
+			}
+		}
+
+		if match >= 0 {
+			paired[match] = true
+		} else {
+			unpaired = append(unpaired, fieldA)
+		}
+	}
+
+	for _, fieldA := range unpaired {
+		for j, fieldB := range b.children {
+			if !paired[j] && sameSymbol(fieldA.fieldName, fieldB.fieldName) {
+				return fieldA, fieldB
+			}
+		}
+
+		return fieldA, nil
+	}
+
+	for j, fieldB := range b.children {
+		if !paired[j] {
+			return nil, fieldB
+		}
+	}
+
+	return nil, nil
+}
+
+// digestTree computes the digest of every value in the tree of root, if any, from the bottom up.
+// The digest of a scalar, or of a null, is its Ion Hash. The digest of a container is serialized as
+// its Ion Hash is, but from the digests of its elements, or of its fields together with their names,
+// rather than from the elements themselves, so that the digests are computed in a single pass.
+func (c *comparison) digestTree(root *node) error {
+	if root == nil {
+		return nil
+	}
+
+	type frame struct {
+		n    *node
+		next int
+	}
+
+	stack := []frame{{n: root}}
+	for len(stack) > 0 {
+		top := &stack[len(stack)-1]
+		if !top.n.isNull && top.next < len(top.n.children) {
+			// The digests of the values in a container are computed before its own.
+			top.next++
+			stack = append(stack, frame{n: top.n.children[top.next-1]})
+			continue
+		}
+
+		stack = stack[:len(stack)-1]
+		digest, err := c.nodeDigest(top.n)
+		if err != nil {
+			return err
+		}
+
+		top.n.digest = digest
+	}
+
+	return nil
+}
+
+// nodeDigest returns the digest of n, given the digests of the values in it.
+func (c *comparison) nodeDigest(n *node) ([]byte, error) {
+	hashFunction, err := c.hasherProvider.NewHasher()
+	if err != nil {
+		return nil, err
+	}
+
+	// The node is serialized as though it were a top-level value, without its field name.
+	serializer := &scalarSerializer{baseSerializer{hashFunction: hashFunction, limiter: &limiter{}}}
+	if n.isNull || !ion.IsContainer(n.ionType) {
+		err = serializer.scalar(n)
+		if err != nil {
+			return nil, err
+		}
+
+		return serializer.sum(nil), nil
+	}
+
+	digests := make([][]byte, len(n.children))
+	for i, child := range n.children {
+		digests[i] = child.digest
+		if n.ionType == ion.StructType {
+			digests[i], err = c.fieldDigest(child)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if n.ionType == ion.StructType {
+		sort.Sort(sortableBytes(digests))
+	}
+
+	err = serializer.stepIn(n)
+	for _, digest := range digests {
+		if err == nil {
+			err = serializer.writeEscaped(digest)
+		}
+	}
+	if err == nil {
+		err = serializer.stepOut()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return serializer.sum(nil), nil
+}
+
+// fieldDigest returns the digest of the field n from its name and the digest of its value.
+func (c *comparison) fieldDigest(n *node) ([]byte, error) {
+	hashFunction, err := c.hasherProvider.NewHasher()
+	if err != nil {
+		return nil, err
+	}
+
+	serializer := &baseSerializer{hashFunction: hashFunction, depth: 1, limiter: &limiter{}}
+	err = serializer.handleFieldName(n)
+	if err == nil {
+		err = serializer.writeEscaped(n.digest)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return serializer.sum(nil), nil
+}
+
+// hashNode hashes n and the values in it.
+func hashNode(h *hasher, n *node) error {
+	if n.isNull || !ion.IsContainer(n.ionType) {
+		return h.scalar(n)
+	}
+
+	err := h.stepIn(n)
+	if err != nil {
+		return err
+	}
+
+	// The containers being hashed, and the index of the next value to hash in each.
+	type frame struct {
+		n    *node
+		next int
+	}
+
+	stack := []frame{{n: n}}
+	for len(stack) > 0 {
+		top := &stack[len(stack)-1]
+		if top.next == len(top.n.children) {
+			stack = stack[:len(stack)-1]
+			err = h.stepOut()
+		} else {
+			child := top.n.children[top.next]
+			top.next++
+
+			if child.isNull || !ion.IsContainer(child.ionType) {
+				err = h.scalar(child)
+			} else {
+				err = h.stepIn(child)
+				stack = append(stack, frame{n: child})
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// newDifference returns the Difference between a and b, which are at path.
+func newDifference(a, b *node, path string) (*Difference, error) {
+	difference := &Difference{Path: path}
+
+	var err error
+	difference.AType, difference.A, err = describeNode(a)
+	if err != nil {
+		return nil, err
+	}
+
+	difference.BType, difference.B, err = describeNode(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return difference, nil
+}
+
+// describeNode returns the type of n and n written as Ion text, or NoType and an empty string if n is nil.
+func describeNode(n *node) (ion.Type, string, error) {
+	if n == nil {
+		return ion.NoType, "", nil
+	}
+
+	var text strings.Builder
+	writer := ion.NewTextWriter(&text)
+	err := writeNode(writer, n)
+	if err != nil {
+		return n.ionType, "", err
+	}
+
+	err = writer.Finish()
+	if err != nil {
+		return n.ionType, "", err
+	}
+
+	return n.ionType, strings.TrimSpace(text.String()), nil
+}
+
+// writeNode writes n, without its field name, and the values in it.
+func writeNode(writer ion.Writer, n *node) error {
+	err := writeNodeStart(writer, n)
+	if err != nil || n.isNull || !ion.IsContainer(n.ionType) {
+		return err
+	}
+
+	// The containers being written, and the index of the next value to write in each.
+	type frame struct {
+		n    *node
+		next int
+	}
+
+	stack := []frame{{n: n}}
+	for len(stack) > 0 {
+		top := &stack[len(stack)-1]
+		if top.next == len(top.n.children) {
+			stack = stack[:len(stack)-1]
+			err = ionvalue.EndContainer(writer, top.n.ionType)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		child := top.n.children[top.next]
+		top.next++
+
+		if top.n.ionType == ion.StructType {
+			err = writer.FieldName(ionvalue.PortableSymbol(*child.fieldName))
+			if err != nil {
+				return err
+			}
+		}
+
+		err = writeNodeStart(writer, child)
+		if err != nil {
+			return err
+		}
+
+		if !child.isNull && ion.IsContainer(child.ionType) {
+			stack = append(stack, frame{n: child})
+		}
+	}
+
+	return nil
+}
+
+// writeNodeStart writes the annotations of n and then n if it is a scalar or a null, or the start of
+// the container that it is otherwise.
+func writeNodeStart(writer ion.Writer, n *node) error {
+	err := ionvalue.WriteAnnotations(writer, n.annotations)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case n.isNull:
+		return ionvalue.WriteNull(writer, n.ionType)
+	case ion.IsContainer(n.ionType):
+		return ionvalue.BeginContainer(writer, n.ionType)
+	}
+
+	return ionvalue.WriteScalar(writer, n.ionType, n.val)
+}
+
+// sameSymbol reports whether two symbols are the same, by their text if they have it.
+func sameSymbol(a, b *ion.SymbolToken) bool {
+	switch {
+	case a == nil || b == nil:
+		return a == b
+	case a.Text != nil && b.Text != nil:
+		return *a.Text == *b.Text
+	case a.Text == nil && b.Text == nil:
+		return a.LocalSID == b.LocalSID
+	}
+
+	return false
+}
+
+// sameSymbols reports whether two lists of symbols are the same.
+func sameSymbols(a, b []ion.SymbolToken) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if !sameSymbol(&a[i], &b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// readTopLevelNode reads the next top-level value of reader, or returns nil if there are no more.
+func readTopLevelNode(reader ion.Reader) (*node, error) {
+	if !reader.Next() {
+		return nil, readerError(reader.Err())
+	}
+
+	return readNode(reader, false)
+}
+
+// readNode reads the current value of reader and the values in it.
+func readNode(reader ion.Reader, inStruct bool) (*node, error) {
+	root, err := readNodeStart(reader, inStruct)
+	if err != nil || root.isNull || !ion.IsContainer(root.ionType) {
+		return root, err
+	}
+
+	// The containers being read, innermost last.
+	stack := []*node{root}
+	for len(stack) > 0 {
+		parent := stack[len(stack)-1]
+		if !reader.Next() {
+			if reader.Err() != nil {
+				return nil, readerError(reader.Err())
+			}
+
+			err = reader.StepOut()
+			if err != nil {
+				return nil, readerError(err)
+			}
+
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		child, err := readNodeStart(reader, parent.ionType == ion.StructType)
+		if err != nil {
+			return nil, err
+		}
+
+		parent.children = append(parent.children, child)
+		if !child.isNull && ion.IsContainer(child.ionType) {
+			stack = append(stack, child)
+		}
+	}
+
+	return root, nil
+}
+
+// readNodeStart reads the current value of reader if it is a scalar or a null, or steps in to the
+// container that it is otherwise.
+func readNodeStart(reader ion.Reader, inStruct bool) (*node, error) {
+	n := &node{ionType: reader.Type(), isNull: reader.IsNull(), inStruct: inStruct}
+
+	var err error
+	if inStruct {
+		n.fieldName, err = reader.FieldName()
+		if err != nil {
+			return nil, readerError(err)
+		}
+	}
+
+	n.annotations, err = reader.Annotations()
+	if err != nil {
+		return nil, readerError(err)
+	}
+
+	switch {
+	case n.isNull:
+		return n, nil
+	case !ion.IsContainer(n.ionType):
+		n.val, err = scalarValue(reader, n.ionType)
+		return n, err
+	}
+
+	return n, readerError(reader.StepIn())
+}
+
+// node is an Ion value held in memory, which is hashed as a hashValue.
+type node struct {
+	ionType     ion.Type
+	isNull      bool
+	inStruct    bool
+	fieldName   *ion.SymbolToken
+	annotations []ion.SymbolToken
+	val         interface{}
+	children    []*node
+
+	// digest is the digest of the node that Equivalent compares, once it has been computed.
+	digest []byte
+}
+
+func (n *node) getFieldName() (*ion.SymbolToken, error) {
+	return n.fieldName, nil
+}
+
+func (n *node) getAnnotations() ([]ion.SymbolToken, error) {
+	return n.annotations, nil
+}
+
+func (n *node) IsNull() bool {
+	return n.isNull
+}
+
+func (n *node) Type() ion.Type {
+	return n.ionType
+}
+
+func (n *node) value() (interface{}, error) {
+	return n.val, nil
+}
+
+func (n *node) IsInStruct() bool {
+	return n.inStruct
+}
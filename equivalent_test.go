@@ -0,0 +1,146 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/amzn/ion-go/ion"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEquivalent(t *testing.T) {
+	testCases := []struct {
+		a, b string
+	}{
+		{``, ``},
+		{`1 "a" {b:[c, (d e)]}`, `1 "a" {b:[c, (d e)]}`},
+		{`{a:1, b:{c:2, d:3}}`, `{b:{d:3, c:2}, a:1}`},
+		{`{a:1, a:2}`, `{a:2, a:1}`},
+		{`x::null.list 2020T`, `x::null.list 2020T`},
+	}
+
+	for _, tc := range testCases {
+		equivalent, difference, err := Equivalent(ion.NewReaderString(tc.a), ion.NewReaderString(tc.b))
+		require.NoError(t, err, "Something went wrong executing Equivalent()")
+		assert.True(t, equivalent, "Expected %s and %s to be equivalent", tc.a, tc.b)
+		assert.Nil(t, difference)
+	}
+}
+
+func TestEquivalentTextAndBinary(t *testing.T) {
+	text := `{id:1, tags:[a, "b"], price:12.50, at:2020-06-15T10:20:30Z, data:{{aGVsbG8=}}} big::123456789012345678901234567890`
+
+	equivalent, difference, err := Equivalent(ion.NewReaderString(text), ion.NewReaderBytes(toBinary(t, text)))
+	require.NoError(t, err, "Something went wrong executing Equivalent()")
+	assert.True(t, equivalent)
+	assert.Nil(t, difference)
+}
+
+func TestEquivalentDifferences(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected Difference
+	}{
+		{`{a:{b:[1, 2, 3]}}`, `{a:{b:[1, 2, 4]}}`, Difference{0, "a.b[2]", ion.IntType, ion.IntType, "3", "4"}},
+		{`1 {a:"1"}`, `1 {a:1}`, Difference{1, "a", ion.StringType, ion.IntType, `"1"`, "1"}},
+		{`{a:1, b:[2]}`, `{a:1}`, Difference{0, "b", ion.ListType, ion.NoType, "[2]", ""}},
+		{`{a:1}`, `{'first name':x, a:1}`, Difference{0, "'first name'", ion.NoType, ion.SymbolType, "", "x"}},
+		{`[1]`, `[1, {c:2}]`, Difference{0, "[1]", ion.NoType, ion.StructType, "", "{c:2}"}},
+		{`1 2`, `1`, Difference{1, "", ion.IntType, ion.NoType, "2", ""}},
+		{`a::[1]`, `b::[1]`, Difference{0, "", ion.ListType, ion.ListType, "a::[1]", "b::[1]"}},
+		{`{a:1, a:2}`, `{a:1, a:3}`, Difference{0, "a", ion.IntType, ion.IntType, "2", "3"}},
+		{`(a (b c))`, `(a (b d))`, Difference{0, "[1][1]", ion.SymbolType, ion.SymbolType, "c", "d"}},
+		{`{a:null}`, `{a:null.int}`, Difference{0, "a", ion.NullType, ion.IntType, "null", "null.int"}},
+	}
+
+	for _, tc := range testCases {
+		equivalent, difference, err := Equivalent(ion.NewReaderString(tc.a), ion.NewReaderString(tc.b))
+		require.NoError(t, err, "Something went wrong executing Equivalent()")
+		assert.False(t, equivalent, "Expected %s and %s not to be equivalent", tc.a, tc.b)
+		if assert.NotNil(t, difference) {
+			assert.Equal(t, tc.expected, *difference, "Difference between %s and %s did not match", tc.a, tc.b)
+		}
+	}
+}
+
+func TestDifferenceString(t *testing.T) {
+	difference := Difference{2, "items[1].sku", ion.StringType, ion.SymbolType, `"x"`, "x"}
+	assert.Equal(t, `top-level value 2, path items[1].sku: a has string "x", b has symbol x`, difference.String())
+
+	difference = Difference{0, "", ion.NoType, ion.IntType, "", "1"}
+	assert.Equal(t, `top-level value 0: a has no value, b has int 1`, difference.String())
+}
+
+func TestEquivalentReaderError(t *testing.T) {
+	_, _, err := Equivalent(ion.NewReaderString(`[1, 2`), ion.NewReaderString(`[1, 2]`))
+	assert.Error(t, err)
+}
+
+func TestEquivalentDeep(t *testing.T) {
+	equivalent, difference, err := Equivalent(ion.NewReaderBytes(nestedLists(100000)), ion.NewReaderBytes(nestedLists(100000)))
+	require.NoError(t, err, "Something went wrong executing Equivalent()")
+	assert.True(t, equivalent)
+	assert.Nil(t, difference)
+
+	equivalent, difference, err = Equivalent(ion.NewReaderBytes(nestedLists(100000)), ion.NewReaderBytes(nestedLists(99999)))
+	require.NoError(t, err, "Something went wrong executing Equivalent()")
+	assert.False(t, equivalent)
+	assert.Equal(t, &Difference{0, strings.Repeat("[0]", 99999), ion.ListType, ion.NoType, "[]", ""}, difference)
+}
+
+// countingHasherProvider counts the IonHashers that it provides.
+type countingHasherProvider struct {
+	IonHasherProvider
+	hashers int
+}
+
+func (chp *countingHasherProvider) NewHasher() (IonHasher, error) {
+	chp.hashers++
+	return chp.IonHasherProvider.NewHasher()
+}
+
+func TestEquivalentDigestsEachValueOnce(t *testing.T) {
+	n, err := readTopLevelNode(ion.NewReaderString(`{a:[1, {b:2}], c:x::(3)}`))
+	require.NoError(t, err, "Something went wrong executing readTopLevelNode()")
+
+	provider := &countingHasherProvider{IonHasherProvider: NewCryptoHasherProvider(SHA256)}
+	c := &comparison{hasherProvider: provider}
+	require.NoError(t, c.digestTree(n), "Something went wrong executing digestTree()")
+
+	// There are seven values, three of which are fields.
+	assert.Equal(t, 10, provider.hashers)
+}
+
+func TestWriteNodeFromBinary(t *testing.T) {
+	// Values read from Ion binary have the symbol IDs of its symbol table, which a new binary writer
+	// doesn't share.
+	data := toBinary(t, `{name:annotation::symbol, list:[other::(value)]}`)
+	n, err := readTopLevelNode(ion.NewReaderBytes(data))
+	require.NoError(t, err, "Something went wrong executing readTopLevelNode()")
+
+	var buf bytes.Buffer
+	writer := ion.NewBinaryWriter(&buf)
+	require.NoError(t, writeNode(writer, n), "Something went wrong executing writeNode()")
+	require.NoError(t, writer.Finish())
+
+	equivalent, difference, err := Equivalent(ion.NewReaderBytes(data), ion.NewReaderBytes(buf.Bytes()))
+	require.NoError(t, err, "Something went wrong executing Equivalent()")
+	assert.True(t, equivalent, "Expected the values written to be equivalent, but %v", difference)
+}
@@ -16,50 +16,260 @@
 package ionhash
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/amzn/ion-go/ion"
 )
 
+// Sentinel errors that the errors returned by this package match with errors.Is, e.g.,
+//
+//	if errors.Is(err, ionhash.ErrLimitExceeded) {
+//	    // ...
+//	}
+var (
+	ErrInvalidOperation = errors.New("ionhash: invalid operation")
+	ErrInvalidArgument  = errors.New("ionhash: invalid argument")
+	ErrInvalidIonType   = errors.New("ionhash: invalid Ion type")
+	ErrUnknownSymbol    = errors.New("ionhash: unknown symbol")
+	ErrMalformedBinary  = errors.New("ionhash: malformed Ion binary")
+	ErrLimitExceeded    = errors.New("ionhash: limit exceeded")
+	ErrReader           = errors.New("ionhash: reader error")
+	ErrKeyFieldMissing  = errors.New("ionhash: key field missing")
+	ErrFieldNotFound    = errors.New("ionhash: field not found")
+	ErrInvalidProof     = errors.New("ionhash: invalid proof")
+)
+
 // An InvalidOperationError is returned when a method call is invalid for the struct's current state.
 type InvalidOperationError struct {
-	structName string
-	methodName string
-	message    string
+	StructName string
+	MethodName string
+	Message    string
 }
 
 func (e *InvalidOperationError) Error() string {
-	if e.message != "" {
-		return fmt.Sprintf(`ionhash: Invalid operation at %v.%v: %v`, e.structName, e.methodName, e.message)
+	if e.Message != "" {
+		return fmt.Sprintf(`ionhash: Invalid operation at %v.%v: %v`, e.StructName, e.MethodName, e.Message)
 	}
 
-	return fmt.Sprintf(`ionhash: Invalid operation error in %v.%v`, e.structName, e.methodName)
+	return fmt.Sprintf(`ionhash: Invalid operation error in %v.%v`, e.StructName, e.MethodName)
+}
+
+// Is reports whether target is ErrInvalidOperation.
+func (e *InvalidOperationError) Is(target error) bool {
+	return target == ErrInvalidOperation
 }
 
 // InvalidArgumentError is returned when one of the arguments given to a function was not valid.
 type InvalidArgumentError struct {
-	argumentName  string
-	argumentValue interface{}
+	ArgumentName  string
+	ArgumentValue interface{}
 }
 
 func (e *InvalidArgumentError) Error() string {
-	return fmt.Sprintf(`ionhash: Invalid value: "%v" specified for argument: %s`, e.argumentValue, e.argumentName)
+	return fmt.Sprintf(`ionhash: Invalid value: "%v" specified for argument: %s`, e.ArgumentValue, e.ArgumentName)
+}
+
+// Is reports whether target is ErrInvalidArgument.
+func (e *InvalidArgumentError) Is(target error) bool {
+	return target == ErrInvalidArgument
 }
 
 // InvalidIonTypeError is returned when processing an unexpected Ion type.
 type InvalidIonTypeError struct {
-	ionType ion.Type
+	IonType ion.Type
 }
 
 func (e *InvalidIonTypeError) Error() string {
-	return fmt.Sprintf(`ionhash: Invalid Ion type: %s`, e.ionType.String())
+	return fmt.Sprintf(`ionhash: Invalid Ion type: %s`, e.IonType.String())
+}
+
+// Is reports whether target is ErrInvalidIonType.
+func (e *InvalidIonTypeError) Is(target error) bool {
+	return target == ErrInvalidIonType
 }
 
 // UnknownSymbolError is returned when processing an unknown field name symbol.
 type UnknownSymbolError struct {
-	sid int64
+	SID int64
 }
 
 func (e *UnknownSymbolError) Error() string {
-	return fmt.Sprintf(`ionhash: Unknown text for sid %d`, e.sid)
+	return fmt.Sprintf(`ionhash: Unknown text for sid %d`, e.SID)
+}
+
+// Is reports whether target is ErrUnknownSymbol.
+func (e *UnknownSymbolError) Is(target error) bool {
+	return target == ErrUnknownSymbol
+}
+
+// MalformedBinaryError is returned when Ion binary data cannot be decoded.
+type MalformedBinaryError struct {
+	Offset  int
+	Message string
+}
+
+func (e *MalformedBinaryError) Error() string {
+	return fmt.Sprintf(`ionhash: Malformed Ion binary at offset %d: %s`, e.Offset, e.Message)
+}
+
+// Is reports whether target is ErrMalformedBinary.
+func (e *MalformedBinaryError) Is(target error) bool {
+	return target == ErrMalformedBinary
+}
+
+// LimitExceededError is returned when hashing a value would exceed one of the configured Limits.
+type LimitExceededError struct {
+	// The name of the limit, e.g. LimitMaxDepth, and its value.
+	Limit string
+	Max   int64
+
+	// The index of the top-level value that exceeded the limit, and the depth within it.
+	Index int
+	Depth int
+}
+
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf(`%s at depth %d of top-level value %d`, e.limitMessage(), e.Depth, e.Index)
+}
+
+// limitMessage describes the limit that was exceeded, without saying where.
+func (e *LimitExceededError) limitMessage() string {
+	return fmt.Sprintf(`ionhash: Exceeded limit %s of %d`, e.Limit, e.Max)
+}
+
+// Is reports whether target is ErrLimitExceeded.
+func (e *LimitExceededError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// A HashError is returned when a value cannot be hashed, and records which value it was.
+type HashError struct {
+	// Path is the location of the value within its top-level value, e.g. orders[12].items[3].sku,
+	// or empty for the top-level value itself.
+	Path string
+
+	// Index is the index of the top-level value in the stream, starting from zero.
+	Index int
+
+	// Offset is the offset of the value in the input, or -1 if it isn't known.
+	Offset int64
+
+	// Err is the reason the value could not be hashed.
+	Err error
+}
+
+func (e *HashError) Error() string {
+	location := fmt.Sprintf("top-level value %d", e.Index)
+	if e.Path != "" {
+		location += ", path " + truncatePath(e.Path)
+	}
+	if e.Offset >= 0 {
+		location += fmt.Sprintf(", offset %d", e.Offset)
+	}
+
+	// The location of a LimitExceededError is already given by the HashError.
+	message := fmt.Sprint(e.Err)
+	if limitExceededError, ok := e.Err.(*LimitExceededError); ok {
+		message = limitExceededError.limitMessage()
+	}
+
+	return fmt.Sprintf(`%s (%s)`, message, location)
+}
+
+// maxErrorPathElements is the number of elements at each end of a path that HashError.Error keeps
+// when it shortens a long path.
+const maxErrorPathElements = 8
+
+// truncatePath shortens a path of more than twice maxErrorPathElements elements to its first and
+// last elements, e.g. [0][0][0] ... [0][0][0].
+func truncatePath(path string) string {
+	// Elements begin with [ or ., except the first, outside the quotes of quoted field names.
+	var starts []int
+	quoted := false
+	for i := 0; i < len(path); i++ {
+		switch {
+		case quoted && path[i] == '\\':
+			i++
+		case path[i] == '\'':
+			quoted = !quoted
+		case !quoted && i > 0 && (path[i] == '[' || path[i] == '.'):
+			starts = append(starts, i)
+		}
+	}
+
+	if len(starts) < 2*maxErrorPathElements {
+		return path
+	}
+
+	return path[:starts[maxErrorPathElements-1]] + " ... " + path[starts[len(starts)-maxErrorPathElements]:]
+}
+
+// Unwrap returns the reason the value could not be hashed.
+func (e *HashError) Unwrap() error {
+	return e.Err
+}
+
+// A ReaderError is returned by a HashReader when its underlying Ion reader fails, as
+// opposed to a failure to hash the values that it reads.
+type ReaderError struct {
+	Err error
+}
+
+func (e *ReaderError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error from the underlying Ion reader.
+func (e *ReaderError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrReader.
+func (e *ReaderError) Is(target error) bool {
+	return target == ErrReader
+}
+
+// KeyFieldMissingError is returned when a key digest cannot be computed because a value has no
+// field at one of the key's paths.
+type KeyFieldMissingError struct {
+	Path string
+}
+
+func (e *KeyFieldMissingError) Error() string {
+	return fmt.Sprintf(`ionhash: Missing key field at path %s`, e.Path)
+}
+
+// Is reports whether target is ErrKeyFieldMissing.
+func (e *KeyFieldMissingError) Is(target error) bool {
+	return target == ErrKeyFieldMissing
+}
+
+// FieldNotFoundError is returned when a proof cannot be made because a value has no field at the
+// path of the field to prove.
+type FieldNotFoundError struct {
+	Path string
+}
+
+func (e *FieldNotFoundError) Error() string {
+	return fmt.Sprintf(`ionhash: No field at path %s`, e.Path)
+}
+
+// Is reports whether target is ErrFieldNotFound.
+func (e *FieldNotFoundError) Is(target error) bool {
+	return target == ErrFieldNotFound
+}
+
+// InvalidProofError is returned when a proof is malformed, rather than merely not matching a digest.
+type InvalidProofError struct {
+	Message string
+}
+
+func (e *InvalidProofError) Error() string {
+	return fmt.Sprintf(`ionhash: Invalid proof: %s`, e.Message)
+}
+
+// Is reports whether target is ErrInvalidProof.
+func (e *InvalidProofError) Is(target error) bool {
+	return target == ErrInvalidProof
 }
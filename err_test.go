@@ -0,0 +1,168 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/amzn/ion-go/ion"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashErrorPath(t *testing.T) {
+	testCases := []struct {
+		text  string
+		path  string
+		index int
+	}{
+		{"a::b::1", "", 0},
+		{"1 [a::b::2]", "[0]", 1},
+		{"{orders: [{items: [1, {sku: a::b::c}]}]}", "orders[0].items[1].sku", 0},
+		{"(1 2 {'a b': [x, a::b::y]})", "[2].'a b'[1]", 0},
+		{`{'it\'s': a::b::{}}`, `'it\'s'`, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.text, func(t *testing.T) {
+			hr, err := NewHashReader(ion.NewReaderString(tc.text), NewCryptoHasherProvider(SHA256),
+				WithLimits(Limits{MaxAnnotations: 1}))
+			require.NoError(t, err, "Expected NewHashReader() to successfully create a HashReader")
+
+			for hr.Next() {
+			}
+
+			var hashError *HashError
+			require.True(t, errors.As(hr.Err(), &hashError), "Expected hr.Err() to be a HashError")
+			assert.Equal(t, tc.path, hashError.Path)
+			assert.Equal(t, tc.index, hashError.Index)
+			assert.Equal(t, int64(-1), hashError.Offset)
+			assert.True(t, errors.Is(hr.Err(), ErrLimitExceeded))
+			assert.False(t, errors.Is(hr.Err(), ErrReader))
+		})
+	}
+}
+
+func TestHashErrorOffset(t *testing.T) {
+	// [name::version::1]
+	data := []byte{0xE0, 0x01, 0x00, 0xEA, 0xB6, 0xE5, 0x82, 0x84, 0x85, 0x21, 0x01}
+
+	_, err := HashBinary(data, NewCryptoHasherProvider(SHA256), WithLimits(Limits{MaxAnnotations: 1}))
+
+	var hashError *HashError
+	require.True(t, errors.As(err, &hashError), "Expected HashBinary() to return a HashError")
+	assert.Equal(t, "[0]", hashError.Path)
+	assert.Equal(t, int64(5), hashError.Offset)
+	assert.Equal(t, "ionhash: Exceeded limit MaxAnnotations of 1 (top-level value 0, path [0], offset 5)", err.Error())
+	assert.Equal(t, "ionhash: Exceeded limit MaxAnnotations of 1 at depth 1 of top-level value 0",
+		hashError.Err.Error())
+}
+
+func TestHashErrorLongPath(t *testing.T) {
+	text := strings.Repeat("[", 101) + strings.Repeat("]", 101)
+	hr, err := NewHashReader(ion.NewReaderString(text), NewCryptoHasherProvider(SHA256), WithMaxDepth(100))
+	require.NoError(t, err, "Expected NewHashReader() to successfully create a HashReader")
+
+	for hr.Next() {
+	}
+
+	var hashError *HashError
+	require.True(t, errors.As(hr.Err(), &hashError), "Expected hr.Err() to be a HashError")
+	assert.Equal(t, strings.Repeat("[0]", 100), hashError.Path)
+
+	short := strings.Repeat("[0]", maxErrorPathElements)
+	assert.Equal(t, "ionhash: Exceeded limit MaxDepth of 100 (top-level value 0, path "+short+" ... "+short+")",
+		hr.Err().Error())
+}
+
+func TestTruncatePath(t *testing.T) {
+	testCases := []struct {
+		path, expected string
+	}{
+		{"", ""},
+		{"a.b[1].c", "a.b[1].c"},
+		{strings.Repeat("[0]", 2*maxErrorPathElements), strings.Repeat("[0]", 2*maxErrorPathElements)},
+		{"a" + strings.Repeat(".b", 2*maxErrorPathElements), "a" + strings.Repeat(".b", maxErrorPathElements-1) +
+			" ... " + strings.Repeat(".b", maxErrorPathElements)},
+		{`'x.[\'.'` + strings.Repeat("[1]", 2*maxErrorPathElements) + `.'y[.'`, `'x.[\'.'` +
+			strings.Repeat("[1]", maxErrorPathElements-1) + " ... " + strings.Repeat("[1]", maxErrorPathElements-1) + `.'y[.'`},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, truncatePath(tc.path))
+	}
+}
+
+func TestHashErrorUnknownFieldName(t *testing.T) {
+	hw, err := NewHashWriter(ion.NewTextWriter(&strings.Builder{}), NewCryptoHasherProvider(SHA256))
+	require.NoError(t, err, "Expected NewHashWriter() to successfully create a HashWriter")
+
+	require.NoError(t, hw.BeginStruct(), "Something went wrong executing hw.BeginStruct()")
+	require.NoError(t, hw.FieldName(ion.SymbolToken{LocalSID: 10}), "Something went wrong executing hw.FieldName()")
+
+	err = hw.WriteInt(1)
+
+	var unknownSymbolError *UnknownSymbolError
+	require.True(t, errors.As(err, &unknownSymbolError), "Expected hw.WriteInt() to return an UnknownSymbolError")
+	assert.Equal(t, int64(10), unknownSymbolError.SID)
+	assert.True(t, errors.Is(err, ErrUnknownSymbol))
+
+	var hashError *HashError
+	require.True(t, errors.As(err, &hashError), "Expected hw.WriteInt() to return a HashError")
+	assert.Equal(t, "$10", hashError.Path)
+}
+
+func TestReaderError(t *testing.T) {
+	hr, err := NewHashReader(ion.NewReaderString("[1, 2"), NewCryptoHasherProvider(SHA256))
+	require.NoError(t, err, "Expected NewHashReader() to successfully create a HashReader")
+
+	for hr.Next() {
+	}
+
+	var readerError *ReaderError
+	assert.True(t, errors.As(hr.Err(), &readerError), "Expected hr.Err() to be a ReaderError")
+	assert.True(t, errors.Is(hr.Err(), ErrReader))
+
+	var hashError *HashError
+	assert.False(t, errors.As(hr.Err(), &hashError), "Expected hr.Err() not to be a HashError")
+}
+
+func TestSentinelErrors(t *testing.T) {
+	testCases := []struct {
+		err      error
+		sentinel error
+	}{
+		{&InvalidOperationError{"hasher", "sum", ""}, ErrInvalidOperation},
+		{&InvalidArgumentError{"algorithm", "SHA0"}, ErrInvalidArgument},
+		{&InvalidIonTypeError{ion.NoType}, ErrInvalidIonType},
+		{&UnknownSymbolError{10}, ErrUnknownSymbol},
+		{&MalformedBinaryError{0, "invalid type descriptor"}, ErrMalformedBinary},
+		{&LimitExceededError{LimitMaxDepth, 1, 0, 1}, ErrLimitExceeded},
+		{&ReaderError{errors.New("EOF")}, ErrReader},
+		{&FieldNotFoundError{"status"}, ErrFieldNotFound},
+		{&InvalidProofError{"no levels"}, ErrInvalidProof},
+	}
+
+	for _, tc := range testCases {
+		assert.True(t, errors.Is(tc.err, tc.sentinel), "Expected %v to be %v", tc.err, tc.sentinel)
+		assert.True(t, errors.Is(&HashError{Offset: -1, Err: tc.err}, tc.sentinel),
+			"Expected a HashError wrapping %v to be %v", tc.err, tc.sentinel)
+		assert.False(t, errors.Is(tc.err, ErrInvalidArgument) && tc.sentinel != ErrInvalidArgument,
+			"Expected %v not to be %v", tc.err, ErrInvalidArgument)
+	}
+}
@@ -0,0 +1,35 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+import "github.com/amzn/ion-go/ion"
+
+// A FieldNameFunc returns the canonical name of a field, given the path of the struct that the
+// field is in, written like the paths of HashErrors, and the field's name. It returns name if the
+// field is not renamed.
+type FieldNameFunc func(path, name string) string
+
+// renamedField is a hashValue whose field name is replaced with its canonical name, which is what
+// handleFieldName serializes. It is reused between values.
+type renamedField struct {
+	hashValue
+	fieldName ion.SymbolToken
+	text      string
+}
+
+func (rf *renamedField) getFieldName() (*ion.SymbolToken, error) {
+	return &rf.fieldName, nil
+}
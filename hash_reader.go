@@ -87,6 +87,17 @@ type HashReader interface {
 	// Sum appends the current hash to b and returns the resulting slice.
 	// It resets the Hash to its initial state.
 	Sum(b []byte) ([]byte, error)
+
+	// KeyDigests returns the key digest of the same value as Sum for each KeySpec given with
+	// WithKeyDigests, in the same order, or nil if none were given.
+	KeyDigests() ([][]byte, error)
+
+	// SubtreeDigests returns the digests of the values within the same value as Sum whose paths
+	// match those given with WithSubtrees, in the order the values begin, or nil if there are none.
+	SubtreeDigests() ([]SubtreeDigest, error)
+
+	// Metadata returns a description of the settings that the digests depend on.
+	Metadata() DigestMetadata
 }
 
 type hashReader struct {
@@ -96,9 +107,9 @@ type hashReader struct {
 	err         error
 }
 
-// NewHashReader takes an Ion reader and a hash provider and returns a new HashReader.
-func NewHashReader(ionReader ion.Reader, hasherProvider IonHasherProvider) (HashReader, error) {
-	newHasher, err := newHasher(hasherProvider)
+// NewHashReader takes an Ion reader, a hash provider and any options and returns a new HashReader.
+func NewHashReader(ionReader ion.Reader, hasherProvider IonHasherProvider, opts ...Option) (HashReader, error) {
+	newHasher, err := newHasher(hasherProvider, newOptions(opts))
 	if err != nil {
 		return nil, err
 	}
@@ -136,7 +147,7 @@ func (hr *hashReader) Next() bool {
 				return false
 			}
 
-			hr.err = hr.StepOut()
+			hr.err = hr.stepOut()
 			if hr.err != nil {
 				return false
 			}
@@ -145,7 +156,7 @@ func (hr *hashReader) Next() bool {
 
 	next := hr.ionReader.Next()
 	if !next {
-		hr.err = hr.ionReader.Err()
+		hr.err = readerError(hr.ionReader.Err())
 	}
 
 	hr.currentType = hr.ionReader.Type()
@@ -153,7 +164,8 @@ func (hr *hashReader) Next() bool {
 	return next
 }
 
-// Err returns an error if a previous call to Next failed.
+// Err returns an error if a previous call to Next failed. An error from the underlying Ion
+// reader is a *ReaderError, and a failure to hash a value is a *HashError.
 func (hr *hashReader) Err() error {
 	return hr.err
 }
@@ -193,7 +205,7 @@ func (hr *hashReader) StepIn() error {
 
 	err = hr.ionReader.StepIn()
 	if err != nil {
-		return err
+		return readerError(err)
 	}
 
 	hr.currentType = ion.NoType
@@ -211,9 +223,14 @@ func (hr *hashReader) StepOut() error {
 		return err
 	}
 
-	err = hr.ionReader.StepOut()
+	return hr.stepOut()
+}
+
+// stepOut steps out of the current container once all of its values have been hashed.
+func (hr *hashReader) stepOut() error {
+	err := hr.ionReader.StepOut()
 	if err != nil {
-		return err
+		return readerError(err)
 	}
 
 	err = hr.hasher.stepOut()
@@ -298,82 +315,135 @@ func (hr *hashReader) Sum(b []byte) ([]byte, error) {
 	return hr.hasher.sum(b)
 }
 
+// KeyDigests returns the key digests of the same value as Sum.
+func (hr *hashReader) KeyDigests() ([][]byte, error) {
+	return hr.hasher.keyDigests()
+}
+
+// SubtreeDigests returns the subtree digests of the same value as Sum.
+func (hr *hashReader) SubtreeDigests() ([]SubtreeDigest, error) {
+	return hr.hasher.subtreeDigests()
+}
+
+// Metadata returns a description of the settings that the digests depend on.
+func (hr *hashReader) Metadata() DigestMetadata {
+	return hr.hasher.metadata()
+}
+
+// traverse hashes the remaining values in the current container, stepping in to and out of
+// any containers nested within it. It keeps track of how deeply it has stepped in rather than
+// recursing, so the depth of nesting that it can handle is limited only by the hasher.
 func (hr *hashReader) traverse() error {
-	for hr.Next() {
-		if ion.IsContainer(hr.currentType) && !hr.IsNull() {
-			err := hr.StepIn()
-			if err != nil {
-				return err
+	depth := 0
+	for {
+		if hr.Next() {
+			if ion.IsContainer(hr.currentType) && !hr.IsNull() {
+				err := hr.StepIn()
+				if err != nil {
+					return err
+				}
+
+				depth++
 			}
 
-			err = hr.traverse()
-			if err != nil {
-				return err
-			}
+			continue
+		}
 
-			err = hr.StepOut()
-			if err != nil {
-				return err
-			}
+		if hr.Err() != nil || depth == 0 {
+			return hr.Err()
+		}
+
+		err := hr.stepOut()
+		if err != nil {
+			return err
 		}
-	}
 
-	return hr.Err()
+		depth--
+	}
 }
 
 // The following implements hashValue interface.
 
 func (hr *hashReader) getFieldName() (*ion.SymbolToken, error) {
-	return hr.FieldName()
+	fieldName, err := hr.FieldName()
+	return fieldName, readerError(err)
 }
 
 func (hr *hashReader) getAnnotations() ([]ion.SymbolToken, error) {
-	return hr.ionReader.Annotations()
+	annotations, err := hr.ionReader.Annotations()
+	return annotations, readerError(err)
 }
 
 func (hr *hashReader) value() (interface{}, error) {
-	switch hr.currentType {
+	if hr.currentType == ion.NoType {
+		return ion.NoType, nil
+	}
+
+	return scalarValue(hr.ionReader, hr.currentType)
+}
+
+// scalarValue reads the current value of reader, a scalar of the given type, in the form that
+// appendScalar accepts.
+func scalarValue(reader ion.Reader, ionType ion.Type) (interface{}, error) {
+	var val interface{}
+	var err error
+
+	switch ionType {
 	case ion.BoolType:
-		return hr.BoolValue()
+		val, err = reader.BoolValue()
 	case ion.BlobType:
-		return hr.ionReader.ByteValue()
+		val, err = reader.ByteValue()
 	case ion.ClobType:
-		return hr.ionReader.ByteValue()
+		val, err = reader.ByteValue()
 	case ion.DecimalType:
-		return hr.DecimalValue()
+		val, err = reader.DecimalValue()
 	case ion.FloatType:
-		return hr.FloatValue()
+		val, err = reader.FloatValue()
 	case ion.IntType:
-		intSize, err := hr.IntSize()
+		var intSize ion.IntSize
+		intSize, err = reader.IntSize()
 		if err != nil {
-			return nil, err
+			break
 		}
 
 		switch intSize {
 		case ion.Int32:
-			return hr.IntValue()
+			val, err = reader.IntValue()
 		case ion.Int64:
-			return hr.Int64Value()
+			val, err = reader.Int64Value()
 		case ion.BigInt:
-			return hr.BigIntValue()
+			val, err = reader.BigIntValue()
 		default:
 			return nil, &InvalidOperationError{
 				"hashReader", "value", "Expected intSize to be one of Int32, Int64, Uint64, or BigInt"}
 		}
 	case ion.StringType:
-		return hr.StringValue()
+		val, err = reader.StringValue()
 	case ion.SymbolType:
-		return hr.SymbolValue()
+		val, err = reader.SymbolValue()
 	case ion.TimestampType:
-		return hr.TimestampValue()
-	case ion.NoType:
-		return ion.NoType, nil
+		val, err = reader.TimestampValue()
+	default:
+		return nil, &InvalidIonTypeError{ionType}
+	}
+
+	if err != nil {
+		return nil, readerError(err)
 	}
 
-	return nil, &InvalidIonTypeError{hr.currentType}
+	return val, nil
 }
 
 // IsInStruct indicates if the reader is currently positioned inside a struct.
 func (hr *hashReader) IsInStruct() bool {
 	return hr.ionReader.IsInStruct()
 }
+
+// readerError wraps an error from the underlying Ion reader in a ReaderError.
+func readerError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &ReaderError{err}
+}
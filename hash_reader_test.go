@@ -16,7 +16,9 @@
 package ionhash
 
 import (
+	"errors"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/amzn/ion-go/ion"
@@ -198,3 +200,48 @@ func consume(t *testing.T, function consumeFunction) {
 
 	assert.Equal(t, []byte{}, sum, "sum did not match expectation")
 }
+
+func TestDeeplyNested(t *testing.T) {
+	depth := 200000
+	text := strings.Repeat("[", depth) + strings.Repeat("]", depth)
+
+	ionHashReader, err := NewHashReader(ion.NewReaderString(text), NewCryptoHasherProvider(SHA256), WithMaxDepth(0))
+	require.NoError(t, err, "Expected NewHashReader() to successfully create a HashReader")
+
+	assert.True(t, ionHashReader.Next())
+	assert.False(t, ionHashReader.Next())
+	require.NoError(t, ionHashReader.Err(), "Something went wrong executing ionHashReader.Next()")
+
+	_, err = ionHashReader.Sum(nil)
+	require.NoError(t, err, "Something went wrong executing ionHashReader.Sum(nil)")
+}
+
+func TestMaxDepth(t *testing.T) {
+	testCases := []struct {
+		text     string
+		maxDepth int
+		ok       bool
+	}{
+		{"[[1]]", 2, true},
+		{"[[[1]]]", 2, false},
+		{"{a:(1)}", 2, true},
+		{"{a:(b::[])}", 2, false},
+		{"[null.list]", 1, true},
+	}
+
+	for _, tc := range testCases {
+		ionHashReader, err := NewHashReader(ion.NewReaderString(tc.text), NewCryptoHasherProvider(SHA256),
+			WithMaxDepth(tc.maxDepth))
+		require.NoError(t, err, "Expected NewHashReader() to successfully create a HashReader")
+
+		for ionHashReader.Next() {
+		}
+
+		if tc.ok {
+			assert.NoError(t, ionHashReader.Err(), "Something went wrong executing ionHashReader.Next()")
+		} else {
+			assert.True(t, errors.Is(ionHashReader.Err(), ErrLimitExceeded),
+				"Expected ionHashReader.Next() to fail with a LimitExceededError")
+		}
+	}
+}
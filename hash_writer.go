@@ -81,6 +81,17 @@ type HashWriter interface {
 	// Sum appends the current hash to b and returns the resulting slice.
 	// It resets the Hash to its initial state.
 	Sum(b []byte) ([]byte, error)
+
+	// KeyDigests returns the key digest of the same value as Sum for each KeySpec given with
+	// WithKeyDigests, in the same order, or nil if none were given.
+	KeyDigests() ([][]byte, error)
+
+	// SubtreeDigests returns the digests of the values within the same value as Sum whose paths
+	// match those given with WithSubtrees, in the order the values begin, or nil if there are none.
+	SubtreeDigests() ([]SubtreeDigest, error)
+
+	// Metadata returns a description of the settings that the digests depend on.
+	Metadata() DigestMetadata
 }
 
 type hashWriter struct {
@@ -94,9 +105,9 @@ type hashWriter struct {
 	annotations      []ion.SymbolToken
 }
 
-// NewHashWriter takes an Ion Writer and a hash provider and returns a new HashWriter.
-func NewHashWriter(ionWriter ion.Writer, hasherProvider IonHasherProvider) (HashWriter, error) {
-	newHasher, err := newHasher(hasherProvider)
+// NewHashWriter takes an Ion Writer, a hash provider and any options and returns a new HashWriter.
+func NewHashWriter(ionWriter ion.Writer, hasherProvider IonHasherProvider, opts ...Option) (HashWriter, error) {
+	newHasher, err := newHasher(hasherProvider, newOptions(opts))
 	if err != nil {
 		return nil, err
 	}
@@ -321,6 +332,21 @@ func (hw *hashWriter) Sum(b []byte) ([]byte, error) {
 	return hw.hasher.sum(b)
 }
 
+// KeyDigests returns the key digests of the same value as Sum.
+func (hw *hashWriter) KeyDigests() ([][]byte, error) {
+	return hw.hasher.keyDigests()
+}
+
+// SubtreeDigests returns the subtree digests of the same value as Sum.
+func (hw *hashWriter) SubtreeDigests() ([]SubtreeDigest, error) {
+	return hw.hasher.subtreeDigests()
+}
+
+// Metadata returns a description of the settings that the digests depend on.
+func (hw *hashWriter) Metadata() DigestMetadata {
+	return hw.hasher.metadata()
+}
+
 // The following implements hashValue interface.
 
 func (hw *hashWriter) getFieldName() (*ion.SymbolToken, error) {
@@ -17,6 +17,7 @@ package ionhash
 
 import (
 	"bytes"
+	"errors"
 	"math/big"
 	"os"
 	"strings"
@@ -386,3 +387,15 @@ func writeFromReaderToWriterAfterNext(t *testing.T, reader ion.Reader, writer io
 
 	writeFromReaderToWriter(t, reader, writer, errExpected)
 }
+
+func TestHashWriterMaxDepth(t *testing.T) {
+	hw, err := NewHashWriter(ion.NewTextWriter(&strings.Builder{}), NewCryptoHasherProvider(SHA256), WithMaxDepth(2))
+	require.NoError(t, err, "Expected NewHashWriter() to successfully create a HashWriter")
+
+	require.NoError(t, hw.BeginList(), "Something went wrong executing hw.BeginList()")
+	require.NoError(t, hw.BeginStruct(), "Something went wrong executing hw.BeginStruct()")
+	require.NoError(t, hw.FieldName(ion.NewSymbolTokenFromString("a")), "Something went wrong executing hw.FieldName()")
+
+	err = hw.BeginSexp()
+	assert.True(t, errors.Is(err, ErrLimitExceeded), "Expected hw.BeginSexp() to return a LimitExceededError")
+}
@@ -16,35 +16,326 @@
 package ionhash
 
 import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
 	"github.com/amzn/ion-go/ion"
-	"github.com/amzn/ion-hash-go/internal"
 )
 
+// contextCheckInterval is the number of values hashed between checks of whether the context is done.
+const contextCheckInterval = 256
+
 type hasher struct {
 	hasherProvider IonHasherProvider
 	currentHasher  serializer
-	hasherStack    internal.Stack
+
+	// serializers is the stack of serializers for the containers being hashed,
+	// with the top-level serializer at the bottom and currentHasher at the top.
+	serializers []serializer
+	limiter     *limiter
+
+	// path holds the position of the value being hashed within each container it is in,
+	// so that errors can say where the value is.
+	path []pathElement
+
+	ctx    context.Context
+	values int
+
+	// stats, if not nil, is updated as values are hashed.
+	stats *Stats
+
+	// normalizer, if not nil, normalizes the scalars that the profile calls for before they are hashed.
+	normalizer *normalizedValue
+
+	// The lists and sexps that are hashed without regard to the order of their values.
+	unorderedSequences bool
+	unorderedPaths     []pathPattern
+	setAnnotations     []string
+
+	// The structs that are hashed with their fields in order.
+	orderedStructs     bool
+	orderedStructPaths []pathPattern
+
+	// The values that are excluded from the hash, and the number of containers entered since
+	// stepping in to an excluded container, or zero if not in one.
+	excludedFields      map[string]bool
+	excludedPaths       []pathPattern
+	excludedAnnotations []string
+	excluding           int
+
+	// annotationFilter, if not nil, filters the annotations of values before they are hashed.
+	annotationFilter *annotationFilter
+
+	// absentFields selects the struct fields that are hashed as though they were absent.
+	absentFields AbsentFields
+
+	// fieldAliases and fieldNameFuncs give the canonical names of fields, which are hashed in place
+	// of their names.
+	fieldAliases   map[string]string
+	fieldNameFuncs []FieldNameFunc
+	renamedField   renamedField
+
+	// transformers replace or drop scalars before they are hashed. dropped is whether they dropped
+	// the last scalar. The hasher of a subtree has no transformers of its own, but takes the
+	// scalars as the transformers of transformedBy, the hasher of its top-level value, left them.
+	transformers  []Transformer
+	transformed   transformedValue
+	dropped       bool
+	transformedBy *hasher
+
+	// quantizations round floats and decimals before they are hashed.
+	quantizations []quantizationRule
+	quantized     quantizedValue
+
+	// redactions selects whether the placeholders of redacted fields are hashed as those fields.
+	redactions bool
+
+	// keySpecs select the fields whose hashes make up key digests.
+	keySpecs []*keySpec
+
+	// subtreePatterns select the values whose digests are computed by the hashers of subtrees,
+	// which are created with subtreeProvider and subtreeOptions.
+	subtreePatterns []pathPattern
+	subtreeProvider IonHasherProvider
+	subtreeOptions  options
+	subtrees        []*subtree
+	freeSubtrees    []*subtree
+	subtreeSums     []SubtreeDigest
+
+	// pathPrefix is the path of the value that the hasher of a subtree hashes, which paths in the
+	// options are matched after. fullPath is reused to hold the two together.
+	pathPrefix []pathElement
+	fullPath   []pathElement
+}
+
+// pathElement is the position of a value within a container.
+type pathElement struct {
+	isStruct  bool
+	index     int
+	fieldName *ion.SymbolToken
+
+	// values is the number of values in the container that have been hashed, and annotated
+	// is whether the container is a struct field with annotations, which is never absent.
+	values    int
+	annotated bool
 }
 
-func newHasher(hasherProvider IonHasherProvider) (*hasher, error) {
+func newHasher(hasherProvider IonHasherProvider, opts options) (*hasher, error) {
+	subtreeProvider := hasherProvider
+	if opts.stats != nil {
+		hasherProvider = &statsHasherProvider{hasherProvider, opts.stats}
+	}
+
 	newHasher, err := hasherProvider.NewHasher()
 	if err != nil {
 		return nil, err
 	}
 
-	currentHasher := newScalarSerializer(newHasher, 0)
+	limiter := &limiter{limits: opts.limits}
+	currentHasher := newScalarSerializer(newHasher, 0, limiter)
 
-	var hasherStack internal.Stack
-	hasherStack.Push(currentHasher)
+	unorderedPaths, err := parsePathPatterns(opts.unorderedPaths)
+	if err != nil {
+		return nil, err
+	}
 
-	return &hasher{hasherProvider, currentHasher, hasherStack}, nil
+	orderedStructPaths, err := parsePathPatterns(opts.orderedStructPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	excludedPaths, err := parsePathPatterns(opts.excludedPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	keySpecs, err := newKeySpecs(opts.keySpecs)
+	if err != nil {
+		return nil, err
+	}
+
+	subtreePatterns, err := parsePathPatterns(opts.subtreePaths)
+	if err != nil {
+		return nil, err
+	}
+
+	quantizations, err := newQuantizationRules(opts.quantizations)
+	if err != nil {
+		return nil, err
+	}
+
+	var excludedFields map[string]bool
+	if len(opts.excludedFields) > 0 {
+		excludedFields = stringSet(opts.excludedFields)
+	}
+
+	var normalizer *normalizedValue
+	if opts.profile != (HashProfile{}) {
+		normalizer = &normalizedValue{profile: &opts.profile}
+	}
+
+	return &hasher{
+		hasherProvider: hasherProvider,
+		currentHasher:  currentHasher,
+		serializers:    []serializer{currentHasher},
+		limiter:        limiter,
+		ctx:            opts.ctx,
+		stats:          opts.stats,
+		normalizer:     normalizer,
+
+		unorderedSequences: opts.unorderedSequences,
+		unorderedPaths:     unorderedPaths,
+		setAnnotations:     opts.setAnnotations,
+		orderedStructs:     opts.orderedStructs,
+		orderedStructPaths: orderedStructPaths,
+
+		excludedFields:      excludedFields,
+		excludedPaths:       excludedPaths,
+		excludedAnnotations: opts.excludedAnnotations,
+		annotationFilter:    newAnnotationFilter(opts),
+		absentFields:        opts.absentFields,
+		fieldAliases:        opts.fieldAliases,
+		fieldNameFuncs:      opts.fieldNameFuncs,
+		transformers:        opts.transformers,
+		redactions:          opts.redactions,
+		quantizations:       quantizations,
+		keySpecs:            keySpecs,
+		subtreePatterns:     subtreePatterns,
+		subtreeProvider:     subtreeProvider,
+		subtreeOptions:      subtreeOptions(opts),
+	}, nil
 }
 
 func (h *hasher) scalar(ionValue hashValue) error {
-	return h.currentHasher.scalar(ionValue)
+	err := h.checkContext()
+	if err != nil {
+		return err
+	}
+
+	if h.stats != nil {
+		defer h.stats.since(time.Now())
+	}
+
+	err = h.beginValue(ionValue)
+	if err != nil {
+		return h.errorAt(err, ionValue, len(h.path))
+	}
+
+	omitted, err := h.isExcluded(ionValue)
+	var redacted []byte
+	var isRedacted bool
+	if err == nil && !omitted && h.redactions {
+		redacted, isRedacted, err = h.redactedDigest(ionValue)
+	}
+	transformed := ionValue
+	if err == nil && !omitted && !isRedacted {
+		transformed, omitted, err = h.transform(ionValue)
+	}
+	if err == nil && !omitted && !isRedacted {
+		omitted, err = h.isAbsentNull(transformed)
+	}
+	if err == nil && !omitted {
+		if isRedacted {
+			h.currentHasher.(*structSerializer).appendRedacted(redacted)
+		} else {
+			err = h.currentHasher.scalar(h.normalize(h.filterAnnotations(h.renameField(h.quantize(transformed)))))
+		}
+		if err == nil {
+			if h.keySpecs != nil && h.inStruct() {
+				structHasher := h.currentHasher.(*structSerializer)
+				h.keyField(structHasher.fieldHashes[len(structHasher.fieldHashes)-1])
+			}
+
+			h.countValue()
+			if h.stats != nil {
+				h.stats.value(transformed.Type())
+			}
+		}
+	}
+
+	err = h.errorAt(err, ionValue, len(h.path))
+	if err == nil && h.subtreePatterns != nil {
+		return h.scalarSubtrees(ionValue)
+	}
+
+	return err
 }
 
 func (h *hasher) stepIn(ionValue hashValue) error {
+	err := h.checkContext()
+	if err != nil {
+		return err
+	}
+
+	if h.stats != nil {
+		defer h.stats.since(time.Now())
+	}
+
+	err = h.beginValue(ionValue)
+	if err != nil {
+		return h.errorAt(err, ionValue, len(h.path))
+	}
+
+	excluded, err := h.isExcluded(ionValue)
+	if err != nil {
+		return h.errorAt(err, ionValue, len(h.path))
+	}
+
+	if excluded {
+		// The values in an excluded container are tracked, but not hashed.
+		err = h.limiter.stepIn()
+		if err != nil {
+			return h.errorAt(err, ionValue, len(h.path))
+		}
+
+		h.excluding++
+		h.path = append(h.path, pathElement{isStruct: ionValue.Type() == ion.StructType, index: -1})
+		if h.subtreePatterns != nil {
+			return h.stepInSubtrees(ionValue)
+		}
+
+		return nil
+	}
+
+	element := pathElement{isStruct: ionValue.Type() == ion.StructType, index: -1}
+	if h.absentFields.EmptyContainers && h.inStruct() {
+		annotations, err := h.filterAnnotations(ionValue).getAnnotations()
+		if err != nil {
+			return h.errorAt(err, ionValue, len(h.path))
+		}
+
+		element.annotated = len(annotations) > 0
+	}
+
+	err = h.pushSerializer(ionValue)
+	if err != nil {
+		return h.errorAt(err, ionValue, len(h.path))
+	}
+
+	h.path = append(h.path, element)
+
+	if h.stats != nil {
+		h.stats.value(ionValue.Type())
+		h.stats.container(len(h.path))
+	}
+
+	if h.subtreePatterns != nil {
+		return h.stepInSubtrees(ionValue)
+	}
+
+	return nil
+}
+
+// pushSerializer pushes a serializer for the container ionValue on to the stack.
+func (h *hasher) pushSerializer(ionValue hashValue) error {
+	err := h.limiter.stepIn()
+	if err != nil {
+		return err
+	}
+
 	var hashFunction IonHasher
 
 	if _, ok := h.currentHasher.(*structSerializer); ok {
@@ -58,19 +349,25 @@ func (h *hasher) stepIn(ionValue hashValue) error {
 		hashFunction = h.currentHasher.(*scalarSerializer).hashFunction
 	}
 
-	if ionValue.Type() == ion.StructType {
-		newStructSerializer, err := newStructSerializer(hashFunction, h.depth(), h.hasherProvider)
+	unordered, err := h.isUnordered(ionValue)
+	if err != nil {
+		return err
+	}
+
+	if unordered || ionValue.Type() == ion.StructType {
+		// A struct that isn't unordered is hashed with its fields in order.
+		newStructSerializer, err := newStructSerializer(hashFunction, h.depth(), h.hasherProvider, h.limiter, !unordered)
 		if err != nil {
 			return err
 		}
 
 		h.currentHasher = newStructSerializer
 	} else {
-		h.currentHasher = newScalarSerializer(hashFunction, h.depth())
+		h.currentHasher = newScalarSerializer(hashFunction, h.depth(), h.limiter)
 	}
 
-	h.hasherStack.Push(h.currentHasher)
-	return h.currentHasher.stepIn(ionValue)
+	h.serializers = append(h.serializers, h.currentHasher)
+	return h.currentHasher.stepIn(h.filterAnnotations(h.renameField(ionValue)))
 }
 
 func (h *hasher) stepOut() error {
@@ -78,38 +375,301 @@ func (h *hasher) stepOut() error {
 		return &InvalidOperationError{"hasher", "stepOut", "Depth is zero. Hasher cannot step out any further"}
 	}
 
+	if h.excluding > 0 {
+		h.excluding--
+		h.path = h.path[:len(h.path)-1]
+		if h.subtreePatterns != nil {
+			return h.stepOutSubtrees()
+		}
+
+		return nil
+	}
+
+	if h.stats != nil {
+		defer h.stats.since(time.Now())
+
+		if element := h.path[len(h.path)-1]; element.isStruct {
+			h.stats.structEnd(element.index + 1)
+		}
+	}
+
 	err := h.currentHasher.stepOut()
 	if err != nil {
-		return err
+		return h.errorAt(err, nil, len(h.path)-1)
 	}
 
-	poppedHasher, err := h.hasherStack.Pop()
-	if err != nil {
-		return &InvalidOperationError{
-			"hasher",
-			"stepOut",
-			err.Error(),
+	poppedHasher := h.currentHasher
+	h.serializers[len(h.serializers)-1] = nil
+	h.serializers = h.serializers[:len(h.serializers)-1]
+	h.currentHasher = h.serializers[len(h.serializers)-1]
+	element := h.path[len(h.path)-1]
+	h.path = h.path[:len(h.path)-1]
+
+	// An empty container in a struct may be hashed as though its field were absent.
+	absent := h.absentFields.EmptyContainers && h.inStruct() && element.values == 0 && !element.annotated
+
+	if structHasher, ok := h.currentHasher.(*structSerializer); ok {
+		sum := poppedHasher.sum(nil)
+		if !absent {
+			structHasher.appendFieldHash(sum)
+
+			if h.keySpecs != nil && h.inStruct() {
+				h.keyField(sum)
+			}
 		}
 	}
-	peekedHasher, err := h.hasherStack.Peek()
+
+	if !absent {
+		h.countValue()
+	}
+
+	if h.subtreePatterns != nil {
+		return h.stepOutSubtrees()
+	}
+
+	return nil
+}
+
+// isUnordered reports whether the values in the container ionValue are hashed separately and their
+// digests sorted, which structs are unless the options call for them to be ordered, and lists and
+// sexps are if the options call for it.
+func (h *hasher) isUnordered(ionValue hashValue) (bool, error) {
+	switch {
+	case ionValue.Type() == ion.StructType:
+		return !h.orderedStructs && !matchesAny(h.orderedStructPaths, h.pathFrom(len(h.path))), nil
+	case h.unorderedSequences:
+		return true, nil
+	case matchesAny(h.unorderedPaths, h.pathFrom(len(h.path))):
+		return true, nil
+	}
+
+	return hasAnnotation(ionValue, h.setAnnotations)
+}
+
+// isExcluded reports whether ionValue contributes nothing to the hash, either because the options
+// exclude it or because it is in an excluded container.
+func (h *hasher) isExcluded(ionValue hashValue) (bool, error) {
+	if h.excluding > 0 {
+		return true, nil
+	}
+
+	path := h.pathFrom(len(h.path))
+	if len(h.excludedFields) > 0 && len(path) > 0 {
+		element := path[len(path)-1]
+		if element.isStruct && element.fieldName != nil && element.fieldName.Text != nil &&
+			h.excludedFields[*element.fieldName.Text] {
+			return true, nil
+		}
+	}
+
+	if matchesAny(h.excludedPaths, path) {
+		return true, nil
+	}
+
+	return hasAnnotation(ionValue, h.excludedAnnotations)
+}
+
+// isAbsentNull reports whether ionValue is a null in a struct that is hashed as though its field
+// were absent.
+func (h *hasher) isAbsentNull(ionValue hashValue) (bool, error) {
+	switch {
+	case !ionValue.IsNull() || !h.inStruct():
+		return false, nil
+	case h.absentFields.Nulls == AllNulls:
+	case h.absentFields.Nulls == UntypedNulls && ionValue.Type() == ion.NullType:
+	default:
+		return false, nil
+	}
+
+	annotations, err := h.filterAnnotations(ionValue).getAnnotations()
+	return len(annotations) == 0, err
+}
+
+// inStruct reports whether the value being hashed is in a struct.
+func (h *hasher) inStruct() bool {
+	return len(h.path) > 0 && h.path[len(h.path)-1].isStruct
+}
+
+// countValue counts a value that has been hashed towards the container that it is in.
+func (h *hasher) countValue() {
+	if len(h.path) > 0 {
+		h.path[len(h.path)-1].values++
+	}
+}
+
+// hasAnnotation reports whether ionValue is annotated with any of the given annotations.
+func hasAnnotation(ionValue hashValue, names []string) (bool, error) {
+	if len(names) == 0 {
+		return false, nil
+	}
+
+	annotations, err := ionValue.getAnnotations()
 	if err != nil {
-		return &InvalidOperationError{
-			"hasher",
-			"stepOut",
-			err.Error(),
+		return false, err
+	}
+
+	for _, annotation := range annotations {
+		for _, name := range names {
+			if annotation.Text != nil && *annotation.Text == name {
+				return true, nil
+			}
 		}
 	}
 
-	h.currentHasher = peekedHasher.(serializer)
+	return false, nil
+}
+
+// filterAnnotations returns ionValue, or a value that filters its annotations if the options call for it.
+func (h *hasher) filterAnnotations(ionValue hashValue) hashValue {
+	if h.annotationFilter == nil {
+		return ionValue
+	}
+
+	h.annotationFilter.hashValue = ionValue
+	return h.annotationFilter
+}
 
-	if structHasher, ok := h.currentHasher.(*structSerializer); ok {
-		sum := poppedHasher.(serializer).sum(nil)
-		structHasher.appendFieldHash(sum)
+// canonicalFieldName returns the canonical name of a field given its name, and whether it differs.
+func (h *hasher) canonicalFieldName(name string) (string, bool) {
+	canonical := name
+	if alias, ok := h.fieldAliases[name]; ok {
+		canonical = alias
+	}
+
+	if len(h.fieldNameFuncs) > 0 {
+		path := h.pathString(len(h.path) - 1)
+		for _, fn := range h.fieldNameFuncs {
+			canonical = fn(path, canonical)
+		}
+	}
+
+	return canonical, canonical != name
+}
+
+// renameField returns ionValue, or a value with the canonical name of its field if it differs.
+func (h *hasher) renameField(ionValue hashValue) hashValue {
+	if h.fieldAliases == nil && h.fieldNameFuncs == nil || !h.inStruct() {
+		return ionValue
+	}
+
+	fieldName := h.path[len(h.path)-1].fieldName
+	if fieldName == nil || fieldName.Text == nil {
+		return ionValue
 	}
 
+	canonical, renamed := h.canonicalFieldName(*fieldName.Text)
+	if !renamed {
+		return ionValue
+	}
+
+	h.renamedField.hashValue = ionValue
+	h.renamedField.text = canonical
+	h.renamedField.fieldName = ion.SymbolToken{Text: &h.renamedField.text, LocalSID: ion.SymbolIDUnknown}
+	return &h.renamedField
+}
+
+// normalize returns ionValue, or a value that normalizes it if the profile calls for it.
+func (h *hasher) normalize(ionValue hashValue) hashValue {
+	if h.normalizer == nil || ionValue.IsNull() || !h.normalizer.profile.normalizes(ionValue.Type()) {
+		return ionValue
+	}
+
+	h.normalizer.hashValue = ionValue
+	return h.normalizer
+}
+
+// checkContext periodically checks whether the context is done.
+func (h *hasher) checkContext() error {
+	if h.ctx != nil && h.values%contextCheckInterval == 0 {
+		err := h.ctx.Err()
+		if err != nil {
+			return err
+		}
+	}
+	h.values++
+
 	return nil
 }
 
+// beginValue records the position of a value and checks the limits that apply to it before
+// the value is hashed.
+func (h *hasher) beginValue(ionValue hashValue) error {
+	if len(h.path) > 0 {
+		element := &h.path[len(h.path)-1]
+		element.index++
+
+		if element.isStruct {
+			fieldName, err := ionValue.getFieldName()
+			if err != nil {
+				return err
+			}
+
+			element.fieldName = fieldName
+		}
+	}
+
+	// The digests of the values of lists and sexps hashed without regard to order are held in
+	// memory as those of struct fields are, so they are limited in the same way.
+	_, unordered := h.currentHasher.(*structSerializer)
+	if len(h.path) > 0 && (h.path[len(h.path)-1].isStruct || unordered) {
+		err := h.limiter.structField(h.path[len(h.path)-1].index, h.depth())
+		if err != nil {
+			return err
+		}
+	}
+
+	if h.depth() == 0 && h.keySpecs != nil {
+		h.beginKeys()
+	}
+
+	if h.depth() == 0 && h.subtreePatterns != nil {
+		h.beginSubtrees()
+	}
+
+	return h.limiter.beginValue(ionValue, h.depth())
+}
+
+// errorAt returns err as a HashError that locates the value at the given number of levels of
+// the path. The value's offset is known if ionValue can provide it.
+func (h *hasher) errorAt(err error, ionValue hashValue, levels int) error {
+	if err == nil {
+		return nil
+	}
+
+	offset := int64(-1)
+	if offsetter, ok := ionValue.(interface{ offset() int64 }); ok {
+		offset = offsetter.offset()
+	}
+
+	return &HashError{h.pathString(levels), h.limiter.topLevelValues - 1, offset, err}
+}
+
+// pathString returns the given number of levels of the path of the value being hashed,
+// e.g. orders[12].items[3].sku.
+func (h *hasher) pathString(levels int) string {
+	var path strings.Builder
+	for _, element := range h.pathFrom(levels) {
+		if element.isStruct {
+			appendPathField(&path, element.fieldName)
+		} else {
+			fmt.Fprintf(&path, "[%d]", element.index)
+		}
+	}
+
+	return path.String()
+}
+
+// pathFrom returns the given number of levels of the path of the value being hashed, from the
+// top-level value that it is in rather than from the subtree that the hasher hashes.
+func (h *hasher) pathFrom(levels int) []pathElement {
+	if h.pathPrefix == nil {
+		return h.path[:levels]
+	}
+
+	h.fullPath = append(append(h.fullPath[:0], h.pathPrefix...), h.path[:levels]...)
+	return h.fullPath
+}
+
 func (h *hasher) sum(b []byte) ([]byte, error) {
 	if h.depth() != 0 {
 		return nil, &InvalidOperationError{
@@ -120,5 +680,26 @@ func (h *hasher) sum(b []byte) ([]byte, error) {
 }
 
 func (h *hasher) depth() int {
-	return h.hasherStack.Size() - 1
+	return len(h.path)
+}
+
+// identifierPattern matches the field names that can appear in a path without quotes.
+var identifierPattern = regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z0-9_$]*$`)
+
+// appendPathField appends a field name to a path, quoting it if it is not an identifier.
+func appendPathField(path *strings.Builder, fieldName *ion.SymbolToken) {
+	if path.Len() > 0 {
+		path.WriteByte('.')
+	}
+
+	switch {
+	case fieldName == nil:
+		path.WriteString("?")
+	case fieldName.Text == nil:
+		fmt.Fprintf(path, "$%d", fieldName.LocalSID)
+	case identifierPattern.MatchString(*fieldName.Text):
+		path.WriteString(*fieldName.Text)
+	default:
+		path.WriteString("'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(*fieldName.Text) + "'")
+	}
 }
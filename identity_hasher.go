@@ -34,7 +34,8 @@ func (ih *identityHasher) Write(bytes []byte) (int, error) {
 	ih.identityHash = append(ih.identityHash, bytes...)
 
 	if bytes != nil {
-		ih.provider.updateHashLog = append(ih.provider.updateHashLog, bytes)
+		// The caller may reuse bytes once Write returns, so log a copy of it.
+		ih.provider.updateHashLog = append(ih.provider.updateHashLog, append([]byte{}, bytes...))
 	}
 
 	return len(bytes), nil
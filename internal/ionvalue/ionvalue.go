@@ -0,0 +1,232 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+// Package ionvalue reads, writes and copies Ion values for the packages of this module, writing
+// symbols by their text so that values can be copied between readers and writers.
+package ionvalue
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/amzn/ion-go/ion"
+)
+
+// PortableSymbol returns symbol without the symbol ID of the data that it was read from, which a
+// binary writer would otherwise write without looking it up in its own symbol table.
+func PortableSymbol(symbol ion.SymbolToken) ion.SymbolToken {
+	if symbol.Text == nil {
+		return symbol
+	}
+
+	return ion.NewSymbolTokenFromString(*symbol.Text)
+}
+
+// WriteAnnotations writes annotations for the next value written to writer.
+func WriteAnnotations(writer ion.Writer, annotations []ion.SymbolToken) error {
+	for _, annotation := range annotations {
+		err := writer.Annotation(PortableSymbol(annotation))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadScalar reads the current value of reader, which is a scalar that isn't null. Ints are read as
+// *big.Int and symbols as portable *ion.SymbolTokens.
+func ReadScalar(reader ion.Reader) (interface{}, error) {
+	switch reader.Type() {
+	case ion.BoolType:
+		return reader.BoolValue()
+	case ion.IntType:
+		return reader.BigIntValue()
+	case ion.FloatType:
+		return reader.FloatValue()
+	case ion.DecimalType:
+		return reader.DecimalValue()
+	case ion.TimestampType:
+		return reader.TimestampValue()
+	case ion.StringType:
+		return reader.StringValue()
+	case ion.SymbolType:
+		symbol, err := reader.SymbolValue()
+		if err != nil || symbol == nil {
+			return symbol, err
+		}
+
+		portable := PortableSymbol(*symbol)
+		return &portable, nil
+	case ion.BlobType, ion.ClobType:
+		return reader.ByteValue()
+	}
+
+	return nil, &ion.UsageError{API: "ionvalue.ReadScalar", Msg: fmt.Sprintf("%v is not a scalar", reader.Type())}
+}
+
+// WriteScalar writes val, a scalar of type ionType that isn't null, held as a reader's value methods
+// return it, e.g. *bool, *int64, *big.Int, *string, *ion.SymbolToken or []byte.
+func WriteScalar(writer ion.Writer, ionType ion.Type, val interface{}) error {
+	switch val := val.(type) {
+	case *bool:
+		return writer.WriteBool(*val)
+	case *int:
+		return writer.WriteInt(int64(*val))
+	case *int64:
+		return writer.WriteInt(*val)
+	case *big.Int:
+		return writer.WriteBigInt(val)
+	case *float64:
+		return writer.WriteFloat(*val)
+	case *ion.Decimal:
+		return writer.WriteDecimal(val)
+	case *ion.Timestamp:
+		return writer.WriteTimestamp(*val)
+	case *string:
+		return writer.WriteString(*val)
+	case *ion.SymbolToken:
+		return writer.WriteSymbol(PortableSymbol(*val))
+	case []byte:
+		if ionType == ion.BlobType {
+			return writer.WriteBlob(val)
+		}
+
+		return writer.WriteClob(val)
+	}
+
+	return &ion.UsageError{API: "ionvalue.WriteScalar", Msg: fmt.Sprintf("%T is not the value of a %v", val, ionType)}
+}
+
+// WriteNull writes a null of type ionType.
+func WriteNull(writer ion.Writer, ionType ion.Type) error {
+	if ionType == ion.NullType {
+		return writer.WriteNull()
+	}
+
+	return writer.WriteNullType(ionType)
+}
+
+// BeginContainer begins writing a container of type ionType, which is a list, sexp or struct.
+func BeginContainer(writer ion.Writer, ionType ion.Type) error {
+	switch ionType {
+	case ion.ListType:
+		return writer.BeginList()
+	case ion.SexpType:
+		return writer.BeginSexp()
+	case ion.StructType:
+		return writer.BeginStruct()
+	}
+
+	return &ion.UsageError{API: "ionvalue.BeginContainer", Msg: fmt.Sprintf("%v is not a container", ionType)}
+}
+
+// EndContainer ends writing the container of type ionType that BeginContainer began.
+func EndContainer(writer ion.Writer, ionType ion.Type) error {
+	switch ionType {
+	case ion.ListType:
+		return writer.EndList()
+	case ion.SexpType:
+		return writer.EndSexp()
+	}
+
+	return writer.EndStruct()
+}
+
+// Copy writes the current value of reader, without its field name, and the values in it, to writer.
+// It leaves reader on the value.
+func Copy(reader ion.Reader, writer ion.Writer) error {
+	// containers holds the types of the containers that have been begun and not yet ended.
+	var containers []ion.Type
+	for {
+		ionType := reader.Type()
+		err := copyStart(reader, writer)
+		if err != nil {
+			return err
+		}
+
+		if !reader.IsNull() && ion.IsContainer(ionType) {
+			err = reader.StepIn()
+			if err != nil {
+				return err
+			}
+
+			containers = append(containers, ionType)
+		}
+
+		for len(containers) > 0 && !reader.Next() {
+			if reader.Err() != nil {
+				return reader.Err()
+			}
+
+			err = reader.StepOut()
+			if err != nil {
+				return err
+			}
+
+			err = EndContainer(writer, containers[len(containers)-1])
+			if err != nil {
+				return err
+			}
+
+			containers = containers[:len(containers)-1]
+		}
+
+		if len(containers) == 0 {
+			return nil
+		}
+
+		if containers[len(containers)-1] == ion.StructType {
+			fieldName, err := reader.FieldName()
+			if err != nil {
+				return err
+			}
+
+			err = writer.FieldName(PortableSymbol(*fieldName))
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// copyStart writes the annotations of the current value of reader and then the value if it is a
+// scalar or a null, or the start of the container that it is otherwise.
+func copyStart(reader ion.Reader, writer ion.Writer) error {
+	annotations, err := reader.Annotations()
+	if err != nil {
+		return err
+	}
+
+	err = WriteAnnotations(writer, annotations)
+	if err != nil {
+		return err
+	}
+
+	ionType := reader.Type()
+	switch {
+	case reader.IsNull():
+		return WriteNull(writer, ionType)
+	case ion.IsContainer(ionType):
+		return BeginContainer(writer, ionType)
+	}
+
+	val, err := ReadScalar(reader)
+	if err != nil {
+		return err
+	}
+
+	return WriteScalar(writer, ionType, val)
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionvalue
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/amzn/ion-go/ion"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopy(t *testing.T) {
+	text := `a::{b:[1, 2.5, 3e0, c::d, "e", {{ZQ==}}, {{"f"}}, 2020T], g:(h null.int), i:null, j:{}, k:true} 12345678901234567890`
+
+	// Symbols are read from binary with local symbol IDs, which the copy must write by their text.
+	var source bytes.Buffer
+	writer := ion.NewBinaryWriter(&source)
+	reader := ion.NewReaderString(text)
+	for reader.Next() {
+		require.NoError(t, Copy(reader, writer), "Something went wrong executing Copy()")
+	}
+	require.NoError(t, reader.Err())
+	require.NoError(t, writer.Finish())
+
+	var copied strings.Builder
+	writer = ion.NewTextWriter(&copied)
+	reader = ion.NewReaderBytes(source.Bytes())
+	for reader.Next() {
+		require.NoError(t, Copy(reader, writer), "Something went wrong executing Copy()")
+	}
+	require.NoError(t, reader.Err())
+	require.NoError(t, writer.Finish())
+
+	assert.Equal(t, normalize(t, text), normalize(t, copied.String()))
+}
+
+func TestCopyDeep(t *testing.T) {
+	text := strings.Repeat("[", 100000) + strings.Repeat("]", 100000)
+
+	var copied bytes.Buffer
+	writer := ion.NewBinaryWriter(&copied)
+	reader := ion.NewReaderString(text)
+	require.True(t, reader.Next())
+	require.NoError(t, Copy(reader, writer), "Something went wrong executing Copy()")
+	require.NoError(t, writer.Finish())
+	assert.False(t, reader.Next())
+	assert.NoError(t, reader.Err())
+}
+
+func TestPortableSymbol(t *testing.T) {
+	symbol := PortableSymbol(ion.SymbolToken{Text: stringPtr("a"), LocalSID: 10})
+	assert.Equal(t, "a", *symbol.Text)
+	assert.Equal(t, int64(ion.SymbolIDUnknown), symbol.LocalSID)
+
+	unknown := ion.SymbolToken{LocalSID: 10}
+	assert.Equal(t, unknown, PortableSymbol(unknown))
+}
+
+// normalize returns text as it is written by a text writer.
+func normalize(t *testing.T, text string) string {
+	var buf strings.Builder
+	writer := ion.NewTextWriter(&buf)
+	reader := ion.NewReaderString(text)
+	for reader.Next() {
+		require.NoError(t, Copy(reader, writer))
+	}
+	require.NoError(t, reader.Err())
+	require.NoError(t, writer.Finish())
+	return buf.String()
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
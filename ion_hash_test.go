@@ -17,6 +17,7 @@ package ionhash
 
 import (
 	"bytes"
+	"io"
 	"os"
 	"strings"
 	"testing"
@@ -54,6 +55,50 @@ func TestIonHash(t *testing.T) {
 	}
 }
 
+func BenchmarkHashReader(b *testing.B) {
+	parameters := ionHashDataSource(b)
+	hasherProvider := NewCryptoHasherProvider(SHA256)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, parameter := range parameters {
+			hr, err := NewHashReader(ion.NewReaderBytes(parameter.testCase), hasherProvider)
+			require.NoError(b, err, "Something went wrong executing NewHashReader()")
+
+			for hr.Next() {
+			}
+			require.NoError(b, hr.Err(), "Something went wrong executing hr.Next()")
+
+			_, err = hr.Sum(nil)
+			require.NoError(b, err, "Something went wrong executing hr.Sum()")
+		}
+	}
+}
+
+func BenchmarkHashWriter(b *testing.B) {
+	parameters := ionHashDataSource(b)
+	hasherProvider := NewCryptoHasherProvider(SHA256)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, parameter := range parameters {
+			hw, err := NewHashWriter(ion.NewBinaryWriter(io.Discard), hasherProvider)
+			require.NoError(b, err, "Something went wrong executing NewHashWriter()")
+
+			reader := ion.NewReaderBytes(parameter.testCase)
+			for reader.Next() {
+				writeToWriters(b, reader, hw)
+			}
+			require.NoError(b, reader.Err(), "Something went wrong executing reader.Next()")
+
+			_, err = hw.Sum(nil)
+			require.NoError(b, err, "Something went wrong executing hw.Sum()")
+		}
+	}
+}
+
 func Traverse(t *testing.T, reader ion.Reader, provider IonHasherProvider) {
 	hr, err := NewHashReader(reader, provider)
 	require.NoError(t, err, "Something went wrong executing NewHashReader()")
@@ -77,7 +122,7 @@ func TraverseReader(t *testing.T, hr HashReader) {
 	require.NoError(t, hr.Err(), "Something went wrong executing hr.Next()")
 }
 
-func ionHashDataSource(t *testing.T) []testObject {
+func ionHashDataSource(t testing.TB) []testObject {
 	var dataList []testObject
 
 	file, err := os.ReadFile("ion-hash-test/ion_hash_tests.ion")
@@ -20,7 +20,9 @@ import "io"
 // IonHasher inherits functions from Ion Writer and adds the Sum function.
 // The Sum function provides read access to the underlying hash value.
 type IonHasher interface {
-	// Write (via the embedded io.Writer interface) adds more data to the running hash.
+	// Write (via the embedded io.Writer interface) adds more data to the running hash. As for
+	// io.Writer, b is only valid for the duration of the call: the buffers passed to Write are
+	// reused, so an implementation that keeps the data must copy it.
 	io.Writer
 
 	// Sum appends the current hash to b and returns the resulting slice.
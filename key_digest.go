@@ -0,0 +1,240 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+import (
+	"sort"
+
+	"github.com/amzn/ion-go/ion"
+)
+
+// MissingKeyFields selects how a key digest is computed for a value that has no field at one of
+// the key's paths.
+type MissingKeyFields int
+
+const (
+	// MissingKeyFieldsFail fails to compute the key digest, with a KeyFieldMissingError.
+	MissingKeyFieldsFail MissingKeyFields = iota
+
+	// MissingKeyFieldsNull computes the key digest as though the field were null.
+	MissingKeyFieldsNull
+
+	// MissingKeyFieldsOmit computes the key digest without the field.
+	MissingKeyFieldsOmit
+)
+
+// A KeySpec selects the fields of a value, such as the fields of a record's business key, from
+// which a key digest is computed. The key digest is the hash of a struct with a field for each path,
+// named by the path, whose value is a struct of the fields that the path selects, so that fields
+// with the same name at different paths are told apart. E.g. the key digest of
+// {tenant:"a", order:{id:1}, total:2.5} for the paths tenant and order.id is the hash of
+// {tenant:{tenant:"a"}, 'order.id':{id:1}}.
+type KeySpec struct {
+	// Paths are the paths of the key fields, written as for WithUnorderedPaths. Each path must end
+	// with a field name, or * if Missing isn't MissingKeyFieldsNull. A path that matches more than one
+	// field, e.g. lines[*].id, selects them all.
+	Paths []string
+
+	// Missing selects how the key digest is computed for a value that has no field at one of Paths.
+	// A path's field is omitted from the key digest, or its struct holds the field as null.
+	Missing MissingKeyFields
+}
+
+type keySpec struct {
+	KeySpec
+	patterns []pathPattern
+
+	// fieldHashes holds the hashes of the fields at each path in the current top-level value.
+	fieldHashes [][][]byte
+}
+
+func newKeySpecs(specs []KeySpec) ([]*keySpec, error) {
+	keySpecs := make([]*keySpec, 0, len(specs))
+	for _, spec := range specs {
+		patterns, err := parsePathPatterns(spec.Paths)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, pattern := range patterns {
+			if len(pattern) == 0 || !pattern[len(pattern)-1].isStruct {
+				return nil, &InvalidArgumentError{"path", spec.Paths[i]}
+			}
+			if spec.Missing == MissingKeyFieldsNull && pattern[len(pattern)-1].any {
+				return nil, &InvalidArgumentError{"path", spec.Paths[i]}
+			}
+		}
+
+		keySpecs = append(keySpecs, &keySpec{KeySpec: spec, patterns: patterns, fieldHashes: make([][][]byte, len(patterns))})
+	}
+
+	return keySpecs, nil
+}
+
+// beginKeys forgets the key fields of the previous top-level value.
+func (h *hasher) beginKeys() {
+	for _, spec := range h.keySpecs {
+		for i := range spec.fieldHashes {
+			spec.fieldHashes[i] = spec.fieldHashes[i][:0]
+		}
+	}
+}
+
+// keyField records the hash of the field that has just been hashed if it is a key field.
+func (h *hasher) keyField(fieldHash []byte) {
+	for _, spec := range h.keySpecs {
+		for i, pattern := range spec.patterns {
+			if pattern.matches(h.pathFrom(len(h.path))) {
+				spec.fieldHashes[i] = append(spec.fieldHashes[i], fieldHash)
+			}
+		}
+	}
+}
+
+// keyDigests returns the key digest of the last top-level value hashed for each KeySpec.
+func (h *hasher) keyDigests() ([][]byte, error) {
+	if h.depth() != 0 {
+		return nil, &InvalidOperationError{
+			"hasher", "keyDigests", "Key digests may only be provided at the same depth hashing started"}
+	}
+
+	if len(h.keySpecs) == 0 || h.limiter.topLevelValues == 0 {
+		return nil, nil
+	}
+
+	digests := make([][]byte, len(h.keySpecs))
+	for i, spec := range h.keySpecs {
+		var fieldHashes [][]byte
+		for j, hashes := range spec.fieldHashes {
+			if len(hashes) == 0 {
+				switch spec.Missing {
+				case MissingKeyFieldsFail:
+					return nil, &KeyFieldMissingError{spec.Paths[j]}
+				case MissingKeyFieldsNull:
+					nullHash, err := h.nullFieldHash(spec.patterns[j][len(spec.patterns[j])-1].field)
+					if err != nil {
+						return nil, err
+					}
+
+					hashes = [][]byte{nullHash}
+				default:
+					continue
+				}
+			}
+
+			fieldHash, err := h.keyFieldHash(spec.Paths[j], hashes)
+			if err != nil {
+				return nil, err
+			}
+
+			fieldHashes = append(fieldHashes, fieldHash)
+		}
+
+		digest, err := h.structDigest(fieldHashes)
+		if err != nil {
+			return nil, err
+		}
+
+		digests[i] = digest
+	}
+
+	return digests, nil
+}
+
+// nullFieldHash returns the hash of a struct field with the given name whose value is null.
+func (h *hasher) nullFieldHash(name string) ([]byte, error) {
+	hashFunction, err := h.hasherProvider.NewHasher()
+	if err != nil {
+		return nil, err
+	}
+
+	bs := &baseSerializer{hashFunction: hashFunction, limiter: &limiter{}}
+	fieldName := ion.NewSymbolTokenFromString(name)
+	err = bs.writeSymbolAsToken(&fieldName)
+	if err != nil {
+		return nil, err
+	}
+
+	err = bs.writeScalar(ion.NullType, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return bs.sum(nil), nil
+}
+
+// keyFieldHash returns the hash of a struct field named by path whose value is a struct with the
+// given field hashes.
+func (h *hasher) keyFieldHash(path string, fieldHashes [][]byte) ([]byte, error) {
+	hashFunction, err := h.hasherProvider.NewHasher()
+	if err != nil {
+		return nil, err
+	}
+
+	bs := &baseSerializer{hashFunction: hashFunction, limiter: &limiter{}}
+	fieldName := ion.NewSymbolTokenFromString(path)
+	err = bs.writeSymbolAsToken(&fieldName)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writeStruct(bs, fieldHashes)
+	if err != nil {
+		return nil, err
+	}
+
+	return bs.sum(nil), nil
+}
+
+// structDigest returns the hash of a struct with the given field hashes.
+func (h *hasher) structDigest(fieldHashes [][]byte) ([]byte, error) {
+	hashFunction, err := h.hasherProvider.NewHasher()
+	if err != nil {
+		return nil, err
+	}
+
+	bs := &baseSerializer{hashFunction: hashFunction, limiter: &limiter{}}
+	err = writeStruct(bs, fieldHashes)
+	if err != nil {
+		return nil, err
+	}
+
+	return bs.sum(nil), nil
+}
+
+// writeStruct serializes a struct with the given field hashes, which it sorts, to bs.
+func writeStruct(bs *baseSerializer, fieldHashes [][]byte) error {
+	sort.Sort(sortableBytes(fieldHashes))
+
+	err := bs.beginMarker()
+	if err != nil {
+		return err
+	}
+
+	err = bs.writeByte(byte(ion.StructType) << 4)
+	if err != nil {
+		return err
+	}
+
+	for _, fieldHash := range fieldHashes {
+		err = bs.writeEscaped(fieldHash)
+		if err != nil {
+			return err
+		}
+	}
+
+	return bs.endMarker()
+}
@@ -0,0 +1,189 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/amzn/ion-go/ion"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// keyDigests returns the key digests of each top-level value in text, failing unless a HashReader,
+// a HashWriter and a BinaryHashReader agree on them.
+func keyDigests(t *testing.T, text string, opts ...Option) [][][]byte {
+	hasherProvider := NewCryptoHasherProvider(SHA256)
+
+	ionHashReader, err := NewHashReader(ion.NewReaderString(text), hasherProvider, opts...)
+	require.NoError(t, err, "Expected NewHashReader() to successfully create a HashReader")
+
+	var readerDigests [][][]byte
+	for ionHashReader.Next() {
+		digests, err := ionHashReader.KeyDigests()
+		require.NoError(t, err, "Something went wrong executing ionHashReader.KeyDigests()")
+		readerDigests = append(readerDigests, digests)
+	}
+	require.NoError(t, ionHashReader.Err(), "Something went wrong executing ionHashReader.Next()")
+
+	// A HashReader's key digests are those of the value before the current one.
+	digests, err := ionHashReader.KeyDigests()
+	require.NoError(t, err, "Something went wrong executing ionHashReader.KeyDigests()")
+	readerDigests = append(readerDigests[1:], digests)
+
+	ionHashWriter, err := NewHashWriter(ion.NewBinaryWriter(&bytes.Buffer{}), hasherProvider, opts...)
+	require.NoError(t, err, "Expected NewHashWriter() to successfully create a HashWriter")
+
+	binaryHashReader, err := NewBinaryHashReader(toBinary(t, text), hasherProvider, opts...)
+	require.NoError(t, err, "Expected NewBinaryHashReader() to successfully create a BinaryHashReader")
+
+	reader := ion.NewReaderString(text)
+	for i := 0; reader.Next(); i++ {
+		require.NoError(t, writeValue(reader, ionHashWriter))
+		digests, err := ionHashWriter.KeyDigests()
+		require.NoError(t, err, "Something went wrong executing ionHashWriter.KeyDigests()")
+		assert.Equal(t, readerDigests[i], digests, "HashWriter key digests did not match HashReader key digests")
+
+		require.True(t, binaryHashReader.Next(), binaryHashReader.Err())
+		digests, err = binaryHashReader.KeyDigests()
+		require.NoError(t, err, "Something went wrong executing binaryHashReader.KeyDigests()")
+		assert.Equal(t, readerDigests[i], digests, "BinaryHashReader key digests did not match HashReader key digests")
+	}
+	require.NoError(t, reader.Err())
+
+	return readerDigests
+}
+
+// sha256Sum returns the digest of the single value in text.
+func sha256Sum(t *testing.T, text string) []byte {
+	sums, err := HashBinary(toBinary(t, text), NewCryptoHasherProvider(SHA256))
+	require.NoError(t, err, "Something went wrong executing HashBinary()")
+	require.Len(t, sums, 1)
+	return sums[0]
+}
+
+func TestKeyDigests(t *testing.T) {
+	text := `{tenant:"a", order_id:1, line:2, total:3.5}
+		{total:4.0, line:2, order_id:1, tenant:"a", note:"x"}
+		{tenant:"a", order_id:1, line:3, total:3.5}`
+
+	digests := keyDigests(t, text, WithKeyDigests(
+		KeySpec{Paths: []string{"tenant", "order_id", "line"}},
+		KeySpec{Paths: []string{"tenant"}}))
+	require.Len(t, digests, 3)
+
+	lineKey := sha256Sum(t, `{tenant:{tenant:"a"}, order_id:{order_id:1}, line:{line:2}}`)
+	tenantKey := sha256Sum(t, `{tenant:{tenant:"a"}}`)
+	assert.Equal(t, [][]byte{lineKey, tenantKey}, digests[0])
+	assert.Equal(t, [][]byte{lineKey, tenantKey}, digests[1])
+	assert.Equal(t, [][]byte{sha256Sum(t, `{tenant:{tenant:"a"}, order_id:{order_id:1}, line:{line:3}}`), tenantKey}, digests[2])
+}
+
+func TestKeyDigestsOfNestedFields(t *testing.T) {
+	testCases := []struct {
+		text     string
+		paths    []string
+		expected string
+	}{
+		{`{order:{id:1, x:2}, y:3}`, []string{"order.id"}, `{'order.id':{id:1}}`},
+		{`{customer:{name:"n", tags:[a, b]}, y:3}`, []string{"customer"}, `{customer:{customer:{name:"n", tags:[a, b]}}}`},
+		{`{lines:[{id:1, q:2}, {id:2, q:3}]}`, []string{"lines[*].id"}, `{'lines[*].id':{id:1, id:2}}`},
+		{`{a:1, b:2, c:3}`, []string{"*"}, `{'*':{a:1, b:2, c:3}}`},
+		{`{a:x::1, b:2}`, []string{"a"}, `{a:{a:x::1}}`},
+		{`{a:1, a:2}`, []string{"a"}, `{a:{a:1, a:2}}`},
+		{`{a:{id:1}, b:{id:2}}`, []string{"a.id", "b.id"}, `{'a.id':{id:1}, 'b.id':{id:2}}`},
+	}
+
+	for _, tc := range testCases {
+		digests := keyDigests(t, tc.text, WithKeyDigests(KeySpec{Paths: tc.paths}))
+		assert.Equal(t, [][][]byte{{sha256Sum(t, tc.expected)}}, digests, "Key digest of %s did not match", tc.text)
+	}
+}
+
+func TestKeyDigestsOfFieldsWithTheSameName(t *testing.T) {
+	// The same values at paths that end in the same field name are different keys.
+	spec := WithKeyDigests(KeySpec{Paths: []string{"a.id", "b.id"}})
+	digests := keyDigests(t, `{a:{id:1}, b:{id:2}} {a:{id:2}, b:{id:1}}`, spec)
+	require.Len(t, digests, 2)
+	assert.NotEqual(t, digests[0], digests[1])
+}
+
+func TestKeyDigestsMissingFields(t *testing.T) {
+	text := `{tenant:"a", total:1}`
+	paths := []string{"tenant", "order_id"}
+
+	assert.Equal(t, [][][]byte{{sha256Sum(t, `{tenant:{tenant:"a"}, order_id:{order_id:null}}`)}},
+		keyDigests(t, text, WithKeyDigests(KeySpec{Paths: paths, Missing: MissingKeyFieldsNull})))
+	assert.Equal(t, [][][]byte{{sha256Sum(t, `{tenant:{tenant:"a"}}`)}},
+		keyDigests(t, text, WithKeyDigests(KeySpec{Paths: paths, Missing: MissingKeyFieldsOmit})))
+
+	ionHashReader, err := NewHashReader(ion.NewReaderString(text), NewCryptoHasherProvider(SHA256),
+		WithKeyDigests(KeySpec{Paths: []string{"tenant"}}, KeySpec{Paths: paths}))
+	require.NoError(t, err, "Expected NewHashReader() to successfully create a HashReader")
+
+	require.True(t, ionHashReader.Next())
+	require.False(t, ionHashReader.Next())
+	_, err = ionHashReader.KeyDigests()
+	assert.True(t, errors.Is(err, ErrKeyFieldMissing), "Expected a KeyFieldMissingError")
+	assert.Equal(t, &KeyFieldMissingError{"order_id"}, err)
+
+	// Excluded fields are missing.
+	assert.Equal(t, [][][]byte{{sha256Sum(t, `{tenant:{tenant:"a"}}`)}},
+		keyDigests(t, `{tenant:"a", order_id:1}`, WithExcludedFields("order_id"),
+			WithKeyDigests(KeySpec{Paths: paths, Missing: MissingKeyFieldsOmit})))
+}
+
+func TestKeyDigestsInvalid(t *testing.T) {
+	specs := []KeySpec{
+		{Paths: []string{""}},
+		{Paths: []string{"a[0]"}},
+		{Paths: []string{"a..b"}},
+		{Paths: []string{"a.*"}, Missing: MissingKeyFieldsNull},
+	}
+
+	for _, spec := range specs {
+		_, err := NewHashReader(ion.NewReaderString("{}"), NewCryptoHasherProvider(SHA256), WithKeyDigests(spec))
+		assert.True(t, errors.Is(err, ErrInvalidArgument), "Expected an InvalidArgumentError for %v", spec.Paths)
+	}
+}
+
+func TestKeyDigestsState(t *testing.T) {
+	ionHashReader, err := NewHashReader(ion.NewReaderString(`{a:1}`), NewCryptoHasherProvider(SHA256),
+		WithKeyDigests(KeySpec{Paths: []string{"a"}}))
+	require.NoError(t, err, "Expected NewHashReader() to successfully create a HashReader")
+
+	// No value has been hashed yet.
+	digests, err := ionHashReader.KeyDigests()
+	require.NoError(t, err)
+	assert.Nil(t, digests)
+
+	require.True(t, ionHashReader.Next())
+	require.NoError(t, ionHashReader.StepIn())
+	_, err = ionHashReader.KeyDigests()
+	assert.IsType(t, &InvalidOperationError{}, err)
+	require.NoError(t, ionHashReader.StepOut())
+
+	// Without any KeySpecs, there are no key digests.
+	ionHashReader, err = NewHashReader(ion.NewReaderString(`{a:1}`), NewCryptoHasherProvider(SHA256))
+	require.NoError(t, err, "Expected NewHashReader() to successfully create a HashReader")
+	require.True(t, ionHashReader.Next())
+	require.False(t, ionHashReader.Next())
+	digests, err = ionHashReader.KeyDigests()
+	require.NoError(t, err)
+	assert.Nil(t, digests)
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+// Limits bounds the resources that hashing a stream of Ion values may use, so that untrusted
+// input can be hashed safely. A limit of zero or less is not enforced, though WithLimits keeps the
+// current setting of a limit that is zero.
+type Limits struct {
+	// MaxDepth is the deepest that containers may be nested, where a top-level container
+	// has a depth of one.
+	MaxDepth int
+
+	// MaxStructFields is the most fields that a struct may have. The hash of each field
+	// is held in memory until the end of the struct, so that they can be sorted. It is also the
+	// most values that a list or sexp hashed without regard to order may have, for the same reason.
+	MaxStructFields int
+
+	// MaxValueBytes is the most bytes of scalars, field names and annotations that a
+	// top-level value may have, measured by the length of their representations.
+	MaxValueBytes int64
+
+	// MaxAnnotations is the most annotations that a value may have.
+	MaxAnnotations int
+
+	// MaxTopLevelValues is the most top-level values that may be hashed.
+	MaxTopLevelValues int
+}
+
+// The names of the limits, as reported by a LimitExceededError.
+const (
+	LimitMaxDepth          = "MaxDepth"
+	LimitMaxStructFields   = "MaxStructFields"
+	LimitMaxValueBytes     = "MaxValueBytes"
+	LimitMaxAnnotations    = "MaxAnnotations"
+	LimitMaxTopLevelValues = "MaxTopLevelValues"
+)
+
+// limiter enforces Limits. It is shared by a hasher and all of its serializers.
+type limiter struct {
+	limits Limits
+
+	topLevelValues int
+	valueBytes     int64
+
+	// depth is the depth of the value being hashed, i.e. the number of containers it is in.
+	depth int
+}
+
+// beginValue checks the limits that apply to a value before it is hashed at the given depth.
+func (l *limiter) beginValue(ionValue hashValue, depth int) error {
+	l.depth = depth
+	if depth == 0 {
+		l.topLevelValues++
+		l.valueBytes = 0
+
+		if l.limits.MaxTopLevelValues > 0 && l.topLevelValues > l.limits.MaxTopLevelValues {
+			return l.exceeded(LimitMaxTopLevelValues, int64(l.limits.MaxTopLevelValues))
+		}
+	}
+
+	if l.limits.MaxAnnotations > 0 {
+		annotations, err := ionValue.getAnnotations()
+		if err != nil {
+			return err
+		}
+
+		if len(annotations) > l.limits.MaxAnnotations {
+			return l.exceeded(LimitMaxAnnotations, int64(l.limits.MaxAnnotations))
+		}
+	}
+
+	return nil
+}
+
+// stepIn checks the limits that apply to the current value before stepping in to it.
+func (l *limiter) stepIn() error {
+	if l.limits.MaxDepth > 0 && l.depth >= l.limits.MaxDepth {
+		return l.exceeded(LimitMaxDepth, int64(l.limits.MaxDepth))
+	}
+
+	return nil
+}
+
+// structField checks the limits that apply to a struct with the given number of fields
+// before another field is added to it at the given depth.
+func (l *limiter) structField(fields int, depth int) error {
+	if l.limits.MaxStructFields > 0 && fields >= l.limits.MaxStructFields {
+		l.depth = depth
+		return l.exceeded(LimitMaxStructFields, int64(l.limits.MaxStructFields))
+	}
+
+	return nil
+}
+
+// addValueBytes counts the length of a representation towards the current top-level value.
+func (l *limiter) addValueBytes(n int) error {
+	l.valueBytes += int64(n)
+	if l.limits.MaxValueBytes > 0 && l.valueBytes > l.limits.MaxValueBytes {
+		return l.exceeded(LimitMaxValueBytes, l.limits.MaxValueBytes)
+	}
+
+	return nil
+}
+
+func (l *limiter) exceeded(limit string, max int64) error {
+	return &LimitExceededError{limit, max, l.topLevelValues - 1, l.depth}
+}
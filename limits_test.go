@@ -0,0 +1,324 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/amzn/ion-go/ion"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var limitsTestCases = []struct {
+	name     string
+	text     string
+	limits   Limits
+	expected *LimitExceededError
+}{
+	{"within limits", `a::{b: [1, 2], c: "hello"} 2`,
+		Limits{MaxDepth: 2, MaxStructFields: 2, MaxValueBytes: 10, MaxAnnotations: 1, MaxTopLevelValues: 2}, nil},
+	{"depth", `1 {a: [[1]]}`, Limits{MaxDepth: 2}, &LimitExceededError{LimitMaxDepth, 2, 1, 2}},
+	{"struct fields", `[{a: 1, b: 2}, {a: 1, b: 2, c: 3}]`, Limits{MaxStructFields: 2},
+		&LimitExceededError{LimitMaxStructFields, 2, 0, 2}},
+	{"struct fields with containers", `{a: [], b: {}, c: ()}`, Limits{MaxStructFields: 2},
+		&LimitExceededError{LimitMaxStructFields, 2, 0, 1}},
+	{"value bytes", `"hello" ["hello", "world"]`, Limits{MaxValueBytes: 9},
+		&LimitExceededError{LimitMaxValueBytes, 9, 1, 1}},
+	{"value bytes of field names", `{hello: "world"}`, Limits{MaxValueBytes: 9},
+		&LimitExceededError{LimitMaxValueBytes, 9, 0, 1}},
+	{"annotations", `a::1 [a::b::2]`, Limits{MaxAnnotations: 1}, &LimitExceededError{LimitMaxAnnotations, 1, 1, 1}},
+	{"top-level values", `1 [2] 3`, Limits{MaxTopLevelValues: 2}, &LimitExceededError{LimitMaxTopLevelValues, 2, 2, 0}},
+}
+
+func TestHashReaderLimits(t *testing.T) {
+	for _, tc := range limitsTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hr, err := NewHashReader(ion.NewReaderString(tc.text), NewCryptoHasherProvider(SHA256), WithLimits(tc.limits))
+			require.NoError(t, err, "Expected NewHashReader() to successfully create a HashReader")
+
+			for hr.Next() {
+			}
+
+			if tc.expected == nil {
+				assert.NoError(t, hr.Err(), "Something went wrong executing hr.Next()")
+			} else {
+				assertLimitExceeded(t, tc.expected, hr.Err())
+			}
+		})
+	}
+}
+
+func TestHashWriterLimits(t *testing.T) {
+	for _, tc := range limitsTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hw, err := NewHashWriter(ion.NewTextWriter(&strings.Builder{}), NewCryptoHasherProvider(SHA256),
+				WithLimits(tc.limits))
+			require.NoError(t, err, "Expected NewHashWriter() to successfully create a HashWriter")
+
+			reader := ion.NewReaderString(tc.text)
+			for reader.Next() {
+				err = writeValue(reader, hw)
+				if err != nil {
+					break
+				}
+			}
+
+			if tc.expected == nil {
+				assert.NoError(t, err, "Something went wrong writing to the HashWriter")
+			} else {
+				assertLimitExceeded(t, tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestBinaryHashReaderLimits(t *testing.T) {
+	for _, tc := range limitsTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := HashBinary(toBinary(t, tc.text), NewCryptoHasherProvider(SHA256), WithLimits(tc.limits))
+
+			if tc.expected == nil {
+				assert.NoError(t, err, "Something went wrong executing HashBinary()")
+			} else {
+				assertLimitExceeded(t, tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestUnorderedSequenceLimits(t *testing.T) {
+	text := `[1, 2] {a:[1, 2, 3]} (1 2 3)`
+	limits := WithLimits(Limits{MaxStructFields: 2})
+
+	// Lists and sexps hashed in order don't hold the digests of their values.
+	assert.Len(t, hashValues(t, text, limits), 3)
+
+	expected := &LimitExceededError{LimitMaxStructFields, 2, 1, 2}
+	hr, err := NewHashReader(ion.NewReaderString(text), NewCryptoHasherProvider(SHA256), limits, WithUnorderedPaths("a"))
+	require.NoError(t, err, "Expected NewHashReader() to successfully create a HashReader")
+
+	for hr.Next() {
+	}
+	assertLimitExceeded(t, expected, hr.Err())
+
+	_, err = HashBinary(toBinary(t, text), NewCryptoHasherProvider(SHA256), limits, WithUnorderedPaths("a"))
+	assertLimitExceeded(t, expected, err)
+
+	hw, err := NewHashWriter(ion.NewTextWriter(&strings.Builder{}), NewCryptoHasherProvider(SHA256), limits,
+		WithUnorderedSequences())
+	require.NoError(t, err, "Expected NewHashWriter() to successfully create a HashWriter")
+
+	reader := ion.NewReaderString(text)
+	for reader.Next() {
+		err = writeValue(reader, hw)
+		if err != nil {
+			break
+		}
+	}
+	assertLimitExceeded(t, expected, err)
+}
+
+func TestNoDefaultLimits(t *testing.T) {
+	text := strings.Repeat("[", 100000) + strings.Repeat("]", 100000)
+
+	hr, err := NewHashReader(ion.NewReaderString(text), NewCryptoHasherProvider(SHA256))
+	require.NoError(t, err, "Expected NewHashReader() to successfully create a HashReader")
+
+	for hr.Next() {
+	}
+	assert.NoError(t, hr.Err(), "Something went wrong executing hr.Next()")
+}
+
+func TestLimitsKeepMaxDepth(t *testing.T) {
+	text := strings.Repeat("[", 11) + strings.Repeat("]", 11)
+
+	// Limits that aren't set keep the settings of earlier options.
+	hr, err := NewHashReader(ion.NewReaderString(text), NewCryptoHasherProvider(SHA256),
+		WithMaxDepth(10), WithLimits(Limits{MaxValueBytes: 1 << 20}))
+	require.NoError(t, err, "Expected NewHashReader() to successfully create a HashReader")
+
+	for hr.Next() {
+	}
+	assertLimitExceeded(t, &LimitExceededError{LimitMaxDepth, 10, 0, 10}, hr.Err())
+
+	// A limit of less than zero removes it.
+	hr, err = NewHashReader(ion.NewReaderString(text), NewCryptoHasherProvider(SHA256),
+		WithMaxDepth(10), WithLimits(Limits{MaxDepth: -1, MaxTopLevelValues: 1}))
+	require.NoError(t, err, "Expected NewHashReader() to successfully create a HashReader")
+
+	for hr.Next() {
+	}
+	assert.NoError(t, hr.Err(), "Something went wrong executing hr.Next()")
+}
+
+func TestLimitsMerge(t *testing.T) {
+	o := newOptions([]Option{WithMaxDepth(5), WithLimits(Limits{MaxAnnotations: 2}), WithLimits(Limits{MaxValueBytes: 9})})
+	assert.Equal(t, Limits{MaxDepth: 5, MaxValueBytes: 9, MaxAnnotations: 2}, o.limits)
+}
+
+func assertLimitExceeded(t *testing.T, expected *LimitExceededError, err error) {
+	var limitExceededError *LimitExceededError
+	require.True(t, errors.As(err, &limitExceededError), "Expected a LimitExceededError")
+	assert.Equal(t, expected, limitExceededError, "error did not match expectation")
+}
+
+// writeValue writes the reader's current value to the writer, returning the first error from the writer.
+func writeValue(reader ion.Reader, writer ion.Writer) error {
+	if reader.IsInStruct() {
+		fieldName, err := reader.FieldName()
+		if err != nil {
+			return err
+		}
+
+		err = writer.FieldName(*fieldName)
+		if err != nil {
+			return err
+		}
+	}
+
+	annotations, err := reader.Annotations()
+	if err != nil {
+		return err
+	}
+
+	if len(annotations) > 0 {
+		err = writer.Annotations(annotations...)
+		if err != nil {
+			return err
+		}
+	}
+
+	if reader.IsNull() {
+		return writer.WriteNullType(reader.Type())
+	}
+
+	switch reader.Type() {
+	case ion.BoolType:
+		val, err := reader.BoolValue()
+		if err != nil {
+			return err
+		}
+
+		return writer.WriteBool(*val)
+	case ion.IntType:
+		size, err := reader.IntSize()
+		if err != nil {
+			return err
+		}
+
+		if size == ion.BigInt {
+			val, err := reader.BigIntValue()
+			if err != nil {
+				return err
+			}
+
+			return writer.WriteBigInt(val)
+		}
+
+		val, err := reader.Int64Value()
+		if err != nil {
+			return err
+		}
+
+		return writer.WriteInt(*val)
+	case ion.StringType:
+		val, err := reader.StringValue()
+		if err != nil {
+			return err
+		}
+
+		return writer.WriteString(*val)
+	case ion.FloatType:
+		val, err := reader.FloatValue()
+		if err != nil {
+			return err
+		}
+
+		return writer.WriteFloat(*val)
+	case ion.SymbolType:
+		val, err := reader.SymbolValue()
+		if err != nil {
+			return err
+		}
+
+		return writer.WriteSymbol(*val)
+	case ion.DecimalType:
+		val, err := reader.DecimalValue()
+		if err != nil {
+			return err
+		}
+
+		return writer.WriteDecimal(val)
+	case ion.TimestampType:
+		val, err := reader.TimestampValue()
+		if err != nil {
+			return err
+		}
+
+		return writer.WriteTimestamp(*val)
+	case ion.BlobType, ion.ClobType:
+		val, err := reader.ByteValue()
+		if err != nil {
+			return err
+		}
+
+		if reader.Type() == ion.BlobType {
+			return writer.WriteBlob(val)
+		}
+
+		return writer.WriteClob(val)
+	case ion.ListType, ion.SexpType, ion.StructType:
+		var begin func() error
+		var end func() error
+		switch reader.Type() {
+		case ion.ListType:
+			begin, end = writer.BeginList, writer.EndList
+		case ion.SexpType:
+			begin, end = writer.BeginSexp, writer.EndSexp
+		default:
+			begin, end = writer.BeginStruct, writer.EndStruct
+		}
+
+		err = begin()
+		if err != nil {
+			return err
+		}
+
+		err = reader.StepIn()
+		if err != nil {
+			return err
+		}
+
+		for reader.Next() {
+			err = writeValue(reader, writer)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = reader.StepOut()
+		if err != nil {
+			return err
+		}
+
+		return end()
+	}
+
+	return &InvalidIonTypeError{reader.Type()}
+}
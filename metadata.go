@@ -0,0 +1,44 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+// DigestMetadata describes some of the settings that digests depend on besides the values hashed,
+// for storing alongside digests so that digests computed with different settings can be told apart.
+// It describes only the quantizations and the domain, not the other options that change digests,
+// such as exclusions, field aliases, transformers, annotation filters, profiles and unordered paths,
+// so digests whose DigestMetadata are equal may still have been computed with different options.
+type DigestMetadata struct {
+	// Quantization describes the quantizations given with WithQuantization, e.g. sig=6, or is
+	// empty if there are none.
+	Quantization string
+
+	// Domain is the domain of the DomainHasherProvider that digests are computed with, or is empty
+	// if there is none.
+	Domain string
+}
+
+// metadata returns the DigestMetadata of the hasher's digests.
+func (h *hasher) metadata() DigestMetadata {
+	metadata := DigestMetadata{
+		Quantization: describeQuantizations(h.quantizations),
+	}
+
+	if dhp, ok := h.subtreeProvider.(*DomainHasherProvider); ok {
+		metadata.Domain = dhp.domain
+	}
+
+	return metadata
+}
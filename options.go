@@ -0,0 +1,314 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+import "context"
+
+// An Option configures a HashReader, HashWriter or BinaryHashReader. Besides WithMaxDepth,
+// WithLimits, WithContext, WithStats, WithKeyDigests and WithSubtrees, which bound, observe or add
+// to hashing, options change how values are hashed, and the digests computed with them are then not
+// those of the Ion Hash specification.
+type Option func(*options)
+
+type options struct {
+	limits  Limits
+	ctx     context.Context
+	stats   *Stats
+	profile HashProfile
+
+	unorderedSequences bool
+	unorderedPaths     []string
+	setAnnotations     []string
+
+	orderedStructs     bool
+	orderedStructPaths []string
+
+	excludedFields      []string
+	excludedPaths       []string
+	excludedAnnotations []string
+
+	ignoreAllAnnotations bool
+	allowedAnnotations   []string
+	ignoredAnnotations   []string
+	unorderedAnnotations bool
+
+	absentFields AbsentFields
+
+	fieldAliases   map[string]string
+	fieldNameFuncs []FieldNameFunc
+
+	transformers []Transformer
+	redactions   bool
+
+	quantizations []quantizationRule
+
+	keySpecs []KeySpec
+
+	subtreePaths []string
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// WithMaxDepth sets the deepest that containers may be nested, where a top-level container
+// has a depth of one. Hashing a value that is nested more deeply fails with a LimitExceededError.
+// Nesting isn't limited unless this or WithLimits sets a limit, and a maxDepth of zero or less
+// removes it. It is the same as setting Limits.MaxDepth.
+func WithMaxDepth(maxDepth int) Option {
+	return func(o *options) {
+		o.limits.MaxDepth = maxDepth
+	}
+}
+
+// WithLimits sets the limits on the resources that hashing may use. Exceeding one of them fails
+// with a LimitExceededError. Nothing is limited unless an option sets a limit. A limit of zero keeps
+// the setting of an earlier option, and a limit of less than zero removes it.
+func WithLimits(limits Limits) Option {
+	return func(o *options) {
+		mergeLimit(&o.limits.MaxDepth, limits.MaxDepth)
+		mergeLimit(&o.limits.MaxStructFields, limits.MaxStructFields)
+		mergeLimit(&o.limits.MaxValueBytes, limits.MaxValueBytes)
+		mergeLimit(&o.limits.MaxAnnotations, limits.MaxAnnotations)
+		mergeLimit(&o.limits.MaxTopLevelValues, limits.MaxTopLevelValues)
+	}
+}
+
+// mergeLimit sets limit to val unless val is zero.
+func mergeLimit[T int | int64](limit *T, val T) {
+	if val != 0 {
+		*limit = val
+	}
+}
+
+// WithContext makes hashing stop once ctx is done. The context is checked periodically as values are
+// hashed, including while a HashReader traverses a container, and once it is done hashing fails with
+// the context's error.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// WithProfile sets the profile that selects how values are hashed, e.g. SemanticProfile. Without it,
+// values are hashed as the Ion Hash specification defines.
+func WithProfile(profile HashProfile) Option {
+	return func(o *options) {
+		o.profile = profile
+	}
+}
+
+// WithStats makes hashing update stats as values are hashed. The same Stats may be given to
+// many HashReaders, HashWriters and BinaryHashReaders to collect statistics across all of them.
+func WithStats(stats *Stats) Option {
+	return func(o *options) {
+		o.stats = stats
+	}
+}
+
+// WithUnorderedSequences makes all lists and sexps hash the same regardless of the order of their
+// values, as structs do, so that they are hashed as multisets. To hash only some lists and sexps
+// this way, see WithUnorderedPaths and WithSetAnnotation.
+func WithUnorderedSequences() Option {
+	return func(o *options) {
+		o.unorderedSequences = true
+	}
+}
+
+// WithUnorderedPaths makes the lists and sexps at the given paths hash the same regardless of the
+// order of their values. Paths are written like those of HashErrors, e.g. users[*].roles, where
+// * matches any field name and [*] matches any index. An invalid path makes creating the
+// HashReader, HashWriter or BinaryHashReader fail with an InvalidArgumentError.
+func WithUnorderedPaths(paths ...string) Option {
+	return func(o *options) {
+		o.unorderedPaths = append(o.unorderedPaths, paths...)
+	}
+}
+
+// WithSetAnnotation makes the lists and sexps annotated with annotation, e.g. set::[a, b], hash the
+// same regardless of the order of their values. The annotation is hashed along with the value.
+func WithSetAnnotation(annotation string) Option {
+	return func(o *options) {
+		o.setAnnotations = append(o.setAnnotations, annotation)
+	}
+}
+
+// WithOrderedStructs makes structs hash differently if the order of their fields differs, as some
+// protocols require. Fields with the same name are kept in order too. To hash only some structs this
+// way, see WithOrderedStructPaths.
+func WithOrderedStructs() Option {
+	return func(o *options) {
+		o.orderedStructs = true
+	}
+}
+
+// WithOrderedStructPaths makes the structs at the given paths hash differently if the order of
+// their fields differs. Paths are written as for WithUnorderedPaths.
+func WithOrderedStructPaths(paths ...string) Option {
+	return func(o *options) {
+		o.orderedStructPaths = append(o.orderedStructPaths, paths...)
+	}
+}
+
+// WithExcludedFields excludes the struct fields with the given names, at any depth, from the hash.
+// An excluded value is still read by a HashReader, or written by a HashWriter to its Ion writer, but
+// it contributes nothing to the digest of its parent, as if it were absent. An excluded top-level
+// value has the digest of an empty input.
+func WithExcludedFields(fieldNames ...string) Option {
+	return func(o *options) {
+		o.excludedFields = append(o.excludedFields, fieldNames...)
+	}
+}
+
+// WithExcludedPaths excludes the values at the given paths from the hash, as WithExcludedFields does.
+// Paths are written as for WithUnorderedPaths.
+func WithExcludedPaths(paths ...string) Option {
+	return func(o *options) {
+		o.excludedPaths = append(o.excludedPaths, paths...)
+	}
+}
+
+// WithExcludedAnnotations excludes the values annotated with any of the given annotations, e.g.
+// volatile::"abc", from the hash, as WithExcludedFields does.
+func WithExcludedAnnotations(annotations ...string) Option {
+	return func(o *options) {
+		o.excludedAnnotations = append(o.excludedAnnotations, annotations...)
+	}
+}
+
+// WithoutAnnotations makes values hash the same regardless of their annotations, as though they had
+// none. Annotations still select values for WithSetAnnotation and WithExcludedAnnotations.
+func WithoutAnnotations() Option {
+	return func(o *options) {
+		o.ignoreAllAnnotations = true
+	}
+}
+
+// WithAllowedAnnotations makes only the given annotations affect the hash, ignoring any others,
+// including those whose text is unknown.
+func WithAllowedAnnotations(annotations ...string) Option {
+	return func(o *options) {
+		if o.allowedAnnotations == nil {
+			// Even an empty allow list drops every annotation.
+			o.allowedAnnotations = []string{}
+		}
+		o.allowedAnnotations = append(o.allowedAnnotations, annotations...)
+	}
+}
+
+// WithIgnoredAnnotations makes the given annotations, e.g. $comment, not affect the hash.
+func WithIgnoredAnnotations(annotations ...string) Option {
+	return func(o *options) {
+		o.ignoredAnnotations = append(o.ignoredAnnotations, annotations...)
+	}
+}
+
+// WithUnorderedAnnotations makes values hash the same regardless of the order of their annotations,
+// e.g. a::b::1 and b::a::1.
+func WithUnorderedAnnotations() Option {
+	return func(o *options) {
+		o.unorderedAnnotations = true
+	}
+}
+
+// WithAbsentFields makes struct fields with the values that absent selects hash as though the fields
+// were absent, so that, e.g., {a:1, b:null} and {a:1} hash the same. Values with annotations that are
+// hashed are never treated as absent.
+func WithAbsentFields(absent AbsentFields) Option {
+	return func(o *options) {
+		o.absentFields = absent
+	}
+}
+
+// WithFieldAliases makes fields named by the keys of aliases hash as though they were named by the
+// corresponding values, e.g. map[string]string{"customerId": "customer_id"}, so that documents from
+// before and after fields were renamed hash the same. Paths, e.g. in HashErrors and WithExcludedPaths,
+// use the fields' names rather than their aliases.
+func WithFieldAliases(aliases map[string]string) Option {
+	return func(o *options) {
+		if o.fieldAliases == nil {
+			o.fieldAliases = make(map[string]string, len(aliases))
+		}
+		for name, alias := range aliases {
+			o.fieldAliases[name] = alias
+		}
+	}
+}
+
+// WithFieldNameFunc makes fields hash as though they were named by the name that fn returns for them,
+// as WithFieldAliases does. Functions are applied in the order they are given, after any aliases.
+func WithFieldNameFunc(fn FieldNameFunc) Option {
+	return func(o *options) {
+		o.fieldNameFuncs = append(o.fieldNameFuncs, fn)
+	}
+}
+
+// WithTransformer makes scalars hash as though they were the scalars that transformer returns for
+// them, e.g. to trim strings or round the floats of a field, or as though they were absent if it
+// drops them. Transformers are applied in the order they are given, after values are excluded. The
+// Ion reader or writer still sees the original values. A BinaryHashReader doesn't support
+// transformers, so creating one with them fails with an InvalidArgumentError.
+func WithTransformer(transformer Transformer) Option {
+	return func(o *options) {
+		o.transformers = append(o.transformers, transformer)
+	}
+}
+
+// WithRedactions hashes the placeholders of fields that Redact redacts as the fields that they
+// replace, so that a redacted value has the digest of the original value. A placeholder is a blob
+// annotated with ion_hash_redacted that holds the field's digest.
+func WithRedactions() Option {
+	return func(o *options) {
+		o.redactions = true
+	}
+}
+
+// WithQuantization rounds the floats, and the decimals if it calls for it, at paths, written as for
+// WithUnorderedPaths, or all of them if no paths are given, as quantization calls for before they
+// are hashed. A quantization for paths applies in place of one for all values, and the first of
+// several that apply to a value is used. The quantizations are described by DigestMetadata, so that
+// digests computed with different ones can be told apart. An invalid quantization or path makes
+// creating the HashReader, HashWriter or BinaryHashReader fail with an InvalidArgumentError.
+func WithQuantization(quantization Quantization, paths ...string) Option {
+	return func(o *options) {
+		o.quantizations = append(o.quantizations, quantizationRule{Quantization: quantization, paths: paths})
+	}
+}
+
+// WithKeyDigests computes a key digest for each of specs from the fields that it selects, as values
+// are hashed, which KeyDigests returns. An invalid KeySpec makes creating the HashReader, HashWriter
+// or BinaryHashReader fail with an InvalidArgumentError.
+func WithKeyDigests(specs ...KeySpec) Option {
+	return func(o *options) {
+		o.keySpecs = append(o.keySpecs, specs...)
+	}
+}
+
+// WithSubtrees computes the digest of every value whose path matches one of paths, written as for
+// WithUnorderedPaths, e.g. payload.body or items[*], as values are hashed, which SubtreeDigests
+// returns. A digest is the value's Ion Hash as though it were a top-level value, so the digests of a
+// top-level value's subtrees are computed while reading it once. An invalid path makes creating the
+// HashReader, HashWriter or BinaryHashReader fail with an InvalidArgumentError.
+func WithSubtrees(paths ...string) Option {
+	return func(o *options) {
+		o.subtreePaths = append(o.subtreePaths, paths...)
+	}
+}
@@ -0,0 +1,552 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/amzn/ion-go/ion"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countdownContext is a context that is done after its Err method has been called a number of times.
+type countdownContext struct {
+	context.Context
+	remaining int
+}
+
+func (c *countdownContext) Err() error {
+	if c.remaining == 0 {
+		return context.Canceled
+	}
+
+	c.remaining--
+	return nil
+}
+
+// largeList returns the Ion text of a list of many ints.
+func largeList() string {
+	return "[" + strings.Repeat("1,", 100*contextCheckInterval) + "]"
+}
+
+func TestWithContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	hr, err := NewHashReader(ion.NewReaderString("1"), NewCryptoHasherProvider(SHA256), WithContext(ctx))
+	require.NoError(t, err, "Expected NewHashReader() to successfully create a HashReader")
+
+	assert.True(t, hr.Next())
+	assert.False(t, hr.Next())
+	assert.Equal(t, context.Canceled, hr.Err())
+}
+
+func TestHashReaderWithContextDuringTraversal(t *testing.T) {
+	ctx := &countdownContext{context.Background(), 2}
+
+	hr, err := NewHashReader(ion.NewReaderString(largeList()), NewCryptoHasherProvider(SHA256), WithContext(ctx))
+	require.NoError(t, err, "Expected NewHashReader() to successfully create a HashReader")
+
+	assert.True(t, hr.Next())
+	assert.False(t, hr.Next())
+	assert.Equal(t, context.Canceled, hr.Err())
+}
+
+func TestHashWriterWithContext(t *testing.T) {
+	ctx := &countdownContext{context.Background(), 2}
+
+	hw, err := NewHashWriter(ion.NewTextWriter(&strings.Builder{}), NewCryptoHasherProvider(SHA256), WithContext(ctx))
+	require.NoError(t, err, "Expected NewHashWriter() to successfully create a HashWriter")
+
+	require.NoError(t, hw.BeginList(), "Something went wrong executing hw.BeginList()")
+	for i := 0; err == nil && i < 100*contextCheckInterval; i++ {
+		err = hw.WriteInt(1)
+	}
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestBinaryHashReaderWithContext(t *testing.T) {
+	ctx := &countdownContext{context.Background(), 2}
+
+	_, err := HashBinary(toBinary(t, largeList()), NewCryptoHasherProvider(SHA256), WithContext(ctx))
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestWithContextNotDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sums, err := HashBinary(toBinary(t, largeList()), NewCryptoHasherProvider(SHA256), WithContext(ctx))
+	require.NoError(t, err, "Something went wrong executing HashBinary()")
+	assert.Len(t, sums, 1)
+}
+
+// assertSameHashes asserts whether the two values in text hash the same.
+func assertSameHashes(t *testing.T, expected bool, text string, opts ...Option) {
+	sums := hashValues(t, text, opts...)
+	require.Len(t, sums, 2)
+	assert.Equal(t, expected, bytes.Equal(sums[0], sums[1]), "Expected values of %s to hash the same: %v", text, expected)
+}
+
+func TestWithUnorderedSequences(t *testing.T) {
+	testCases := []struct {
+		text string
+		same bool
+	}{
+		{"[a, b, c] [c, a, b]", true},
+		{"(a b c) (b c a)", true},
+		{"[1, [2, 3], {a:4}] [{a:4}, [2, 3], 1]", true},
+		{"[[1, 2], (3 4)] [(4 3), [2, 1]]", true},
+		{"{a:[1, 2]} {a:[2, 1]}", true},
+		{"x::[1, 2] x::[2, 1]", true},
+		{"[a, b] [a, b, b]", false},
+		{"[a, b] (a b)", false},
+		{"[a, b] [a, c]", false},
+		{"[] ()", false},
+	}
+
+	for _, tc := range testCases {
+		assertSameHashes(t, tc.same, tc.text, WithUnorderedSequences())
+	}
+
+	// Lists that differ only in order hash differently by default.
+	assertSameHashes(t, false, "[a, b, c] [c, a, b]")
+}
+
+func TestWithUnorderedPaths(t *testing.T) {
+	testCases := []struct {
+		text  string
+		paths []string
+		same  bool
+	}{
+		{"{tags:[a, b], order:[a, b]} {tags:[b, a], order:[a, b]}", []string{"tags"}, true},
+		{"{tags:[a, b], order:[a, b]} {tags:[a, b], order:[b, a]}", []string{"tags"}, false},
+		{"{users:[{roles:[r, w]}, {roles:(x y)}]} {users:[{roles:[w, r]}, {roles:(y x)}]}", []string{"users[*].roles"}, true},
+		{"{users:[{roles:[r, w]}, {roles:[x, y]}]} {users:[{roles:[w, r]}, {roles:[y, x]}]}", []string{"users[0].roles"}, false},
+		{"{a:{s:[1, 2]}, b:{s:[1, 2]}} {a:{s:[2, 1]}, b:{s:[2, 1]}}", []string{"*.s"}, true},
+		{"{'first name':[1, 2]} {'first name':[2, 1]}", []string{"'first name'"}, true},
+		{"[[1, 2], [3, 4]] [[2, 1], [3, 4]]", []string{"[0]"}, true},
+		{"[[1, 2], [3, 4]] [[3, 4], [1, 2]]", []string{"[*]"}, false},
+		{"[[1, 2], [3, 4]] [[3, 4], [1, 2]]", []string{""}, true},
+		{"[1, 2] [2, 1]", []string{"tags", ""}, true},
+	}
+
+	for _, tc := range testCases {
+		assertSameHashes(t, tc.same, tc.text, WithUnorderedPaths(tc.paths...))
+	}
+}
+
+func TestWithUnorderedPathsInvalid(t *testing.T) {
+	_, err := NewHashReader(ion.NewReaderString("[]"), NewCryptoHasherProvider(SHA256), WithUnorderedPaths("a..b"))
+	assert.True(t, errors.Is(err, ErrInvalidArgument), "Expected an InvalidArgumentError")
+
+	_, err = NewHashWriter(ion.NewTextWriter(&bytes.Buffer{}), NewCryptoHasherProvider(SHA256), WithUnorderedPaths("[x]"))
+	assert.True(t, errors.Is(err, ErrInvalidArgument), "Expected an InvalidArgumentError")
+
+	_, err = HashBinary(nil, NewCryptoHasherProvider(SHA256), WithUnorderedPaths("a["))
+	assert.True(t, errors.Is(err, ErrInvalidArgument), "Expected an InvalidArgumentError")
+}
+
+func TestWithSetAnnotation(t *testing.T) {
+	testCases := []struct {
+		text string
+		same bool
+	}{
+		{"set::[a, b] set::[b, a]", true},
+		{"x::set::(a b) x::set::(b a)", true},
+		{"{p:set::[1, 2]} {p:set::[2, 1]}", true},
+		{"set::[[1, 2], 3] set::[3, [1, 2]]", true},
+		{"set::[[1, 2], 3] set::[3, [2, 1]]", false},
+		{"set::[a, b] [a, b]", false},
+		{"[a, b] [b, a]", false},
+		{"bag::[a, b] bag::[b, a]", false},
+		{"set::{a:1} set::{a:1}", true},
+	}
+
+	for _, tc := range testCases {
+		assertSameHashes(t, tc.same, tc.text, WithSetAnnotation("set"))
+	}
+}
+
+func TestWithOrderedStructs(t *testing.T) {
+	testCases := []struct {
+		text    string
+		ordered bool
+	}{
+		{"{a:1, b:2} {b:2, a:1}", false},
+		{"{a:1, a:2} {a:2, a:1}", false},
+		{"{x:{a:1, b:2}} {x:{b:2, a:1}}", false},
+		{"[{a:1, b:2}] [{b:2, a:1}]", false},
+		{"{a:1, b:2} {a:1, b:2}", true},
+		{"{a:1, a:2} {a:1, a:2}", true},
+		{"{a:1, b:2} {a:1, b:3}", false},
+	}
+
+	for _, tc := range testCases {
+		assertSameHashes(t, tc.ordered, tc.text, WithOrderedStructs())
+	}
+
+	// Structs that differ only in the order of their fields hash the same by default.
+	assertSameHashes(t, true, "{a:1, a:2, b:{c:3, d:4}} {b:{d:4, c:3}, a:2, a:1}")
+}
+
+func TestWithOrderedStructPaths(t *testing.T) {
+	testCases := []struct {
+		text  string
+		paths []string
+		same  bool
+	}{
+		{"{h:{a:1, b:2}, p:{a:1, b:2}} {h:{b:2, a:1}, p:{a:1, b:2}}", []string{"h"}, false},
+		{"{h:{a:1, b:2}, p:{a:1, b:2}} {h:{a:1, b:2}, p:{b:2, a:1}}", []string{"h"}, true},
+		{"{h:{a:1, b:2}, p:{a:1, b:2}} {p:{a:1, b:2}, h:{a:1, b:2}}", []string{"h"}, true},
+		{"{a:1, b:2} {b:2, a:1}", []string{""}, false},
+		{"{a:1, h:{c:3, d:4}} {h:{c:3, d:4}, a:1}", []string{"h"}, true},
+		{"[{a:1, b:2}, {a:1, b:2}] [{a:1, b:2}, {b:2, a:1}]", []string{"[*]"}, false},
+		{"[{a:1, b:2}, {a:1, b:2}] [{a:1, b:2}, {b:2, a:1}]", []string{"[0]"}, true},
+	}
+
+	for _, tc := range testCases {
+		assertSameHashes(t, tc.same, tc.text, WithOrderedStructPaths(tc.paths...))
+	}
+
+	_, err := NewHashReader(ion.NewReaderString("{}"), NewCryptoHasherProvider(SHA256), WithOrderedStructPaths("a."))
+	assert.True(t, errors.Is(err, ErrInvalidArgument), "Expected an InvalidArgumentError")
+}
+
+func TestWithExcludedFields(t *testing.T) {
+	testCases := []struct {
+		text string
+		same bool
+	}{
+		{`{id:1, updated_at:2020T, _etag:"x"} {id:1, updated_at:2021T, _etag:"y"}`, true},
+		{`{id:1, updated_at:2020T, _etag:"x"} {id:1}`, true},
+		{`{id:1, updated_at:{by:"a", at:[2020T]}} {id:1}`, true},
+		{`{a:{updated_at:1, b:2}} {a:{b:2}}`, true},
+		{`[{updated_at:1}, {updated_at:2}] [{}, {}]`, true},
+		{`{id:1, updated_at:2020T} {id:2, updated_at:2020T}`, false},
+		{`{id:1, updated:2020T} {id:1}`, false},
+		{`[updated_at] []`, false},
+	}
+
+	for _, tc := range testCases {
+		assertSameHashes(t, tc.same, tc.text, WithExcludedFields("updated_at", "_etag"))
+	}
+}
+
+func TestWithExcludedPaths(t *testing.T) {
+	testCases := []struct {
+		text  string
+		paths []string
+		same  bool
+	}{
+		{"{meta:{etag:1}, id:1} {meta:{etag:2}, id:1}", []string{"meta.etag"}, true},
+		{"{meta:{etag:1}, id:1} {meta:{}, id:1}", []string{"meta.etag"}, true},
+		{"{etag:1, id:1} {etag:2, id:1}", []string{"meta.etag"}, false},
+		{"{items:[{v:1, t:2}, {v:3, t:4}]} {items:[{v:1}, {v:3}]}", []string{"items[*].t"}, true},
+		{"[1, 2, 3] [1, 9, 3]", []string{"[1]"}, true},
+		{"[1, 2, 3] [1, 3]", []string{"[1]"}, false},
+		{"1 2", []string{""}, true},
+	}
+
+	for _, tc := range testCases {
+		assertSameHashes(t, tc.same, tc.text, WithExcludedPaths(tc.paths...))
+	}
+
+	_, err := NewHashReader(ion.NewReaderString("{}"), NewCryptoHasherProvider(SHA256), WithExcludedPaths("[*"))
+	assert.True(t, errors.Is(err, ErrInvalidArgument), "Expected an InvalidArgumentError")
+}
+
+func TestWithExcludedAnnotations(t *testing.T) {
+	testCases := []struct {
+		text string
+		same bool
+	}{
+		{"[1, volatile::2, 3] [1, 3]", true},
+		{"{a:volatile::{b:[1]}, c:2} {c:2}", true},
+		{"(a volatile::(b c)) (a)", true},
+		{"x::volatile::1 volatile::2", true},
+		{"volatile::[1, 2] transient::{}", true},
+		{"[1, stable::2] [1]", false},
+	}
+
+	for _, tc := range testCases {
+		assertSameHashes(t, tc.same, tc.text, WithExcludedAnnotations("volatile", "transient"))
+	}
+
+	// An excluded top-level value has the digest of an empty input.
+	tihp := newTestIonHasherProvider("identity")
+	sums, err := HashBinary(toBinary(t, "volatile::1"), tihp.getInstance(), WithExcludedAnnotations("volatile"))
+	require.NoError(t, err, "Something went wrong executing HashBinary()")
+	assert.Equal(t, [][]byte{{}}, sums)
+}
+
+func TestExcludedValuesPassThrough(t *testing.T) {
+	text := `{id:1, updated_at:volatile::{by:"a"}, tags:[volatile::x, y]}`
+
+	ionHashReader, err := NewHashReader(ion.NewReaderString(text), NewCryptoHasherProvider(SHA256),
+		WithExcludedFields("updated_at"), WithExcludedAnnotations("volatile"))
+	require.NoError(t, err, "Expected NewHashReader() to successfully create a HashReader")
+
+	require.True(t, ionHashReader.Next())
+	require.NoError(t, ionHashReader.StepIn())
+
+	var fieldNames []string
+	for ionHashReader.Next() {
+		fieldName, err := ionHashReader.FieldName()
+		require.NoError(t, err)
+		fieldNames = append(fieldNames, *fieldName.Text)
+
+		if *fieldName.Text == "updated_at" {
+			require.NoError(t, ionHashReader.StepIn())
+			require.True(t, ionHashReader.Next())
+			val, err := ionHashReader.StringValue()
+			require.NoError(t, err)
+			assert.Equal(t, "a", *val)
+			require.NoError(t, ionHashReader.StepOut())
+		}
+	}
+	require.NoError(t, ionHashReader.Err())
+	require.NoError(t, ionHashReader.StepOut())
+	assert.Equal(t, []string{"id", "updated_at", "tags"}, fieldNames)
+
+	assert.False(t, ionHashReader.Next())
+	require.NoError(t, ionHashReader.Err())
+	readerSum, err := ionHashReader.Sum(nil)
+	require.NoError(t, err)
+
+	buf := bytes.Buffer{}
+	ionWriter := ion.NewTextWriter(&buf)
+	ionHashWriter, err := NewHashWriter(ionWriter, NewCryptoHasherProvider(SHA256),
+		WithExcludedFields("updated_at"), WithExcludedAnnotations("volatile"))
+	require.NoError(t, err, "Expected NewHashWriter() to successfully create a HashWriter")
+
+	reader := ion.NewReaderString(text)
+	require.True(t, reader.Next())
+	require.NoError(t, writeValue(reader, ionHashWriter))
+	writerSum, err := ionHashWriter.Sum(nil)
+	require.NoError(t, err)
+	require.NoError(t, ionHashWriter.Finish())
+
+	assert.Equal(t, `{id:1,updated_at:volatile::{by:"a"},tags:[volatile::x,y]}`, strings.TrimSpace(buf.String()))
+	assert.Equal(t, readerSum, writerSum)
+	assert.Equal(t, hashValues(t, "{id:1, tags:[y]}")[0], writerSum)
+}
+
+func TestWithoutAnnotations(t *testing.T) {
+	testCases := []string{
+		"a::1 1",
+		"a::b::[1, c::2] [1, 2]",
+		"{x:a::{y:b::(c::z)}} {x:{y:(z)}}",
+		"a::null.list null.list",
+		"$comment::[] []",
+	}
+
+	for _, text := range testCases {
+		assertSameHashes(t, false, text)
+		assertSameHashes(t, true, text, WithoutAnnotations())
+	}
+
+	assertSameHashes(t, false, "a::[1] a::[2]", WithoutAnnotations())
+
+	// Annotations still select values to hash as sets or to exclude.
+	assertSameHashes(t, true, "set::[1, 2] set::[2, 1]", WithoutAnnotations(), WithSetAnnotation("set"))
+	assertSameHashes(t, true, "[volatile::1, 2] [2]", WithoutAnnotations(), WithExcludedAnnotations("volatile"))
+}
+
+func TestWithAllowedAnnotations(t *testing.T) {
+	testCases := []struct {
+		text string
+		same bool
+	}{
+		{"hint::1 1", true},
+		{"hint::money::1 money::1", true},
+		{"money::[hint::1] money::[1]", true},
+		{"money::1 1", false},
+		{"money::{a:hint::{}} {a:{}}", false},
+	}
+
+	for _, tc := range testCases {
+		assertSameHashes(t, tc.same, tc.text, WithAllowedAnnotations("money"))
+	}
+
+	assertSameHashes(t, true, "money::1 1", WithAllowedAnnotations())
+}
+
+func TestWithIgnoredAnnotations(t *testing.T) {
+	testCases := []struct {
+		text string
+		same bool
+	}{
+		{"$comment::1 1", true},
+		{"a::$comment::b::[1] a::b::[1]", true},
+		{"{x:$comment::(1)} {x:(1)}", true},
+		{"a::1 1", false},
+		{"$comment::a::1 a::$comment::1", true},
+	}
+
+	for _, tc := range testCases {
+		assertSameHashes(t, tc.same, tc.text, WithIgnoredAnnotations("$comment"))
+	}
+
+	// An annotation that is both allowed and ignored is ignored.
+	assertSameHashes(t, true, "a::b::1 b::1", WithAllowedAnnotations("a", "b"), WithIgnoredAnnotations("a"))
+}
+
+func TestWithUnorderedAnnotations(t *testing.T) {
+	testCases := []struct {
+		text string
+		same bool
+	}{
+		{"a::b::1 b::a::1", true},
+		{"a::b::c::[1] c::a::b::[1]", true},
+		{"{x:b::a::{}} {x:a::b::{}}", true},
+		{"a::b::1 a::1", false},
+		{"a::a::1 a::1", false},
+	}
+
+	for _, tc := range testCases {
+		assertSameHashes(t, tc.same, tc.text, WithUnorderedAnnotations())
+	}
+
+	assertSameHashes(t, false, "a::b::1 b::a::1")
+}
+
+func TestWithAbsentFields(t *testing.T) {
+	untyped := AbsentFields{Nulls: UntypedNulls}
+	all := AbsentFields{Nulls: AllNulls}
+	empty := AbsentFields{EmptyContainers: true}
+	allAndEmpty := AbsentFields{Nulls: AllNulls, EmptyContainers: true}
+
+	testCases := []struct {
+		text   string
+		absent AbsentFields
+		same   bool
+	}{
+		{"{a:1, b:null} {a:1}", untyped, true},
+		{"{a:1, b:null.null} {a:1}", untyped, true},
+		{"{a:1, b:null.string} {a:1}", untyped, false},
+		{"{a:1, b:null.string} {a:1}", all, true},
+		{"{a:1, b:null.list} {a:1}", all, true},
+		{"{a:1, b:null.struct, c:{d:null}} {a:1, c:{}}", all, true},
+		{"{a:1, b:x::null} {a:1}", all, false},
+		{"[1, null] [1]", all, false},
+		{"null {}", all, false},
+		{"{a:1, b:[]} {a:1}", all, false},
+		{"{a:1, b:[]} {a:1}", empty, true},
+		{"{a:1, b:(), c:{}} {a:1}", empty, true},
+		{"{a:1, b:[null]} {a:1}", empty, false},
+		{"{a:1, b:x::[]} {a:1}", empty, false},
+		{"{a:1, b:{c:{d:[]}}} {a:1}", empty, true},
+		{"{a:1, b:{c:null}} {a:1}", allAndEmpty, true},
+		{"{a:1, b:{c:null}} {a:1}", empty, false},
+		{"{a:1, b:{c:null}} {a:1, b:{}}", all, true},
+		{"{a:[[]]} {a:[]}", empty, false},
+		{"[{}] [{a:[]}]", empty, true},
+		{"{} {}", empty, true},
+		{"{a:null} {a:null.int}", AbsentFields{}, false},
+	}
+
+	for _, tc := range testCases {
+		assertSameHashes(t, tc.same, tc.text, WithAbsentFields(tc.absent))
+	}
+
+	// Annotations that aren't hashed don't keep a value from being absent.
+	assertSameHashes(t, true, "{a:1, b:x::null, c:y::[]} {a:1}", WithAbsentFields(allAndEmpty), WithoutAnnotations())
+
+	// Neither do values that are excluded.
+	assertSameHashes(t, true, "{a:1, b:{updated_at:2020T}} {a:1}", WithAbsentFields(empty),
+		WithExcludedFields("updated_at"))
+}
+
+func TestWithFieldAliases(t *testing.T) {
+	aliases := map[string]string{"customerId": "customer_id", "old": "new"}
+
+	testCases := []struct {
+		text string
+		same bool
+	}{
+		{"{customerId:1, a:2} {customer_id:1, a:2}", true},
+		{"{x:{customerId:1}, y:[{old:(2)}]} {x:{customer_id:1}, y:[{new:(2)}]}", true},
+		{"{customerId:{old:1}} {customer_id:{new:1}}", true},
+		{"{customerId:1} {customer_id:2}", false},
+		{"{customerid:1} {customer_id:1}", false},
+		{"[customerId] [customer_id]", false},
+	}
+
+	for _, tc := range testCases {
+		assertSameHashes(t, tc.same, tc.text, WithFieldAliases(aliases))
+	}
+
+	assertSameHashes(t, false, "{customerId:1} {customer_id:1}")
+
+	// Later aliases are added to earlier ones.
+	assertSameHashes(t, true, "{a:1, b:2} {x:1, y:2}",
+		WithFieldAliases(map[string]string{"a": "x"}), WithFieldAliases(map[string]string{"b": "y"}))
+}
+
+func TestWithFieldNameFunc(t *testing.T) {
+	type call struct{ path, name string }
+	var calls []call
+	record := func(path, name string) string {
+		calls = append(calls, call{path, name})
+		return name
+	}
+
+	ionHashReader, err := NewHashReader(ion.NewReaderString("{a:{b:1}, c:[{d:2}], 'e f':{g:3}}"), NewCryptoHasherProvider(SHA256),
+		WithFieldNameFunc(record))
+	require.NoError(t, err, "Expected NewHashReader() to successfully create a HashReader")
+	for ionHashReader.Next() {
+	}
+	require.NoError(t, ionHashReader.Err())
+
+	assert.ElementsMatch(t, []call{{"", "a"}, {"a", "b"}, {"", "c"}, {"c[0]", "d"}, {"", "e f"}, {"'e f'", "g"}}, calls)
+
+	orderIDs := func(path, name string) string {
+		if strings.HasPrefix(path, "orders[") && name == "orderId" {
+			return "id"
+		}
+		return name
+	}
+
+	assertSameHashes(t, true, "{orders:[{orderId:1}]} {orders:[{id:1}]}", WithFieldNameFunc(orderIDs))
+	assertSameHashes(t, false, "{orderId:1} {id:1}", WithFieldNameFunc(orderIDs))
+
+	// Functions are applied after aliases and in order.
+	assertSameHashes(t, true, "{a:1} {C:1}", WithFieldAliases(map[string]string{"a": "b"}),
+		WithFieldNameFunc(func(_, name string) string { return name + "c" }),
+		WithFieldNameFunc(func(_, name string) string { return strings.ToUpper(name[1:]) }))
+}
+
+func TestFieldAliasesKeepPaths(t *testing.T) {
+	ionHashReader, err := NewHashReader(ion.NewReaderString("{customerId:[[1]]}"), NewCryptoHasherProvider(SHA256),
+		WithFieldAliases(map[string]string{"customerId": "customer_id"}), WithMaxDepth(2))
+	require.NoError(t, err, "Expected NewHashReader() to successfully create a HashReader")
+	for ionHashReader.Next() {
+	}
+
+	var hashError *HashError
+	require.True(t, errors.As(ionHashReader.Err(), &hashError), "Expected a HashError")
+	assert.Equal(t, "customerId[0]", hashError.Path)
+
+	// Excluded paths use the fields' names rather than their aliases.
+	assertSameHashes(t, true, "{customerId:1, a:2} {a:2}", WithFieldAliases(map[string]string{"customerId": "customer_id"}),
+		WithExcludedPaths("customerId"))
+}
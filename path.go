@@ -0,0 +1,173 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+import (
+	"strconv"
+	"strings"
+)
+
+// A pathPattern matches the paths of values within their top-level values. Patterns are written
+// like the paths of HashErrors, e.g. orders[12].items, with field names that are not identifiers
+// quoted, e.g. 'first name'. A * in place of a field name matches any field name, and [*] matches
+// any index, e.g. orders[*].*.sku. The empty pattern matches top-level values.
+type pathPattern []patternElement
+
+type patternElement struct {
+	isStruct bool
+
+	// field is the field name to match, if any is false.
+	field string
+
+	// index is the index to match, if any is false.
+	index int
+
+	any bool
+}
+
+// parsePathPatterns parses patterns, returning an InvalidArgumentError for the first that is invalid.
+func parsePathPatterns(patterns []string) ([]pathPattern, error) {
+	parsed := make([]pathPattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		p, ok := parsePathPattern(pattern)
+		if !ok {
+			return nil, &InvalidArgumentError{"path", pattern}
+		}
+
+		parsed = append(parsed, p)
+	}
+
+	return parsed, nil
+}
+
+// parsePathPattern parses a pattern, returning false if it is invalid.
+func parsePathPattern(pattern string) (pathPattern, bool) {
+	var parsed pathPattern
+
+	for i := 0; i < len(pattern); {
+		if pattern[i] == '[' {
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				return nil, false
+			}
+
+			element := patternElement{}
+			switch text := pattern[i+1 : i+end]; text {
+			case "*":
+				element.any = true
+			default:
+				index, err := strconv.Atoi(text)
+				if err != nil || index < 0 {
+					return nil, false
+				}
+
+				element.index = index
+			}
+
+			parsed = append(parsed, element)
+			i += end + 1
+			continue
+		}
+
+		// A field name, which follows a dot unless it begins the pattern.
+		if len(parsed) > 0 {
+			if pattern[i] != '.' {
+				return nil, false
+			}
+			i++
+		}
+
+		element := patternElement{isStruct: true}
+		switch {
+		case strings.HasPrefix(pattern[i:], "*"):
+			element.any = true
+			i++
+		case strings.HasPrefix(pattern[i:], "'"):
+			var field strings.Builder
+			for i++; i < len(pattern) && pattern[i] != '\''; i++ {
+				if pattern[i] == '\\' {
+					i++
+					if i == len(pattern) {
+						return nil, false
+					}
+				}
+				field.WriteByte(pattern[i])
+			}
+
+			if i == len(pattern) {
+				return nil, false
+			}
+
+			element.field = field.String()
+			i++
+		default:
+			end := strings.IndexAny(pattern[i:], ".[")
+			if end < 0 {
+				end = len(pattern) - i
+			}
+
+			element.field = pattern[i : i+end]
+			if !identifierPattern.MatchString(element.field) {
+				return nil, false
+			}
+
+			i += end
+		}
+
+		parsed = append(parsed, element)
+	}
+
+	return parsed, true
+}
+
+// matches reports whether the pattern matches the given path.
+func (p pathPattern) matches(path []pathElement) bool {
+	if len(p) != len(path) {
+		return false
+	}
+
+	for i, element := range path {
+		pe := p[i]
+		if pe.isStruct != element.isStruct {
+			return false
+		}
+
+		switch {
+		case pe.any:
+		case element.isStruct:
+			if element.fieldName == nil || element.fieldName.Text == nil || *element.fieldName.Text != pe.field {
+				return false
+			}
+		default:
+			if element.index != pe.index {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// matchesAny reports whether any of the patterns matches the given path.
+func matchesAny(patterns []pathPattern, path []pathElement) bool {
+	for _, pattern := range patterns {
+		if pattern.matches(path) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+import (
+	"testing"
+
+	"github.com/amzn/ion-go/ion"
+	"github.com/stretchr/testify/assert"
+)
+
+// testPath returns a path of struct fields for strings and list elements for ints.
+func testPath(elements ...interface{}) []pathElement {
+	var path []pathElement
+	for _, element := range elements {
+		switch e := element.(type) {
+		case string:
+			text := e
+			path = append(path, pathElement{isStruct: true, fieldName: &ion.SymbolToken{Text: &text}})
+		case int:
+			path = append(path, pathElement{index: e})
+		}
+	}
+
+	return path
+}
+
+func TestParsePathPattern(t *testing.T) {
+	testCases := []struct {
+		pattern  string
+		expected pathPattern
+	}{
+		{"", nil},
+		{"a", pathPattern{{isStruct: true, field: "a"}}},
+		{"a.b_2.$c", pathPattern{{isStruct: true, field: "a"}, {isStruct: true, field: "b_2"}, {isStruct: true, field: "$c"}}},
+		{"[3]", pathPattern{{index: 3}}},
+		{"a[0][*]", pathPattern{{isStruct: true, field: "a"}, {index: 0}, {any: true}}},
+		{"*.b", pathPattern{{isStruct: true, any: true}, {isStruct: true, field: "b"}}},
+		{"'first name'.'it\\'s'", pathPattern{{isStruct: true, field: "first name"}, {isStruct: true, field: "it's"}}},
+		{"[1].a", pathPattern{{index: 1}, {isStruct: true, field: "a"}}},
+	}
+
+	for _, tc := range testCases {
+		pattern, ok := parsePathPattern(tc.pattern)
+		assert.True(t, ok, "Expected %q to parse", tc.pattern)
+		assert.Equal(t, tc.expected, pattern, "Pattern %q did not parse as expected", tc.pattern)
+	}
+
+	for _, invalid := range []string{".a", "a.", "a..b", "a[", "a[]", "a[-1]", "a[x]", "a b", "'a", "a'b'", "[0]a"} {
+		_, ok := parsePathPattern(invalid)
+		assert.False(t, ok, "Expected %q not to parse", invalid)
+	}
+}
+
+func TestPathPatternMatches(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		path    []pathElement
+		matches bool
+	}{
+		{"", testPath(), true},
+		{"", testPath("a"), false},
+		{"a", testPath("a"), true},
+		{"a", testPath("b"), false},
+		{"a", testPath(0), false},
+		{"a", testPath("a", "b"), false},
+		{"a.b", testPath("a", "b"), true},
+		{"*", testPath("a"), true},
+		{"*", testPath(0), false},
+		{"[0]", testPath(0), true},
+		{"[0]", testPath(1), false},
+		{"[*]", testPath(7), true},
+		{"[*]", testPath("a"), false},
+		{"orders[*].items[*].sku", testPath("orders", 3, "items", 0, "sku"), true},
+		{"orders[*].items[1].sku", testPath("orders", 3, "items", 0, "sku"), false},
+		{"a", []pathElement{{isStruct: true}}, false},
+		{"a", []pathElement{{isStruct: true, fieldName: &ion.SymbolToken{LocalSID: 10}}}, false},
+		{"*", []pathElement{{isStruct: true, fieldName: &ion.SymbolToken{LocalSID: 10}}}, true},
+	}
+
+	for _, tc := range testCases {
+		pattern, ok := parsePathPattern(tc.pattern)
+		assert.True(t, ok, "Expected %q to parse", tc.pattern)
+		assert.Equal(t, tc.matches, pattern.matches(tc.path), "Expected %q to match: %v", tc.pattern, tc.matches)
+	}
+}
@@ -0,0 +1,223 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+import (
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/amzn/ion-go/ion"
+)
+
+// A HashProfile selects how values are hashed. The zero HashProfile hashes values exactly as
+// the Ion Hash specification defines, under which values that are equivalent but written
+// differently, e.g. 1.0 and 1.00, hash differently. Other profiles normalize such values so that
+// they hash the same, and so their digests are not those of the specification.
+type HashProfile struct {
+	// NormalizeDecimals makes decimals with the same numeric value hash the same, ignoring their
+	// trailing zeros and the sign of zero, e.g. 1.0, 1.00 and 1d0, or 0.0 and -0d0.
+	NormalizeDecimals bool
+
+	// NormalizeTimestamps makes timestamps of the same instant hash the same, ignoring their offset
+	// and precision, e.g. 2020-01-01T00:00Z, 2020-01-01T01:00+01:00 and 2020-01-01T. A timestamp
+	// with an unknown offset is taken to be in UTC.
+	NormalizeTimestamps bool
+
+	// TimestampUnit, if positive, truncates the instants of normalized timestamps to a multiple of it,
+	// e.g. time.Millisecond, so that timestamps within the same unit hash the same. It has no effect
+	// unless NormalizeTimestamps is set.
+	TimestampUnit time.Duration
+}
+
+// SemanticProfile hashes decimals and timestamps by their values rather than how they are written.
+var SemanticProfile = HashProfile{NormalizeDecimals: true, NormalizeTimestamps: true}
+
+// normalizes reports whether the profile changes how values of the given type are hashed.
+func (p *HashProfile) normalizes(ionType ion.Type) bool {
+	switch ionType {
+	case ion.DecimalType:
+		return p.NormalizeDecimals
+	case ion.TimestampType:
+		return p.NormalizeTimestamps
+	}
+
+	return false
+}
+
+// normalizedValue is a hashValue whose scalar value is normalized by a HashProfile. It is reused
+// between values so that normalizing a value doesn't allocate once scratch has grown to fit it.
+type normalizedValue struct {
+	hashValue
+	profile *HashProfile
+	encoded encodedScalar
+	scratch []byte
+}
+
+func (nv *normalizedValue) value() (interface{}, error) {
+	val, err := nv.hashValue.value()
+	if err != nil {
+		return nil, err
+	}
+
+	tq, representation, err := appendScalar(nv.scratch[:0], nv.Type(), val, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// The normalized representation is appended after the original one in scratch.
+	start := len(representation)
+	switch nv.Type() {
+	case ion.DecimalType:
+		nv.scratch = normalizeDecimal(representation, representation)
+	case ion.TimestampType:
+		nv.scratch = normalizeTimestamp(representation, representation, nv.profile.TimestampUnit)
+	}
+
+	nv.encoded.tq = tq
+	nv.encoded.representation = nv.scratch[start:]
+	return &nv.encoded, nil
+}
+
+// normalizeDecimal appends the representation of the decimal with the given representation
+// after removing the trailing zeros of its coefficient. All zeros are normalized to 0d0.
+func normalizeDecimal(b []byte, representation []byte) []byte {
+	if len(representation) == 0 {
+		return b
+	}
+
+	exponent, _, rest := decodeVarInt(representation)
+	coefficient := decodeSignedInt(rest)
+	if coefficient.Sign() == 0 {
+		return b
+	}
+
+	ten := big.NewInt(10)
+	quotient, remainder := new(big.Int), new(big.Int)
+	for exponent < math.MaxInt32 {
+		quotient.QuoRem(coefficient, ten, remainder)
+		if remainder.Sign() != 0 {
+			break
+		}
+
+		coefficient, quotient = quotient, coefficient
+		exponent++
+	}
+
+	return appendDecimal(b, ion.NewDecimal(coefficient, int32(exponent), false))
+}
+
+// normalizeTimestamp appends the representation of the instant of the timestamp with the given
+// representation, in UTC and at second or fractional second precision, after truncating it to unit.
+func normalizeTimestamp(b []byte, representation []byte, unit time.Duration) []byte {
+	// Skip the offset; the components that follow it are in UTC.
+	_, _, rest := decodeVarInt(representation)
+
+	components := [6]int{1, 1, 1, 0, 0, 0}
+	for i := 0; i < len(components) && len(rest) > 0; i++ {
+		var val uint64
+		val, rest = decodeVarUint(rest)
+		components[i] = int(val)
+	}
+
+	nanoseconds := 0
+	if len(rest) > 0 {
+		exponent, _, coefficient := decodeVarInt(rest)
+		fraction := decodeSignedInt(coefficient)
+
+		if exponent >= -9 {
+			fraction.Mul(fraction, new(big.Int).Exp(big.NewInt(10), big.NewInt(9+exponent), nil))
+		} else {
+			fraction.Quo(fraction, new(big.Int).Exp(big.NewInt(10), big.NewInt(-9-exponent), nil))
+		}
+
+		nanoseconds = int(fraction.Int64())
+	}
+
+	instant := time.Date(components[0], time.Month(components[1]), components[2],
+		components[3], components[4], components[5], nanoseconds, time.UTC)
+	if unit > 0 {
+		instant = instant.Truncate(unit)
+	}
+
+	fraction, fractionDigits := instant.Nanosecond(), uint8(9)
+	if fraction == 0 {
+		return appendTimestampParts(b, false, 0, instant, ion.TimestampPrecisionSecond, 0, 0)
+	}
+
+	for fraction%10 == 0 {
+		fraction /= 10
+		fractionDigits--
+	}
+
+	return appendTimestampParts(b, false, 0, instant, ion.TimestampPrecisionNanosecond, fractionDigits, fraction)
+}
+
+// decodeVarUint decodes a VarUInt from the start of b and returns it with the rest of b.
+func decodeVarUint(b []byte) (uint64, []byte) {
+	var val uint64
+	for i, c := range b {
+		val = val<<7 | uint64(c&0x7F)
+		if c&0x80 != 0 {
+			return val, b[i+1:]
+		}
+	}
+
+	return val, nil
+}
+
+// decodeVarInt decodes a VarInt from the start of b and returns it, whether it is negative,
+// and the rest of b.
+func decodeVarInt(b []byte) (int64, bool, []byte) {
+	if len(b) == 0 {
+		return 0, false, nil
+	}
+
+	negative := b[0]&0x40 != 0
+	val := int64(b[0] & 0x3F)
+	rest := b[1:]
+	if b[0]&0x80 == 0 {
+		for i, c := range rest {
+			val = val<<7 | int64(c&0x7F)
+			if c&0x80 != 0 {
+				rest = rest[i+1:]
+				break
+			}
+		}
+	}
+
+	if negative {
+		val = -val
+	}
+
+	return val, negative, rest
+}
+
+// decodeSignedInt decodes a big-endian signed Int, whose sign is the high bit of its first byte.
+func decodeSignedInt(b []byte) *big.Int {
+	val := new(big.Int)
+	if len(b) == 0 {
+		return val
+	}
+
+	magnitude := append([]byte{b[0] & 0x7F}, b[1:]...)
+	val.SetBytes(magnitude)
+	if b[0]&0x80 != 0 {
+		val.Neg(val)
+	}
+
+	return val
+}
@@ -0,0 +1,164 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/amzn/ion-go/ion"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hashValues returns the digests of the top-level values of text as hashed by a HashReader,
+// a HashWriter and a BinaryHashReader, failing unless all three agree.
+func hashValues(t *testing.T, text string, opts ...Option) [][]byte {
+	readerSums := hashTextValues(t, text, opts...)
+
+	binarySums, err := HashBinary(toBinary(t, text), NewCryptoHasherProvider(SHA256), opts...)
+	require.NoError(t, err, "Something went wrong executing HashBinary()")
+	assert.Equal(t, readerSums, binarySums, "HashBinary sums did not match HashReader sums of %s", text)
+
+	return readerSums
+}
+
+// hashTextValues returns the digests of the top-level values of text as hashed by a HashReader
+// and a HashWriter, failing unless both agree.
+func hashTextValues(t *testing.T, text string, opts ...Option) [][]byte {
+	hasherProvider := NewCryptoHasherProvider(SHA256)
+
+	ionHashReader, err := NewHashReader(ion.NewReaderString(text), hasherProvider, opts...)
+	require.NoError(t, err, "Expected NewHashReader() to successfully create a HashReader")
+
+	var readerSums [][]byte
+	for ionHashReader.Next() {
+		sum, err := ionHashReader.Sum(nil)
+		require.NoError(t, err, "Something went wrong executing ionHashReader.Sum(nil)")
+		readerSums = append(readerSums, sum)
+	}
+	require.NoError(t, ionHashReader.Err(), "Something went wrong executing ionHashReader.Next()")
+
+	// A HashReader's sum is that of the value before the current one.
+	sum, err := ionHashReader.Sum(nil)
+	require.NoError(t, err, "Something went wrong executing ionHashReader.Sum(nil)")
+	readerSums = append(readerSums[1:], sum)
+
+	ionHashWriter, err := NewHashWriter(ion.NewBinaryWriter(&bytes.Buffer{}), hasherProvider, opts...)
+	require.NoError(t, err, "Expected NewHashWriter() to successfully create a HashWriter")
+
+	var writerSums [][]byte
+	reader := ion.NewReaderString(text)
+	for reader.Next() {
+		require.NoError(t, writeValue(reader, ionHashWriter))
+
+		sum, err := ionHashWriter.Sum(nil)
+		require.NoError(t, err, "Something went wrong executing ionHashWriter.Sum(nil)")
+		writerSums = append(writerSums, sum)
+	}
+	require.NoError(t, reader.Err())
+
+	assert.Equal(t, readerSums, writerSums, "HashWriter sums did not match HashReader sums of %s", text)
+	return readerSums
+}
+
+// assertAllEqual asserts whether every value in text hashes the same.
+func assertAllEqual(t *testing.T, expected bool, text string, opts ...Option) {
+	sums := hashValues(t, text, opts...)
+	require.True(t, len(sums) > 1)
+
+	allEqual := true
+	for _, sum := range sums[1:] {
+		allEqual = allEqual && bytes.Equal(sums[0], sum)
+	}
+
+	assert.Equal(t, expected, allEqual, "Expected values of %s to hash the same: %v", text, expected)
+}
+
+var equivalentDecimals = []string{
+	"1.0 1.00 1d0 1. 10d-1 0.001d3",
+	"0. 0.0 -0. -0.00 0d10 -0d-10",
+	"-12.5 -12.500 -125d-1",
+	"100. 1d2 10d1 100.000",
+	"123456789012345678901234567890. 123456789012345678901234567890.000",
+}
+
+var equivalentTimestamps = []string{
+	"2020-01-01T00:00Z 2020-01-01T01:00+01:00 2019-12-31T19:00-05:00 2020-01-01T00:00:00.000Z",
+	"2020-01-01T 2020-01T 2020T 2020-01-01T00:00-00:00 2020-01-01T00:00:00Z",
+	"2020-06-15T12:30:45.5Z 2020-06-15T14:30:45.500+02:00 2020-06-15T12:30:45.500000000000Z",
+}
+
+func TestSemanticProfile(t *testing.T) {
+	for _, text := range append(append([]string{}, equivalentDecimals...), equivalentTimestamps...) {
+		assertAllEqual(t, false, text)
+		assertAllEqual(t, true, text, WithProfile(SemanticProfile))
+	}
+}
+
+func TestSemanticProfileDistinguishesValues(t *testing.T) {
+	texts := []string{
+		"1.0 1.01 10. 0.1 -1.0 0.",
+		"2020-01-01T00:00Z 2020-01-01T00:01Z 2020-01-01T00:00:00.001Z 2020-01-01T00:00:00.000000001Z",
+		"2020-01-01T00:00Z 2020-01-01T00:00+01:00",
+	}
+
+	for _, text := range texts {
+		sums := hashValues(t, text, WithProfile(SemanticProfile))
+		for i := range sums {
+			for j := i + 1; j < len(sums); j++ {
+				assert.NotEqual(t, sums[i], sums[j], "Expected values %d and %d of %s to hash differently", i, j, text)
+			}
+		}
+	}
+}
+
+func TestProfileOnlyNormalizesSelectedTypes(t *testing.T) {
+	decimals := HashProfile{NormalizeDecimals: true}
+	assertAllEqual(t, true, equivalentDecimals[0], WithProfile(decimals))
+	assertAllEqual(t, false, equivalentTimestamps[0], WithProfile(decimals))
+
+	timestamps := HashProfile{NormalizeTimestamps: true}
+	assertAllEqual(t, false, equivalentDecimals[0], WithProfile(timestamps))
+	assertAllEqual(t, true, equivalentTimestamps[0], WithProfile(timestamps))
+
+	// Other types and nulls are hashed as the specification defines.
+	assert.Equal(t, hashValues(t, "1 1e0 abc null.decimal null.timestamp [1.0] {a:2020T}"),
+		hashValues(t, "1 1e0 abc null.decimal null.timestamp [1.0] {a:2020T}",
+			WithProfile(HashProfile{TimestampUnit: time.Second})))
+}
+
+func TestProfileTimestampUnit(t *testing.T) {
+	text := "2020-01-01T00:00:00.999Z 2020-01-01T00:00:00Z 2020-01-01T01:00:00.5+01:00"
+	assertAllEqual(t, false, text, WithProfile(SemanticProfile))
+
+	profile := SemanticProfile
+	profile.TimestampUnit = time.Second
+	assertAllEqual(t, true, text, WithProfile(profile))
+
+	profile.TimestampUnit = 24 * time.Hour
+	assertAllEqual(t, true, "2020-01-01T23:59:59.999Z 2020-01-01T 2020-01-02T05:00+06:00", WithProfile(profile))
+}
+
+func TestProfileInContainers(t *testing.T) {
+	assertAllEqual(t, true, "{a:1.0, b:[2020T]} {b:[2020-01-01T00:00Z], a:1.00}", WithProfile(SemanticProfile))
+}
+
+func TestDefaultProfileIsSpecConformant(t *testing.T) {
+	text := "1.0 1.00 -0. 2020-01-01T00:00Z 2020-01-01T01:00+01:00 2020T"
+	assert.Equal(t, hashValues(t, text), hashValues(t, text, WithProfile(HashProfile{})))
+}
@@ -0,0 +1,745 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/amzn/ion-go/ion"
+)
+
+// A Proof proves that a top-level value with a given digest has a struct field at a path, without revealing
+// the value's other fields. A struct's digest is computed from the sorted digests of its fields, so
+// the field's digest, the digests of the other fields of each struct that it is in, and the bytes
+// hashed around those structs are enough to compute the digest of the top-level value.
+//
+// Proofs are of digests computed without options. The values of lists and sexps aren't hashed
+// separately, so a proof reveals the other values of any list or sexp that the field is in.
+//
+// As Ion, a proof is a struct, e.g.
+//
+//	ion_hash_proof::{
+//	  path:"order.status",
+//	  value:"paid",
+//	  levels:[{siblings:[{{...}}, {{...}}], prefix:{{}}, suffix:{{}}}]
+//	}
+type Proof struct {
+	// Path is the path of the field proved, written as for NewProof, and Value is its value encoded
+	// as Ion binary.
+	Path  string
+	Value []byte
+
+	// Levels are the structs that the field is in, from the innermost to the outermost.
+	Levels []ProofLevel
+}
+
+// A ProofLevel is a struct that a proved field is in.
+type ProofLevel struct {
+	// Siblings are the digests of the struct's other fields.
+	Siblings [][]byte
+
+	// Prefix and Suffix are the bytes hashed before and after the struct to compute the digest of
+	// the field of the next struct that it is in, or of the top-level value: the field's name, the
+	// struct's annotations, and the lists and sexps that the struct is in, with their other values.
+	Prefix []byte
+	Suffix []byte
+}
+
+// proofAnnotation annotates the Ion serialization of a Proof.
+const proofAnnotation = "ion_hash_proof"
+
+// NewProof reads the next top-level value of reader and returns a proof that it has the field at
+// path, which is written like the paths of HashErrors, e.g. order.items[0].status. If a struct has
+// more than one field with a name in path, the first is proved. It returns a FieldNotFoundError if
+// the value has no field at path.
+func NewProof(reader ion.Reader, hasherProvider IonHasherProvider, path string) (*Proof, error) {
+	pattern, ok := parseProofPath(path)
+	if !ok {
+		return nil, &InvalidArgumentError{"path", path}
+	}
+
+	root, err := readTopLevelNode(reader)
+	if err != nil {
+		return nil, err
+	}
+	if root == nil {
+		return nil, &InvalidOperationError{"ionhash", "NewProof", "There is no value to prove a field of"}
+	}
+
+	// chain holds the top-level value and the values in it down to the field.
+	chain := []*node{root}
+	for _, element := range pattern {
+		child := findChild(chain[len(chain)-1], element)
+		if child == nil {
+			return nil, &FieldNotFoundError{path}
+		}
+
+		chain = append(chain, child)
+	}
+
+	field := chain[len(chain)-1]
+	if field.fieldName.Text == nil {
+		return nil, &UnknownSymbolError{field.fieldName.LocalSID}
+	}
+
+	value, err := encodeNode(field)
+	if err != nil {
+		return nil, err
+	}
+
+	proof := &Proof{Path: path, Value: value}
+
+	// Each struct is hashed along with the lists and sexps that it is in, up to the field or
+	// top-level value whose digest is computed from them.
+	for structIndex := len(chain) - 2; structIndex >= 0; {
+		top := structIndex
+		for top > 0 && chain[top-1].ionType != ion.StructType {
+			top--
+		}
+
+		level, err := newProofLevel(hasherProvider, chain[top:], structIndex-top)
+		if err != nil {
+			return nil, err
+		}
+
+		proof.Levels = append(proof.Levels, level)
+		structIndex = top - 1
+	}
+
+	return proof, nil
+}
+
+// parseProofPath parses the path of a proved field, returning false unless it is a valid path that
+// ends in a field name and has no wildcards.
+func parseProofPath(path string) (pathPattern, bool) {
+	pattern, ok := parsePathPattern(path)
+	if !ok || len(pattern) == 0 || !pattern[len(pattern)-1].isStruct {
+		return nil, false
+	}
+
+	for _, element := range pattern {
+		if element.any {
+			return nil, false
+		}
+	}
+
+	return pattern, true
+}
+
+// findChild returns the first value in n at the given element of a path, or nil if there is none.
+func findChild(n *node, element patternElement) *node {
+	if n.isNull || element.isStruct != (n.ionType == ion.StructType) {
+		return nil
+	}
+
+	if !element.isStruct {
+		if element.index >= len(n.children) {
+			return nil
+		}
+
+		return n.children[element.index]
+	}
+
+	for _, child := range n.children {
+		if child.fieldName.Text != nil && *child.fieldName.Text == element.field {
+			return child
+		}
+	}
+
+	return nil
+}
+
+// encodeNode returns n, without its field name, as Ion binary.
+func encodeNode(n *node) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := ion.NewBinaryWriter(&buf)
+	err := writeNode(writer, n)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writer.Finish()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// newProofLevel returns the ProofLevel of the struct chain[structIndex], where chain holds a field or
+// top-level value and the lists and sexps in it down to the struct, followed by the proved field
+// or the field of the struct that it is in.
+func newProofLevel(hasherProvider IonHasherProvider, chain []*node, structIndex int) (ProofLevel, error) {
+	h, err := newHasher(hasherProvider, newOptions(nil))
+	if err != nil {
+		return ProofLevel{}, err
+	}
+
+	// The bytes hashed for the top of the chain are recorded rather than hashed.
+	recorder := &recordingHasher{}
+	h.currentHasher.(*scalarSerializer).hashFunction = recorder
+
+	if chain[0].inStruct {
+		// The field's name is hashed before its value, as though it were within its struct.
+		bs := &baseSerializer{hashFunction: recorder, depth: 1, limiter: &limiter{}}
+		err = bs.handleFieldName(chain[0])
+		if err != nil {
+			return ProofLevel{}, err
+		}
+	}
+
+	prover := &prover{hasher: h, recorder: recorder, chain: chain, structIndex: structIndex}
+	err = prover.hash(chain[0], 0)
+	if err != nil {
+		return ProofLevel{}, err
+	}
+
+	return ProofLevel{
+		Siblings: prover.siblings,
+		Prefix:   recorder.bytes[:prover.structStart],
+		Suffix:   recorder.bytes[prover.structEnd:],
+	}, nil
+}
+
+// prover hashes the values of a chain, noting where the bytes of its struct begin and end, and the
+// digests of its fields besides the next value of the chain.
+type prover struct {
+	hasher      *hasher
+	recorder    *recordingHasher
+	chain       []*node
+	structIndex int
+
+	siblings               [][]byte
+	structStart, structEnd int
+}
+
+// hash hashes n, which is chain[index].
+func (p *prover) hash(n *node, index int) error {
+	err := p.hasher.stepIn(n)
+	if err != nil {
+		return err
+	}
+
+	// The begin marker and type qualifier of the container have just been written.
+	start := len(p.recorder.bytes) - 2
+
+	var fieldIndex int
+	for _, child := range n.children {
+		switch {
+		case child != p.chain[index+1]:
+			err = hashNode(p.hasher, child)
+		case index == p.structIndex:
+			fieldIndex = len(p.hasher.currentHasher.(*structSerializer).fieldHashes)
+			err = hashNode(p.hasher, child)
+		default:
+			err = p.hash(child, index+1)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if index == p.structIndex {
+		// The digests of the fields are sorted when the struct ends, so the siblings are taken first.
+		fieldHashes := p.hasher.currentHasher.(*structSerializer).fieldHashes
+		p.siblings = append(append([][]byte(nil), fieldHashes[:fieldIndex]...), fieldHashes[fieldIndex+1:]...)
+	}
+
+	err = p.hasher.stepOut()
+	if err != nil {
+		return err
+	}
+
+	if index == p.structIndex {
+		p.structStart = start
+		p.structEnd = len(p.recorder.bytes)
+		if len(n.annotations) > 0 {
+			// The end marker of the annotation wrapper follows that of the struct.
+			p.structEnd--
+		}
+	}
+
+	return nil
+}
+
+// recordingHasher is an IonHasher that records the bytes written to it rather than hashing them.
+type recordingHasher struct {
+	bytes []byte
+}
+
+func (rh *recordingHasher) Write(bytes []byte) (int, error) {
+	rh.bytes = append(rh.bytes, bytes...)
+	return len(bytes), nil
+}
+
+func (rh *recordingHasher) Sum(b []byte) []byte {
+	return append(b, rh.bytes...)
+}
+
+func (rh *recordingHasher) Reset() {
+	rh.bytes = nil
+}
+
+// Verify reports whether proof proves that the top-level value whose digest, computed with
+// hasherProvider, is rootDigest has the proof's field at the proof's Path, which the caller should
+// check is the path it expects. It returns an InvalidProofError if the proof is malformed, including
+// if its levels aren't those of the structs on its path.
+func Verify(proof *Proof, rootDigest []byte, hasherProvider IonHasherProvider) (bool, error) {
+	pattern, ok := parseProofPath(proof.Path)
+	if !ok {
+		return false, &InvalidProofError{fmt.Sprintf("%q is not the path of a field", proof.Path)}
+	}
+
+	// fields holds the positions in pattern of the field names, one for each struct on the path.
+	var fields []int
+	for i, element := range pattern {
+		if element.isStruct {
+			fields = append(fields, i)
+		}
+	}
+
+	if len(proof.Levels) != len(fields) {
+		return false, &InvalidProofError{
+			fmt.Sprintf("a proof of %s has %d levels rather than %d", proof.Path, len(proof.Levels), len(fields))}
+	}
+
+	digest, err := proofFieldHash(proof.Value, pattern[len(pattern)-1].field, hasherProvider)
+	if err != nil {
+		return false, err
+	}
+
+	for i, level := range proof.Levels {
+		// The level's struct is reached from the field before it on the path, or from the top-level
+		// value, through the lists and sexps at the indexes in between.
+		var fieldName *string
+		start, end := 0, fields[len(fields)-1-i]
+		if i < len(fields)-1 {
+			start = fields[len(fields)-2-i] + 1
+			fieldName = &pattern[start-1].field
+		}
+
+		err = checkFraming(level, fieldName, pattern[start:end])
+		if err != nil {
+			return false, err
+		}
+
+		digests := append([][]byte{digest}, level.Siblings...)
+		for _, sibling := range level.Siblings {
+			if len(sibling) != len(digest) {
+				return false, &InvalidProofError{fmt.Sprintf("a sibling digest has %d bytes rather than %d", len(sibling), len(digest))}
+			}
+		}
+		sort.Sort(sortableBytes(digests))
+
+		hashFunction, err := hasherProvider.NewHasher()
+		if err != nil {
+			return false, err
+		}
+
+		bs := &baseSerializer{hashFunction: hashFunction, limiter: &limiter{}}
+		err = bs.write(level.Prefix)
+		if err == nil {
+			err = bs.beginMarker()
+		}
+		if err == nil {
+			err = bs.writeByte(byte(ion.StructType) << 4)
+		}
+		for _, d := range digests {
+			if err == nil {
+				err = bs.writeEscaped(d)
+			}
+		}
+		if err == nil {
+			err = bs.endMarker()
+		}
+		if err == nil {
+			err = bs.write(level.Suffix)
+		}
+		if err != nil {
+			return false, err
+		}
+
+		digest = bs.sum(nil)
+	}
+
+	return bytes.Equal(digest, rootDigest), nil
+}
+
+// proofFieldHash returns the digest of the field with the given name and value, encoded as Ion binary.
+func proofFieldHash(value []byte, name string, hasherProvider IonHasherProvider) ([]byte, error) {
+	field, err := readTopLevelNode(ion.NewReaderBytes(value))
+	if err != nil {
+		return nil, err
+	}
+	if field == nil {
+		return nil, &InvalidProofError{"the field has no value"}
+	}
+
+	fieldName := ion.NewSymbolTokenFromString(name)
+	field.inStruct = true
+	field.fieldName = &fieldName
+
+	h, err := newHasher(hasherProvider, newOptions(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.stepIn(&node{ionType: ion.StructType})
+	if err != nil {
+		return nil, err
+	}
+
+	err = hashNode(h, field)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.currentHasher.(*structSerializer).fieldHashes[0], nil
+}
+
+// checkFraming returns an InvalidProofError unless the prefix and suffix of level are the bytes
+// hashed around a struct that is reached from a field with the given name, or from a top-level value
+// if it is nil, through lists and sexps at the given indexes. The prefix must then be the field's
+// name, followed by each list or sexp with as many values as its index before the next, and the
+// struct's annotations, and the suffix must end each of them, with any values after the next.
+func checkFraming(level ProofLevel, fieldName *string, indexes pathPattern) error {
+	invalid := &InvalidProofError{"a level's prefix and suffix are not those of a struct at its path"}
+
+	prefix := level.Prefix
+	if fieldName != nil {
+		recorder := &recordingHasher{}
+		bs := &baseSerializer{hashFunction: recorder, depth: 1, limiter: &limiter{}}
+		name := ion.NewSymbolTokenFromString(*fieldName)
+		err := bs.handleFieldName(&node{inStruct: true, fieldName: &name})
+		if err != nil {
+			return err
+		}
+
+		if !bytes.HasPrefix(prefix, recorder.bytes) {
+			return invalid
+		}
+		prefix = prefix[len(recorder.bytes):]
+	}
+
+	// annotated notes which of the lists and sexps, followed by the struct, have annotations.
+	annotated := make([]bool, len(indexes)+1)
+	ok := true
+	for i, element := range indexes {
+		prefix, annotated[i], ok = skipAnnotations(prefix)
+		if !ok || len(prefix) < 2 || prefix[0] != beginMarkerByte ||
+			(prefix[1] != byte(ion.ListType)<<4 && prefix[1] != byte(ion.SexpType)<<4) {
+			return invalid
+		}
+
+		prefix = prefix[2:]
+		for j := 0; ok && j < element.index; j++ {
+			prefix, ok = skipValue(prefix)
+		}
+		if !ok {
+			return invalid
+		}
+	}
+
+	prefix, annotated[len(indexes)], ok = skipAnnotations(prefix)
+	if !ok || len(prefix) != 0 {
+		return invalid
+	}
+
+	suffix := level.Suffix
+	for i := len(indexes); i >= 0; i-- {
+		if i < len(indexes) {
+			for ok && len(suffix) > 0 && suffix[0] == beginMarkerByte {
+				suffix, ok = skipValue(suffix)
+			}
+			if !ok || len(suffix) == 0 || suffix[0] != endMarkerByte {
+				return invalid
+			}
+			suffix = suffix[1:]
+		}
+
+		if annotated[i] {
+			if len(suffix) == 0 || suffix[0] != endMarkerByte {
+				return invalid
+			}
+			suffix = suffix[1:]
+		}
+	}
+
+	if len(suffix) != 0 {
+		return invalid
+	}
+
+	return nil
+}
+
+// skipAnnotations returns data after the beginning of an annotation wrapper and its annotations,
+// if it begins with one, and whether it did. It returns false for ok if an annotation is incomplete.
+func skipAnnotations(data []byte) (rest []byte, annotated bool, ok bool) {
+	if len(data) < 2 || data[0] != beginMarkerByte || data[1] != tqValue {
+		return data, false, true
+	}
+
+	rest = data[2:]
+	for ok = true; ok && len(rest) > 1 && rest[0] == beginMarkerByte && rest[1]>>4 == byte(ion.SymbolType); {
+		rest, ok = skipValue(rest)
+	}
+
+	return rest, true, ok
+}
+
+// skipValue returns data after the serialization of the value that it begins with, or false if it
+// doesn't begin with a complete one.
+func skipValue(data []byte) ([]byte, bool) {
+	if len(data) == 0 || data[0] != beginMarkerByte {
+		return nil, false
+	}
+
+	depth := 0
+	escaped := false
+	for i, b := range data {
+		switch {
+		case escaped:
+			escaped = false
+		case b == escapeByte:
+			escaped = true
+		case b == beginMarkerByte:
+			depth++
+		case b == endMarkerByte:
+			depth--
+			if depth == 0 {
+				return data[i+1:], true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// Write writes the proof as Ion to writer.
+func (p *Proof) Write(writer ion.Writer) error {
+	value, err := readTopLevelNode(ion.NewReaderBytes(p.Value))
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		return &InvalidProofError{"the field has no value"}
+	}
+
+	err = writer.Annotation(ion.NewSymbolTokenFromString(proofAnnotation))
+	if err != nil {
+		return err
+	}
+
+	err = writer.BeginStruct()
+	if err != nil {
+		return err
+	}
+
+	err = writer.FieldName(ion.NewSymbolTokenFromString("path"))
+	if err != nil {
+		return err
+	}
+
+	err = writer.WriteString(p.Path)
+	if err != nil {
+		return err
+	}
+
+	err = writer.FieldName(ion.NewSymbolTokenFromString("value"))
+	if err != nil {
+		return err
+	}
+
+	err = writeNode(writer, value)
+	if err != nil {
+		return err
+	}
+
+	err = writer.FieldName(ion.NewSymbolTokenFromString("levels"))
+	if err != nil {
+		return err
+	}
+
+	err = writer.BeginList()
+	if err != nil {
+		return err
+	}
+
+	for _, level := range p.Levels {
+		err = level.write(writer)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = writer.EndList()
+	if err != nil {
+		return err
+	}
+
+	return writer.EndStruct()
+}
+
+func (l *ProofLevel) write(writer ion.Writer) error {
+	err := writer.BeginStruct()
+	if err != nil {
+		return err
+	}
+
+	err = writer.FieldName(ion.NewSymbolTokenFromString("siblings"))
+	if err != nil {
+		return err
+	}
+
+	err = writer.BeginList()
+	if err != nil {
+		return err
+	}
+
+	for _, sibling := range l.Siblings {
+		err = writer.WriteBlob(sibling)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = writer.EndList()
+	if err != nil {
+		return err
+	}
+
+	err = writer.FieldName(ion.NewSymbolTokenFromString("prefix"))
+	if err != nil {
+		return err
+	}
+
+	err = writer.WriteBlob(l.Prefix)
+	if err != nil {
+		return err
+	}
+
+	err = writer.FieldName(ion.NewSymbolTokenFromString("suffix"))
+	if err != nil {
+		return err
+	}
+
+	err = writer.WriteBlob(l.Suffix)
+	if err != nil {
+		return err
+	}
+
+	return writer.EndStruct()
+}
+
+// ReadProof reads a proof written as Ion, e.g. by Proof.Write, from the next top-level value of
+// reader.
+func ReadProof(reader ion.Reader) (*Proof, error) {
+	n, err := readTopLevelNode(reader)
+	if err != nil {
+		return nil, err
+	}
+	if n == nil || n.ionType != ion.StructType || n.isNull {
+		return nil, &InvalidProofError{"a proof is a struct"}
+	}
+
+	proof := &Proof{}
+	var hasPath, hasValue bool
+	for _, field := range n.children {
+		switch fieldNameText(field) {
+		case "path":
+			if field.ionType != ion.StringType || field.isNull {
+				return nil, &InvalidProofError{"a proof's path is not a string"}
+			}
+
+			proof.Path = *field.val.(*string)
+			hasPath = true
+		case "value":
+			field.inStruct = false
+			proof.Value, err = encodeNode(field)
+			if err != nil {
+				return nil, err
+			}
+
+			hasValue = true
+		case "levels":
+			if field.ionType != ion.ListType || field.isNull {
+				return nil, &InvalidProofError{"a proof's levels are not a list"}
+			}
+
+			for _, child := range field.children {
+				level, err := readProofLevel(child)
+				if err != nil {
+					return nil, err
+				}
+
+				proof.Levels = append(proof.Levels, level)
+			}
+		}
+	}
+
+	if !hasPath || !hasValue {
+		return nil, &InvalidProofError{"a proof has no path or value"}
+	}
+
+	return proof, nil
+}
+
+func readProofLevel(n *node) (ProofLevel, error) {
+	var level ProofLevel
+	if n.ionType != ion.StructType || n.isNull {
+		return level, &InvalidProofError{"a proof's level is not a struct"}
+	}
+
+	for _, field := range n.children {
+		switch fieldNameText(field) {
+		case "siblings":
+			if field.ionType != ion.ListType || field.isNull {
+				return level, &InvalidProofError{"a level's siblings are not a list"}
+			}
+
+			for _, sibling := range field.children {
+				if sibling.ionType != ion.BlobType || sibling.isNull {
+					return level, &InvalidProofError{"a level's sibling is not a blob"}
+				}
+
+				level.Siblings = append(level.Siblings, sibling.val.([]byte))
+			}
+		case "prefix", "suffix":
+			if field.ionType != ion.BlobType || field.isNull {
+				return level, &InvalidProofError{fmt.Sprintf("a level's %s is not a blob", fieldNameText(field))}
+			}
+
+			if fieldNameText(field) == "prefix" {
+				level.Prefix = field.val.([]byte)
+			} else {
+				level.Suffix = field.val.([]byte)
+			}
+		}
+	}
+
+	return level, nil
+}
+
+// fieldNameText returns the text of the field name of n, or an empty string if it is unknown.
+func fieldNameText(n *node) string {
+	if n.fieldName == nil || n.fieldName.Text == nil {
+		return ""
+	}
+
+	return *n.fieldName.Text
+}
@@ -0,0 +1,213 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/amzn/ion-go/ion"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProof(t *testing.T) {
+	testCases := []struct {
+		text, path string
+	}{
+		{`{status:"paid", total:12.5, customer:{name:"x"}}`, `status`},
+		{`{order:{id:1, status:"paid"}, x:2}`, `order.status`},
+		{`a::{b:c::d::{d:1, e:2}, f:[3]}`, `b.d`},
+		{`{items:[1, {sku:"a", n:2}, 3], id:4}`, `items[1].sku`},
+		{`{items:[[x::{sku:"a"}], (y)], id:4}`, `items[0][0].sku`},
+		{`[{a:1}, 2]`, `[0].a`},
+		{`(x {a:b::1})`, `[1].a`},
+		{`{a:{b:[1, 2]}, c:3}`, `a`},
+		{`{a:{b:[1, 2]}, c:3}`, `a.b`},
+		{`{a:1, a:2, b:3}`, `a`},
+		{`{'first name':"x", 'it\'s':{y:z}}`, `'it\'s'.y`},
+		{`{a:{{aGVsbG8=}}, b:{{"clob"}}, c:2020T, d:1.5e0, e:null.struct}`, `e`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.text+" "+tc.path, func(t *testing.T) {
+			rootDigest := digestOf(t, tc.text)
+
+			for _, reader := range []ion.Reader{ion.NewReaderString(tc.text), ion.NewReaderBytes(toBinary(t, tc.text))} {
+				proof, err := NewProof(reader, NewCryptoHasherProvider(SHA256), tc.path)
+				require.NoError(t, err, "Something went wrong executing NewProof()")
+
+				verified, err := Verify(roundTripProof(t, proof), rootDigest, NewCryptoHasherProvider(SHA256))
+				require.NoError(t, err, "Something went wrong executing Verify()")
+				assert.True(t, verified, "Expected the proof of %s in %s to verify", tc.path, tc.text)
+			}
+		})
+	}
+}
+
+func TestProofHidesOtherFields(t *testing.T) {
+	text := `{status:"paid", card:"4111111111111111", customer:{name:"secret"}}`
+	proof, err := NewProof(ion.NewReaderString(text), NewCryptoHasherProvider(SHA256), "status")
+	require.NoError(t, err, "Something went wrong executing NewProof()")
+
+	var buf bytes.Buffer
+	writer := ion.NewBinaryWriter(&buf)
+	require.NoError(t, proof.Write(writer))
+	require.NoError(t, writer.Finish())
+
+	assert.False(t, bytes.Contains(buf.Bytes(), []byte("4111111111111111")))
+	assert.False(t, bytes.Contains(buf.Bytes(), []byte("secret")))
+	require.Len(t, proof.Levels, 1)
+	assert.Len(t, proof.Levels[0].Siblings, 2)
+}
+
+func TestProofDoesNotVerify(t *testing.T) {
+	text := `{order:{id:1, status:"paid"}, x:2}`
+	proof, err := NewProof(ion.NewReaderString(text), NewCryptoHasherProvider(SHA256), "order.status")
+	require.NoError(t, err, "Something went wrong executing NewProof()")
+
+	// A proof of a different value, or for a different document, doesn't verify.
+	forged := *proof
+	forged.Value = toBinary(t, `"unpaid"`)
+	verified, err := Verify(&forged, digestOf(t, text), NewCryptoHasherProvider(SHA256))
+	require.NoError(t, err, "Something went wrong executing Verify()")
+	assert.False(t, verified)
+
+	verified, err = Verify(proof, digestOf(t, `{order:{id:1, status:"paid"}, x:3}`), NewCryptoHasherProvider(SHA256))
+	require.NoError(t, err, "Something went wrong executing Verify()")
+	assert.False(t, verified)
+
+	// The digests of a proof made with another algorithm aren't even the right size.
+	_, err = Verify(proof, digestOf(t, text), NewCryptoHasherProvider(SHA512))
+	assert.True(t, errors.Is(err, ErrInvalidProof), "Expected an InvalidProofError, got %v", err)
+}
+
+func TestProofIsOfItsPath(t *testing.T) {
+	testCases := []struct {
+		text, path, claimed string
+	}{
+		{`{status:"unpaid", refund:{status:"paid"}}`, `refund.status`, `status`},
+		{`{status:"unpaid", refund:{status:"paid"}}`, `refund.status`, `refund.refund`},
+		{`{items:[{a:1}, {a:2}]}`, `items[1].a`, `items[0].a`},
+		{`{items:[{a:1}, {a:2}]}`, `items[1].a`, `items[1][0].a`},
+		{`{items:[[{a:1}]]}`, `items[0][0].a`, `items[0].a`},
+		{`{items:({a:1})}`, `items[0].a`, `items.a`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.text+" "+tc.claimed, func(t *testing.T) {
+			proof, err := NewProof(ion.NewReaderString(tc.text), NewCryptoHasherProvider(SHA256), tc.path)
+			require.NoError(t, err, "Something went wrong executing NewProof()")
+
+			claimed := *proof
+			claimed.Path = tc.claimed
+			verified, err := Verify(&claimed, digestOf(t, tc.text), NewCryptoHasherProvider(SHA256))
+			assert.False(t, verified, "Expected the proof of %s not to verify as %s", tc.path, tc.claimed)
+			if err != nil {
+				assert.True(t, errors.Is(err, ErrInvalidProof), "Expected an InvalidProofError, got %v", err)
+			}
+		})
+	}
+}
+
+func TestProofErrors(t *testing.T) {
+	provider := NewCryptoHasherProvider(SHA256)
+
+	_, err := NewProof(ion.NewReaderString(`{a:{b:1}}`), provider, "a.c")
+	var fieldNotFound *FieldNotFoundError
+	require.True(t, errors.As(err, &fieldNotFound), "Expected a FieldNotFoundError, got %v", err)
+	assert.Equal(t, "a.c", fieldNotFound.Path)
+
+	for _, path := range []string{"", "[0]", "a[*]", "*", "a[0"} {
+		_, err = NewProof(ion.NewReaderString(`{a:[1]}`), provider, path)
+		assert.True(t, errors.Is(err, ErrInvalidArgument), "Expected an InvalidArgumentError for path %q, got %v", path, err)
+	}
+
+	for _, text := range []string{`[1]`, `{a:null.struct}`, `{a:[1]}`} {
+		_, err = NewProof(ion.NewReaderString(text), provider, "a.b")
+		assert.True(t, errors.Is(err, ErrFieldNotFound), "Expected a FieldNotFoundError for %s, got %v", text, err)
+	}
+
+	_, err = NewProof(ion.NewReaderString(``), provider, "a")
+	assert.True(t, errors.Is(err, ErrInvalidOperation), "Expected an InvalidOperationError, got %v", err)
+
+	proof, err := NewProof(ion.NewReaderString(`{a:{b:1}}`), provider, "a.b")
+	require.NoError(t, err, "Something went wrong executing NewProof()")
+
+	invalid := []func(p *Proof){
+		func(p *Proof) { p.Levels = nil },
+		func(p *Proof) { p.Value = nil },
+		func(p *Proof) { p.Levels[0].Prefix = append(p.Levels[0].Prefix, escapeByte) },
+		func(p *Proof) { p.Levels[0].Suffix = append(p.Levels[0].Suffix, endMarkerByte) },
+		func(p *Proof) { p.Levels[1].Siblings = [][]byte{{1, 2, 3}} },
+		func(p *Proof) { p.Path = "b" },
+		func(p *Proof) { p.Path = "a[0].b" },
+		func(p *Proof) { p.Path = "a.*" },
+		func(p *Proof) { p.Levels[0].Prefix = p.Levels[0].Prefix[1:] },
+		func(p *Proof) { p.Levels[1].Prefix = p.Levels[0].Prefix },
+	}
+
+	for _, invalidate := range invalid {
+		p := *proof
+		p.Levels = append([]ProofLevel(nil), proof.Levels...)
+		invalidate(&p)
+
+		_, err = Verify(&p, digestOf(t, `{a:{b:1}}`), provider)
+		assert.True(t, errors.Is(err, ErrInvalidProof), "Expected an InvalidProofError, got %v", err)
+	}
+}
+
+func TestReadProofInvalid(t *testing.T) {
+	testCases := []string{
+		``,
+		`1`,
+		`{value:1}`,
+		`{path:a, value:1}`,
+		`{path:"a"}`,
+		`{path:"a", value:1, levels:{}}`,
+		`{path:"a", value:1, levels:[1]}`,
+		`{path:"a", value:1, levels:[{siblings:[1]}]}`,
+		`{path:"a", value:1, levels:[{prefix:"x"}]}`,
+	}
+
+	for _, tc := range testCases {
+		_, err := ReadProof(ion.NewReaderString(tc))
+		assert.True(t, errors.Is(err, ErrInvalidProof), "Expected an InvalidProofError reading %s, got %v", tc, err)
+	}
+}
+
+// digestOf returns the digest of the one top-level value of text.
+func digestOf(t *testing.T, text string) []byte {
+	digests, err := HashBinary(toBinary(t, text), NewCryptoHasherProvider(SHA256))
+	require.NoError(t, err, "Something went wrong executing HashBinary()")
+	require.Len(t, digests, 1)
+	return digests[0]
+}
+
+// roundTripProof returns proof after it is written as Ion binary and read again.
+func roundTripProof(t *testing.T, proof *Proof) *Proof {
+	var buf bytes.Buffer
+	writer := ion.NewBinaryWriter(&buf)
+	require.NoError(t, proof.Write(writer), "Something went wrong writing the proof")
+	require.NoError(t, writer.Finish())
+
+	read, err := ReadProof(ion.NewReaderBytes(buf.Bytes()))
+	require.NoError(t, err, "Something went wrong executing ReadProof()")
+	assert.Equal(t, proof.Path, read.Path)
+	assert.Equal(t, len(proof.Levels), len(read.Levels))
+	return read
+}
@@ -0,0 +1,300 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/amzn/ion-go/ion"
+)
+
+// QuantizationMode selects how a Quantization rounds values.
+type QuantizationMode int
+
+const (
+	// QuantizeSignificantDigits rounds values to a number of significant digits, e.g. 1234.5 to
+	// 1230 for three digits.
+	QuantizeSignificantDigits QuantizationMode = iota + 1
+
+	// QuantizeDecimalPlaces rounds values to a number of decimal places, e.g. 1234.5678 to 1234.57
+	// for two places, or to a multiple of a power of ten if it is negative, e.g. 1234.5 to 1200 for
+	// minus two places.
+	QuantizeDecimalPlaces
+)
+
+// A Quantization rounds floats, and optionally decimals, before they are hashed, so that values
+// that differ only in their last few digits, e.g. the results of the same calculation on different
+// platforms, hash the same. Values are rounded half to even, from the shortest decimal form of a
+// float, and are hashed without trailing zeros, so, e.g., the decimals 1.5 and 1.50 hash the same.
+// A value that rounds to zero is hashed as positive zero. NaN and infinities are not changed.
+type Quantization struct {
+	Mode QuantizationMode
+
+	// Digits is the number of significant digits, which must be positive, or of decimal places.
+	Digits int
+
+	// Decimals selects whether decimals are rounded as well as floats.
+	Decimals bool
+}
+
+// String returns a description of the quantization, e.g. sig=6,decimals.
+func (q Quantization) String() string {
+	var description strings.Builder
+	switch q.Mode {
+	case QuantizeSignificantDigits:
+		description.WriteString("sig=")
+	case QuantizeDecimalPlaces:
+		description.WriteString("places=")
+	default:
+		fmt.Fprintf(&description, "mode(%d)=", q.Mode)
+	}
+
+	description.WriteString(strconv.Itoa(q.Digits))
+	if q.Decimals {
+		description.WriteString(",decimals")
+	}
+
+	return description.String()
+}
+
+// quantizationRule is a Quantization of the values at paths, or of all values if there are none.
+type quantizationRule struct {
+	Quantization
+	paths    []string
+	patterns []pathPattern
+}
+
+func newQuantizationRules(rules []quantizationRule) ([]quantizationRule, error) {
+	parsed := make([]quantizationRule, 0, len(rules))
+	for _, rule := range rules {
+		switch {
+		case rule.Mode == QuantizeSignificantDigits && rule.Digits > 0:
+		case rule.Mode == QuantizeDecimalPlaces:
+		default:
+			return nil, &InvalidArgumentError{"quantization", rule.Quantization}
+		}
+
+		patterns, err := parsePathPatterns(rule.paths)
+		if err != nil {
+			return nil, err
+		}
+
+		rule.patterns = patterns
+		parsed = append(parsed, rule)
+	}
+
+	return parsed, nil
+}
+
+// describeQuantizations returns a description of the quantization rules for DigestMetadata, e.g.
+// sig=6; [payload.temp]places=2,decimals.
+func describeQuantizations(rules []quantizationRule) string {
+	descriptions := make([]string, len(rules))
+	for i, rule := range rules {
+		descriptions[i] = rule.String()
+		if len(rule.paths) > 0 {
+			descriptions[i] = "[" + strings.Join(rule.paths, ",") + "]" + descriptions[i]
+		}
+	}
+
+	return strings.Join(descriptions, "; ")
+}
+
+// quantizedValue is a hashValue whose float or decimal value is rounded by a Quantization. It is
+// reused between values.
+type quantizedValue struct {
+	hashValue
+	quantization *Quantization
+	scratch      []byte
+}
+
+func (qv *quantizedValue) value() (interface{}, error) {
+	val, err := qv.hashValue.value()
+	if err != nil {
+		return nil, err
+	}
+
+	// Encoding the value first reads it the same way from every kind of hashValue.
+	_, representation, err := appendScalar(qv.scratch[:0], qv.Type(), val, false)
+	if err != nil {
+		return nil, err
+	}
+	qv.scratch = representation
+
+	if qv.Type() == ion.FloatType {
+		if len(representation) == 0 {
+			return float64(0), nil
+		}
+
+		return quantizeFloat(math.Float64frombits(binary.BigEndian.Uint64(representation)), qv.quantization), nil
+	}
+
+	if len(representation) == 0 {
+		return quantizeDecimal(new(big.Int), 0, qv.quantization), nil
+	}
+
+	exponent, _, rest := decodeVarInt(representation)
+	return quantizeDecimal(decodeSignedInt(rest), int32(exponent), qv.quantization), nil
+}
+
+// quantizeFloat rounds a float as q calls for.
+func quantizeFloat(val float64, q *Quantization) float64 {
+	if math.IsNaN(val) || math.IsInf(val, 0) {
+		return val
+	}
+
+	// The shortest decimal form of the float is the one that is rounded.
+	mantissa, exponent, err := splitFloat(strconv.FormatFloat(val, 'e', -1, 64))
+	if err != nil {
+		return val
+	}
+
+	coefficient, exponent := quantizeCoefficient(mantissa, exponent, q)
+	if coefficient.Sign() == 0 {
+		return 0
+	}
+
+	rounded, err := strconv.ParseFloat(coefficient.String()+"e"+strconv.Itoa(int(exponent)), 64)
+	if err != nil {
+		// The rounded value overflows, as only values close to the largest float can.
+		return val
+	}
+
+	return rounded
+}
+
+// splitFloat splits a float formatted with 'e' into an integer coefficient and an exponent, e.g.
+// -1.25e+03 into -125 and 1.
+func splitFloat(formatted string) (*big.Int, int32, error) {
+	mantissa, exponentText, _ := strings.Cut(formatted, "e")
+	exponent, err := strconv.Atoi(exponentText)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	whole, fraction, _ := strings.Cut(mantissa, ".")
+	coefficient, ok := new(big.Int).SetString(whole+fraction, 10)
+	if !ok {
+		return nil, 0, &InvalidArgumentError{"float", formatted}
+	}
+
+	return coefficient, int32(exponent - len(fraction)), nil
+}
+
+// quantizeDecimal rounds a decimal with the given coefficient and exponent as q calls for.
+func quantizeDecimal(coefficient *big.Int, exponent int32, q *Quantization) *ion.Decimal {
+	coefficient, exponent = quantizeCoefficient(coefficient, exponent, q)
+	return ion.NewDecimal(coefficient, exponent, false)
+}
+
+// quantizeCoefficient rounds the number with the given coefficient and exponent as q calls for,
+// returning it without trailing zeros. Zero is returned with a zero exponent.
+func quantizeCoefficient(coefficient *big.Int, exponent int32, q *Quantization) (*big.Int, int32) {
+	if coefficient.Sign() == 0 {
+		return new(big.Int), 0
+	}
+
+	digits := int64(len(new(big.Int).Abs(coefficient).String()))
+	target := int64(-q.Digits)
+	if q.Mode == QuantizeSignificantDigits {
+		target = int64(exponent) + digits - int64(q.Digits)
+	}
+
+	if shift := target - int64(exponent); shift > digits {
+		// The number is less than a tenth of the unit it is rounded to, so it rounds to zero.
+		return new(big.Int), 0
+	} else if shift > 0 {
+		divisor := new(big.Int).Exp(big.NewInt(10), big.NewInt(shift), nil)
+		quotient, remainder := new(big.Int).QuoRem(coefficient, divisor, new(big.Int))
+
+		// The quotient is truncated towards zero, so rounding its magnitude up moves it away from zero.
+		half := new(big.Int).Abs(remainder)
+		half.Mul(half, big.NewInt(2))
+		switch cmp := half.Cmp(divisor); {
+		case cmp > 0, cmp == 0 && quotient.Bit(0) == 1:
+			quotient.Add(quotient, big.NewInt(int64(remainder.Sign())))
+		}
+
+		if quotient.Sign() == 0 {
+			return quotient, 0
+		}
+
+		coefficient, exponent = quotient, int32(target)
+	}
+
+	// Trailing zeros are removed so that, e.g., 1.5 and 1.50 are rounded alike.
+	ten := big.NewInt(10)
+	quotient, remainder := new(big.Int), new(big.Int)
+	for exponent < math.MaxInt32 {
+		quotient.QuoRem(coefficient, ten, remainder)
+		if remainder.Sign() != 0 {
+			break
+		}
+
+		coefficient, quotient = quotient, new(big.Int)
+		exponent++
+	}
+
+	return coefficient, exponent
+}
+
+// quantize returns ionValue, or a value that rounds it if a quantization applies to it.
+func (h *hasher) quantize(ionValue hashValue) hashValue {
+	if h.quantizations == nil || ionValue.IsNull() {
+		return ionValue
+	}
+
+	switch ionValue.Type() {
+	case ion.FloatType, ion.DecimalType:
+	default:
+		return ionValue
+	}
+
+	quantization := h.quantizationAt()
+	if quantization == nil || ionValue.Type() == ion.DecimalType && !quantization.Decimals {
+		return ionValue
+	}
+
+	h.quantized.hashValue = ionValue
+	h.quantized.quantization = quantization
+	return &h.quantized
+}
+
+// quantizationAt returns the quantization of the value being hashed: that of the first rule with a
+// path that matches it, or else that of the first rule without paths, or nil if there is none.
+func (h *hasher) quantizationAt() *Quantization {
+	path := h.pathFrom(len(h.path))
+
+	var global *Quantization
+	for i := range h.quantizations {
+		rule := &h.quantizations[i]
+		switch {
+		case len(rule.patterns) == 0:
+			if global == nil {
+				global = &rule.Quantization
+			}
+		case matchesAny(rule.patterns, path):
+			return &rule.Quantization
+		}
+	}
+
+	return global
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/amzn/ion-go/ion"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuantizeFloat(t *testing.T) {
+	significant := func(digits int) *Quantization {
+		return &Quantization{Mode: QuantizeSignificantDigits, Digits: digits}
+	}
+	places := func(digits int) *Quantization {
+		return &Quantization{Mode: QuantizeDecimalPlaces, Digits: digits}
+	}
+
+	testCases := []struct {
+		val          float64
+		quantization *Quantization
+		expected     float64
+	}{
+		{1234.5678, significant(3), 1230},
+		{1234.5678, significant(6), 1234.57},
+		{-1234.5678, significant(6), -1234.57},
+		{0.1 + 0.2, significant(15), 0.3},
+		{1.5e-300, significant(1), 2e-300},
+		{2.5, significant(1), 2},
+		{3.5, significant(1), 4},
+		{-2.5, significant(1), -2},
+		{1234.5678, places(2), 1234.57},
+		{1234.5, places(-2), 1200},
+		{0.0004, places(3), 0},
+		{-0.0004, places(3), 0},
+		{0.0006, places(3), 0.001},
+		{0.6, places(0), 1},
+		{math.MaxFloat64, significant(1), math.MaxFloat64},
+		{math.Inf(-1), places(2), math.Inf(-1)},
+	}
+
+	for _, tc := range testCases {
+		actual := quantizeFloat(tc.val, tc.quantization)
+		assert.Equal(t, tc.expected, actual, "Expected %v rounded to %v to be %v", tc.val, tc.quantization, tc.expected)
+		assert.False(t, math.Signbit(actual) && actual == 0, "Expected %v to round to positive zero", tc.val)
+	}
+
+	assert.True(t, math.IsNaN(quantizeFloat(math.NaN(), significant(3))))
+}
+
+func TestQuantizeDecimal(t *testing.T) {
+	testCases := []struct {
+		val          string
+		quantization Quantization
+		expected     string
+	}{
+		{"1.2345", Quantization{Mode: QuantizeDecimalPlaces, Digits: 2}, "1.23"},
+		{"1.2350", Quantization{Mode: QuantizeDecimalPlaces, Digits: 2}, "1.24"},
+		{"1.50", Quantization{Mode: QuantizeDecimalPlaces, Digits: 2}, "1.5"},
+		{"100.", Quantization{Mode: QuantizeDecimalPlaces, Digits: 2}, "1d2"},
+		{"-0.001", Quantization{Mode: QuantizeDecimalPlaces, Digits: 2}, "0."},
+		{"1d-1000000", Quantization{Mode: QuantizeDecimalPlaces, Digits: 2}, "0."},
+		{"1d1000000", Quantization{Mode: QuantizeDecimalPlaces, Digits: 2}, "1d1000000"},
+		{"123456789012345678901234567890", Quantization{Mode: QuantizeSignificantDigits, Digits: 3}, "1.23d29"},
+		{"-0.000123456", Quantization{Mode: QuantizeSignificantDigits, Digits: 2}, "-0.00012"},
+		{"0.000", Quantization{Mode: QuantizeSignificantDigits, Digits: 2}, "0."},
+	}
+
+	for _, tc := range testCases {
+		coefficient, exponent := ion.MustParseDecimal(tc.val).CoEx()
+		actual := quantizeDecimal(coefficient, exponent, &tc.quantization)
+		assert.True(t, actual.Equal(ion.MustParseDecimal(tc.expected)),
+			"Expected %s rounded to %v to be %s, not %s", tc.val, tc.quantization, tc.expected, actual)
+	}
+}
+
+func TestWithQuantization(t *testing.T) {
+	sixDigits := WithQuantization(Quantization{Mode: QuantizeSignificantDigits, Digits: 6})
+	assertAllEqual(t, true, `1.0000000001e0 0.9999999999e0 1e0 1.0000001e0`, sixDigits)
+	assertAllEqual(t, true, `[0.30000000000000004e0] [0.3e0]`, sixDigits)
+	assertAllEqual(t, true, `-0e0 1e-20 -1e-20 0e0`, WithQuantization(Quantization{Mode: QuantizeDecimalPlaces, Digits: 10}))
+	assertAllEqual(t, false, `1.00001e0 1.00002e0`, sixDigits)
+	assertAllEqual(t, false, `1.0000000001e0 1e0`)
+
+	// Decimals are only rounded if the quantization calls for it.
+	assertAllEqual(t, false, `1.2345 1.2346`, WithQuantization(Quantization{Mode: QuantizeDecimalPlaces, Digits: 3}))
+	assertAllEqual(t, true, `1.2347 1.2346 1.235 1.23500`,
+		WithQuantization(Quantization{Mode: QuantizeDecimalPlaces, Digits: 3, Decimals: true}))
+
+	// Other types are never rounded.
+	assertAllEqual(t, false, `1 2`, WithQuantization(Quantization{Mode: QuantizeDecimalPlaces, Digits: -1, Decimals: true}))
+}
+
+func TestWithQuantizationPaths(t *testing.T) {
+	opts := []Option{
+		WithQuantization(Quantization{Mode: QuantizeSignificantDigits, Digits: 2}, "readings[*].temp"),
+		WithQuantization(Quantization{Mode: QuantizeDecimalPlaces, Digits: 4}),
+	}
+
+	sums := hashValues(t, `{readings:[{temp:21.04e0, id:1.00001e0}], total:3.00001e0}
+		{readings:[{temp:20.96e0, id:1e0}], total:3e0}
+		{readings:[{temp:21.6e0, id:1e0}], total:3e0}`, opts...)
+	assert.Equal(t, sums[0], sums[1])
+	assert.NotEqual(t, sums[0], sums[2])
+}
+
+func TestWithQuantizationFirstApplies(t *testing.T) {
+	twoDigits := Quantization{Mode: QuantizeSignificantDigits, Digits: 2}
+	fourPlaces := Quantization{Mode: QuantizeDecimalPlaces, Digits: 4}
+
+	// 1.234 and 1.2 are the same to two significant digits, but not to four decimal places.
+	assertAllEqual(t, true, `1.234e0 1.2e0`, WithQuantization(twoDigits), WithQuantization(fourPlaces))
+	assertAllEqual(t, false, `1.234e0 1.2e0`, WithQuantization(fourPlaces), WithQuantization(twoDigits))
+
+	assertAllEqual(t, true, `{a:1.234e0} {a:1.2e0}`, WithQuantization(twoDigits, "a"), WithQuantization(fourPlaces, "a"))
+	assertAllEqual(t, false, `{a:1.234e0} {a:1.2e0}`, WithQuantization(fourPlaces, "a"), WithQuantization(twoDigits, "a"))
+}
+
+func TestWithQuantizationInvalid(t *testing.T) {
+	quantizations := []Quantization{
+		{},
+		{Mode: QuantizeSignificantDigits},
+		{Mode: QuantizeSignificantDigits, Digits: -1},
+		{Mode: 3, Digits: 1},
+	}
+
+	for _, quantization := range quantizations {
+		_, err := NewHashReader(ion.NewReaderString("1e0"), NewCryptoHasherProvider(SHA256), WithQuantization(quantization))
+		assert.True(t, errors.Is(err, ErrInvalidArgument), "Expected an InvalidArgumentError for %v", quantization)
+	}
+
+	_, err := NewHashReader(ion.NewReaderString("1e0"), NewCryptoHasherProvider(SHA256),
+		WithQuantization(Quantization{Mode: QuantizeDecimalPlaces}, "a..b"))
+	assert.True(t, errors.Is(err, ErrInvalidArgument), "Expected an InvalidArgumentError")
+}
+
+func TestDigestMetadata(t *testing.T) {
+	hasherProvider := NewCryptoHasherProvider(SHA256)
+
+	ionHashReader, err := NewHashReader(ion.NewReaderString("1e0"), hasherProvider)
+	require.NoError(t, err, "Expected NewHashReader() to successfully create a HashReader")
+	assert.Equal(t, DigestMetadata{}, ionHashReader.Metadata())
+
+	opts := []Option{
+		WithQuantization(Quantization{Mode: QuantizeSignificantDigits, Digits: 6}),
+		WithQuantization(Quantization{Mode: QuantizeDecimalPlaces, Digits: 2, Decimals: true}, "a.b", "c[*]"),
+	}
+	expected := DigestMetadata{Quantization: "sig=6; [a.b,c[*]]places=2,decimals"}
+
+	ionHashReader, err = NewHashReader(ion.NewReaderString("1e0"), hasherProvider, opts...)
+	require.NoError(t, err, "Expected NewHashReader() to successfully create a HashReader")
+	assert.Equal(t, expected, ionHashReader.Metadata())
+
+	ionHashWriter, err := NewHashWriter(ion.NewTextWriter(&strings.Builder{}), hasherProvider, opts...)
+	require.NoError(t, err, "Expected NewHashWriter() to successfully create a HashWriter")
+	assert.Equal(t, expected, ionHashWriter.Metadata())
+
+	binaryHashReader, err := NewBinaryHashReader(nil, hasherProvider, opts...)
+	require.NoError(t, err, "Expected NewBinaryHashReader() to successfully create a BinaryHashReader")
+	assert.Equal(t, expected, binaryHashReader.Metadata())
+
+	// Different quantizations are told apart.
+	ionHashReader, err = NewHashReader(ion.NewReaderString("1e0"), hasherProvider,
+		WithQuantization(Quantization{Mode: QuantizeSignificantDigits, Digits: 7}))
+	require.NoError(t, err, "Expected NewHashReader() to successfully create a HashReader")
+	assert.NotEqual(t, expected, ionHashReader.Metadata())
+}
@@ -0,0 +1,191 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+import (
+	"github.com/amzn/ion-go/ion"
+	"github.com/amzn/ion-hash-go/internal/ionvalue"
+)
+
+// redactedAnnotation annotates the placeholder of a redacted field.
+const redactedAnnotation = "ion_hash_redacted"
+
+// Redact writes the remaining values of reader to writer with the fields at paths, written as for
+// WithUnorderedPaths, replaced by placeholders: blobs annotated with ion_hash_redacted that hold
+// the digests of the fields, computed with hasherProvider and opts. Hashing the redacted values with
+// the same options and WithRedactions gives the digests of the original values. Each path must end
+// with a field name, or * for every field of a struct. A field that isn't hashed at all, e.g.
+// because it is excluded, is left out.
+func Redact(reader ion.Reader, writer ion.Writer, hasherProvider IonHasherProvider, paths []string, opts ...Option) error {
+	patterns, err := parsePathPatterns(paths)
+	if err != nil {
+		return err
+	}
+
+	for i, pattern := range patterns {
+		if len(pattern) == 0 || !pattern[len(pattern)-1].isStruct {
+			return &InvalidArgumentError{"path", paths[i]}
+		}
+	}
+
+	// The fields of values that have already been redacted keep their placeholders.
+	h, err := newHasher(hasherProvider, newOptions(append(opts[:len(opts):len(opts)], WithRedactions())))
+	if err != nil {
+		return err
+	}
+
+	r := &redactor{hasher: h, writer: writer, patterns: patterns}
+	for {
+		n, err := readTopLevelNode(reader)
+		if err != nil || n == nil {
+			return err
+		}
+
+		err = r.value(n)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// redactor hashes values and writes them, with the fields that patterns match replaced by placeholders.
+type redactor struct {
+	hasher   *hasher
+	writer   ion.Writer
+	patterns []pathPattern
+}
+
+// value hashes and writes n.
+func (r *redactor) value(n *node) error {
+	if n.isNull || !ion.IsContainer(n.ionType) {
+		err := hashNode(r.hasher, n)
+		if err != nil {
+			return err
+		}
+
+		err = r.fieldName(n)
+		if err != nil {
+			return err
+		}
+
+		return writeNode(r.writer, n)
+	}
+
+	err := r.hasher.stepIn(n)
+	if err != nil {
+		return err
+	}
+
+	err = r.fieldName(n)
+	if err != nil {
+		return err
+	}
+
+	err = ionvalue.WriteAnnotations(r.writer, n.annotations)
+	if err != nil {
+		return err
+	}
+
+	err = ionvalue.BeginContainer(r.writer, n.ionType)
+	if err != nil {
+		return err
+	}
+
+	for _, child := range n.children {
+		if n.ionType == ion.StructType && r.isRedacted(child) {
+			err = r.redact(child)
+		} else {
+			err = r.value(child)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	err = r.hasher.stepOut()
+	if err != nil {
+		return err
+	}
+
+	return ionvalue.EndContainer(r.writer, n.ionType)
+}
+
+// isRedacted reports whether field, which is in the struct being hashed, is at a path to redact.
+func (r *redactor) isRedacted(field *node) bool {
+	path := append([]pathElement(nil), r.hasher.pathFrom(len(r.hasher.path))...)
+	path[len(path)-1].fieldName = field.fieldName
+	return matchesAny(r.patterns, path)
+}
+
+// redact hashes field, which is in the struct being hashed, and writes its placeholder.
+func (r *redactor) redact(field *node) error {
+	if r.hasher.excluding > 0 {
+		// The struct isn't hashed, so neither is the field.
+		return hashNode(r.hasher, field)
+	}
+
+	structHasher := r.hasher.currentHasher.(*structSerializer)
+	hashed := len(structHasher.fieldHashes)
+	err := hashNode(r.hasher, field)
+	if err != nil || len(structHasher.fieldHashes) == hashed {
+		return err
+	}
+
+	err = r.fieldName(field)
+	if err != nil {
+		return err
+	}
+
+	err = r.writer.Annotation(ion.NewSymbolTokenFromString(redactedAnnotation))
+	if err != nil {
+		return err
+	}
+
+	return r.writer.WriteBlob(structHasher.fieldHashes[hashed])
+}
+
+func (r *redactor) fieldName(n *node) error {
+	if !n.inStruct {
+		return nil
+	}
+
+	return r.writer.FieldName(ionvalue.PortableSymbol(*n.fieldName))
+}
+
+// redactedDigest returns the digest held by ionValue if it is the placeholder of a redacted field.
+func (h *hasher) redactedDigest(ionValue hashValue) ([]byte, bool, error) {
+	if !h.inStruct() || ionValue.Type() != ion.BlobType || ionValue.IsNull() {
+		return nil, false, nil
+	}
+
+	annotations, err := ionValue.getAnnotations()
+	if err != nil || len(annotations) != 1 || annotations[0].Text == nil || *annotations[0].Text != redactedAnnotation {
+		return nil, false, err
+	}
+
+	val, err := ionValue.value()
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Encoding the value reads it the same way from every kind of hashValue.
+	_, digest, err := appendScalar(nil, ion.BlobType, val, false)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return digest, true, nil
+}
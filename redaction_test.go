@@ -0,0 +1,103 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/amzn/ion-go/ion"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// redact returns the values of text, redacted at paths, as Ion text.
+func redact(t *testing.T, text string, paths []string, opts ...Option) string {
+	var redacted strings.Builder
+	writer := ion.NewTextWriter(&redacted)
+	err := Redact(ion.NewReaderString(text), writer, NewCryptoHasherProvider(SHA256), paths, opts...)
+	require.NoError(t, err, "Something went wrong executing Redact()")
+	require.NoError(t, writer.Finish())
+	return redacted.String()
+}
+
+func TestRedact(t *testing.T) {
+	testCases := []struct {
+		text  string
+		paths []string
+	}{
+		{`{id:1, ssn:"123-45-6789"}`, []string{"ssn"}},
+		{`{id:1, address:{street:"1 Main St", city:"Springfield"}}`, []string{"address.street"}},
+		{`{id:1, address:a::{street:"1 Main St", city:"Springfield"}}`, []string{"address"}},
+		{`{items:[{sku:"a", price:1.5}, {sku:"b", price:2.5}], total:4.0}`, []string{"items[*].price"}},
+		{`{a:1, a:2, b:x::3}`, []string{"a", "b"}},
+		{`{a:1, b:{c:2}} {a:3} [{a:4}]`, []string{"a", "[0].a"}},
+		{`{a:1, b:2, c:[3]}`, []string{"*"}},
+		{`{a:1, b:null, c:null.list}`, []string{"b", "c"}},
+		{`{id:1, ssn:"123-45-6789"}`, []string{"missing"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.text, func(t *testing.T) {
+			redacted := redact(t, tc.text, tc.paths)
+			assert.Equal(t, hashValues(t, tc.text), hashValues(t, redacted, WithRedactions()),
+				"Expected %s to have the digests of %s", redacted, tc.text)
+
+			// Redacting again keeps the placeholders.
+			assert.Equal(t, redacted, redact(t, redacted, tc.paths, WithRedactions()))
+		})
+	}
+}
+
+func TestRedactHidesFields(t *testing.T) {
+	text := `{id:1, ssn:"123-45-6789", address:{street:"1 Main St", city:"Springfield"}}`
+	redacted := redact(t, text, []string{"ssn", "address.street"})
+
+	assert.NotContains(t, redacted, "123-45-6789")
+	assert.NotContains(t, redacted, "Main St")
+	assert.Contains(t, redacted, "Springfield")
+	assert.Equal(t, 2, strings.Count(redacted, "ion_hash_redacted::{{"))
+
+	// Without WithRedactions, placeholders are hashed as the blobs that they are.
+	assert.NotEqual(t, hashValues(t, text), hashValues(t, redacted))
+}
+
+func TestRedactWithOptions(t *testing.T) {
+	text := `{id:1, secret:"x", tags:[b, a], meta:{note:"y", at:2020T}}`
+	opts := []Option{WithOrderedStructs(), WithUnorderedPaths("tags"), WithExcludedPaths("meta.at")}
+
+	redacted := redact(t, text, []string{"secret", "tags", "meta.at"}, opts...)
+	assert.NotContains(t, redacted, "2020")
+	assert.Equal(t, hashValues(t, text, opts...), hashValues(t, redacted, append(opts, WithRedactions())...))
+}
+
+func TestRedactionPlaceholdersOutsideStructs(t *testing.T) {
+	// Placeholders are only those of struct fields, so elsewhere they are hashed as blobs.
+	text := `ion_hash_redacted::{{AAAA}} [ion_hash_redacted::{{AAAA}}]`
+	assert.Equal(t, hashValues(t, text), hashValues(t, text, WithRedactions()))
+
+	text = `{a:ion_hash_redacted::other::{{AAAA}}, b:ion_hash_redacted::"AAAA"}`
+	assert.Equal(t, hashValues(t, text), hashValues(t, text, WithRedactions()))
+}
+
+func TestRedactInvalidPaths(t *testing.T) {
+	for _, path := range []string{"", "[0]", "a[*]", "a[0"} {
+		err := Redact(ion.NewReaderString(`{a:1}`), ion.NewTextWriter(&strings.Builder{}),
+			NewCryptoHasherProvider(SHA256), []string{path})
+		assert.True(t, errors.Is(err, ErrInvalidArgument), "Expected an InvalidArgumentError for path %q, got %v", path, err)
+	}
+}
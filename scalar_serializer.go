@@ -15,14 +15,12 @@
 
 package ionhash
 
-import "github.com/amzn/ion-go/ion"
-
 type scalarSerializer struct {
 	baseSerializer
 }
 
-func newScalarSerializer(hashFunction IonHasher, depth int) serializer {
-	return &scalarSerializer{baseSerializer{hashFunction: hashFunction, depth: depth}}
+func newScalarSerializer(hashFunction IonHasher, depth int, limiter *limiter) serializer {
+	return &scalarSerializer{baseSerializer{hashFunction: hashFunction, depth: depth, limiter: limiter}}
 }
 
 func (ss *scalarSerializer) scalar(ionValue hashValue) error {
@@ -31,56 +29,15 @@ func (ss *scalarSerializer) scalar(ionValue hashValue) error {
 		return err
 	}
 
-	err = ss.beginMarker()
-	if err != nil {
-		return err
-	}
-
 	var ionVal interface{}
-	var ionType ion.Type
-	if ionValue.IsNull() {
-		ionVal = nil
-		ionType = ion.NoType
-	} else {
+	if !ionValue.IsNull() {
 		ionVal, err = ionValue.value()
 		if err != nil {
 			return err
 		}
-		ionType = ionValue.Type()
-	}
-
-	scalarBytes, err := ss.getBytes(ionValue.Type(), ionVal, ionValue.IsNull())
-	if err != nil {
-		return err
-	}
-
-	var symbol *ion.SymbolToken = nil
-	if ionValue.Type() == ion.SymbolType {
-		if token, ok := ionVal.(ion.SymbolToken); ok {
-			symbol = &token
-		} else if token, ok := ionVal.(*ion.SymbolToken); ok {
-			symbol = token
-		}
-	}
-
-	tq, representation, err := ss.scalarOrNullSplitParts(ionType, symbol, ionValue.IsNull(), scalarBytes)
-	if err != nil {
-		return err
-	}
-
-	err = ss.write([]byte{tq})
-	if err != nil {
-		return err
-	}
-
-	if len(representation) > 0 {
-		err = ss.write(escape(representation))
-		if err != nil {
-			return err
-		}
 	}
 
-	err = ss.endMarker()
+	err = ss.writeScalar(ionValue.Type(), ionVal, ionValue.IsNull())
 	if err != nil {
 		return err
 	}
@@ -16,31 +16,119 @@
 package ionhash
 
 import (
+	"math/big"
 	"testing"
 
+	"github.com/amzn/ion-go/ion"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestEscape(t *testing.T) {
 	// Null case.
-	assert.Nil(t, escape(nil))
+	assert.Nil(t, appendEscaped(nil, nil))
 
 	// Happy cases.
 	var empty []byte
-	assert.Equal(t, empty, escape(empty))
+	assert.Equal(t, empty, appendEscaped(nil, empty))
 
 	bytes := []byte{0x10, 0x11, 0x12, 0x13}
-	assert.Equal(t, bytes, escape(bytes))
+	assert.Equal(t, bytes, appendEscaped(nil, bytes))
 
 	// Escape cases.
-	assert.Equal(t, []byte{escapeByte, 0x0B}, escape([]byte{0x0B}))
-	assert.Equal(t, []byte{escapeByte, 0x0E}, escape([]byte{0x0E}))
-	assert.Equal(t, []byte{escapeByte, 0x0C}, escape([]byte{0x0C}))
+	assert.Equal(t, []byte{escapeByte, 0x0B}, appendEscaped(nil, []byte{0x0B}))
+	assert.Equal(t, []byte{escapeByte, 0x0E}, appendEscaped(nil, []byte{0x0E}))
+	assert.Equal(t, []byte{escapeByte, 0x0C}, appendEscaped(nil, []byte{0x0C}))
 
-	assert.Equal(t, []byte{escapeByte, 0x0B, escapeByte, 0x0E, escapeByte, 0x0C}, escape([]byte{0x0B, 0x0E, 0x0C}))
+	assert.Equal(t, []byte{escapeByte, 0x0B, escapeByte, 0x0E, escapeByte, 0x0C}, appendEscaped(nil, []byte{0x0B, 0x0E, 0x0C}))
 
-	assert.Equal(t, []byte{escapeByte, 0x0C, escapeByte, 0x0C}, escape([]byte{0x0C, 0x0C}))
+	assert.Equal(t, []byte{escapeByte, 0x0C, escapeByte, 0x0C}, appendEscaped(nil, []byte{0x0C, 0x0C}))
 
 	assert.Equal(t, []byte{escapeByte, 0x0C, 0x10, escapeByte, 0x0C, 0x11, escapeByte, 0x0C, 0x12, escapeByte, 0x0C},
-		escape([]byte{0x0C, 0x10, 0x0C, 0x11, 0x0C, 0x12, 0x0C}))
+		appendEscaped(nil, []byte{0x0C, 0x10, 0x0C, 0x11, 0x0C, 0x12, 0x0C}))
+}
+
+func TestScalarSerializerAllocations(t *testing.T) {
+	for _, value := range testScalarValues() {
+		ionHasher, err := newCryptoHasher(SHA256)
+		require.NoError(t, err, "Something went wrong executing newCryptoHasher()")
+
+		ss := newScalarSerializer(ionHasher, 0, &limiter{})
+		allocs := testing.AllocsPerRun(100, func() {
+			require.NoError(t, ss.scalar(value), "Something went wrong executing ss.scalar()")
+		})
+
+		assert.Zero(t, allocs, "Expected hashing %s to not allocate", value.name)
+	}
+}
+
+func BenchmarkScalarSerializer(b *testing.B) {
+	for _, value := range testScalarValues() {
+		b.Run(value.name, func(b *testing.B) {
+			ionHasher, err := newCryptoHasher(SHA256)
+			require.NoError(b, err, "Something went wrong executing newCryptoHasher()")
+
+			ss := newScalarSerializer(ionHasher, 0, &limiter{})
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := ss.scalar(value); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// testScalar is a top-level scalar hashValue that doesn't allocate when asked for its value.
+type testScalar struct {
+	name        string
+	ionType     ion.Type
+	ionValue    interface{}
+	annotations []ion.SymbolToken
+}
+
+func (ts *testScalar) getFieldName() (*ion.SymbolToken, error) {
+	return nil, nil
+}
+
+func (ts *testScalar) getAnnotations() ([]ion.SymbolToken, error) {
+	return ts.annotations, nil
+}
+
+func (ts *testScalar) IsNull() bool {
+	return ts.ionValue == nil
+}
+
+func (ts *testScalar) Type() ion.Type {
+	return ts.ionType
+}
+
+func (ts *testScalar) value() (interface{}, error) {
+	return ts.ionValue, nil
+}
+
+func (ts *testScalar) IsInStruct() bool {
+	return false
+}
+
+func testScalarValues() []*testScalar {
+	bigInt, _ := new(big.Int).SetString("-123456789012345678901234567890", 10)
+
+	return []*testScalar{
+		{name: "null", ionType: ion.IntType},
+		{name: "bool", ionType: ion.BoolType, ionValue: true},
+		{name: "int", ionType: ion.IntType, ionValue: int64(-1234567)},
+		{name: "bigInt", ionType: ion.IntType, ionValue: bigInt},
+		{name: "float", ionType: ion.FloatType, ionValue: 1.5},
+		{name: "decimal", ionType: ion.DecimalType, ionValue: ion.MustParseDecimal("-123.4500")},
+		{name: "timestamp", ionType: ion.TimestampType, ionValue: ion.MustParseTimestamp("2020-06-15T10:20:30.123-08:00")},
+		{name: "string", ionType: ion.StringType, ionValue: "hello world"},
+		{name: "escapedString", ionType: ion.StringType, ionValue: "\x0b\x0c\x0e"},
+		{name: "symbol", ionType: ion.SymbolType, ionValue: ion.NewSymbolTokenFromString("hello")},
+		{name: "blob", ionType: ion.BlobType, ionValue: []byte{0x0B, 0x0C, 0x0E, 0x0F}},
+		{name: "annotated", ionType: ion.IntType, ionValue: int64(1000),
+			annotations: []ion.SymbolToken{ion.NewSymbolTokenFromString("a"), ion.NewSymbolTokenFromString("b")}},
+	}
 }
@@ -0,0 +1,84 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package signing
+
+import (
+	"crypto"
+	"fmt"
+	"strings"
+
+	ionhash "github.com/amzn/ion-hash-go"
+)
+
+// SignatureAlgorithm is the name of the algorithm that signs digests.
+type SignatureAlgorithm string
+
+// Constants for each of the signature algorithm names supported.
+const (
+	Ed25519 SignatureAlgorithm = "Ed25519"
+	ECDSA   SignatureAlgorithm = "ECDSA"
+	RSAPSS  SignatureAlgorithm = "RSA-PSS"
+)
+
+// An Algorithm is the hash algorithm that computes the Ion Hash of a value and the algorithm that
+// signs the digest.
+type Algorithm struct {
+	Signature SignatureAlgorithm
+	Hash      ionhash.Algorithm
+}
+
+// String returns the algorithm as it is written in envelopes, e.g. ECDSA/SHA256.
+func (a Algorithm) String() string {
+	return string(a.Signature) + "/" + string(a.Hash)
+}
+
+// ParseAlgorithm parses an algorithm written as by Algorithm.String, returning an
+// InvalidEnvelopeError if it isn't supported.
+func ParseAlgorithm(text string) (Algorithm, error) {
+	signature, hash, _ := strings.Cut(text, "/")
+	algorithm := Algorithm{SignatureAlgorithm(signature), ionhash.Algorithm(hash)}
+
+	switch algorithm.Signature {
+	case Ed25519, ECDSA, RSAPSS:
+	default:
+		return algorithm, &InvalidEnvelopeError{fmt.Sprintf("the signature algorithm of %q isn't supported", text)}
+	}
+
+	if _, ok := cryptoHashes[algorithm.Hash]; !ok {
+		return algorithm, &InvalidEnvelopeError{fmt.Sprintf("the hash algorithm of %q isn't supported", text)}
+	}
+
+	return algorithm, nil
+}
+
+// cryptoHashes maps the hash algorithms that digests may be signed with to the hashes that signers
+// are told they were computed with. Hash algorithms that are no longer collision resistant, such as
+// MD5 and SHA1, aren't supported, and neither are SHA224 and SHA384, whose digests are those of
+// SHA256 and SHA512.
+var cryptoHashes = map[ionhash.Algorithm]crypto.Hash{
+	ionhash.SHA256:     crypto.SHA256,
+	ionhash.SHA512:     crypto.SHA512,
+	ionhash.SHA512s224: crypto.SHA512_224,
+	ionhash.SHA512s256: crypto.SHA512_256,
+	ionhash.SHA3s224:   crypto.SHA3_224,
+	ionhash.SHA3s256:   crypto.SHA3_256,
+	ionhash.SHA3s384:   crypto.SHA3_384,
+	ionhash.SHA3s512:   crypto.SHA3_512,
+	ionhash.BLAKE2s256: crypto.BLAKE2s_256,
+	ionhash.BLAKE2b256: crypto.BLAKE2b_256,
+	ionhash.BLAKE2b384: crypto.BLAKE2b_384,
+	ionhash.BLAKE2b512: crypto.BLAKE2b_512,
+}
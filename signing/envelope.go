@@ -0,0 +1,226 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package signing
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/amzn/ion-go/ion"
+	"github.com/amzn/ion-hash-go/internal/ionvalue"
+)
+
+// envelopeAnnotation annotates the Ion form of an envelope.
+const envelopeAnnotation = "ion_hash_signed"
+
+// An Envelope is a signed Ion value. Its Ion form is a struct annotated with ion_hash_signed, e.g.
+//
+//	ion_hash_signed::{value:{id:1}, algorithm:"ECDSA/SHA256", key_id:"k1", signature:{{...}}}
+//
+// Only the value is signed. Algorithm and KeyID aren't, so they are hints that anyone can change:
+// check that Algorithm is one that you accept, and look up the key for KeyID among keys you trust.
+type Envelope struct {
+	// Value is the signed value, encoded as Ion binary.
+	Value     []byte
+	Algorithm Algorithm
+	KeyID     string
+	Signature []byte
+}
+
+// Write writes the envelope as Ion to writer.
+func (e *Envelope) Write(writer ion.Writer) error {
+	reader := ion.NewReaderBytes(e.Value)
+	if !reader.Next() {
+		if reader.Err() != nil {
+			return reader.Err()
+		}
+
+		return &InvalidEnvelopeError{"the envelope has no value"}
+	}
+
+	err := writer.Annotation(ion.NewSymbolTokenFromString(envelopeAnnotation))
+	if err != nil {
+		return err
+	}
+
+	err = writer.BeginStruct()
+	if err != nil {
+		return err
+	}
+
+	err = writer.FieldName(ion.NewSymbolTokenFromString("value"))
+	if err != nil {
+		return err
+	}
+
+	err = ionvalue.Copy(reader, writer)
+	if err != nil {
+		return err
+	}
+
+	err = writer.FieldName(ion.NewSymbolTokenFromString("algorithm"))
+	if err != nil {
+		return err
+	}
+
+	err = writer.WriteString(e.Algorithm.String())
+	if err != nil {
+		return err
+	}
+
+	err = writer.FieldName(ion.NewSymbolTokenFromString("key_id"))
+	if err != nil {
+		return err
+	}
+
+	err = writer.WriteString(e.KeyID)
+	if err != nil {
+		return err
+	}
+
+	err = writer.FieldName(ion.NewSymbolTokenFromString("signature"))
+	if err != nil {
+		return err
+	}
+
+	err = writer.WriteBlob(e.Signature)
+	if err != nil {
+		return err
+	}
+
+	return writer.EndStruct()
+}
+
+// ReadEnvelope reads an envelope written as Ion, e.g. by Envelope.Write, from the next top-level
+// value of reader. It returns an InvalidEnvelopeError if the envelope has more than one value,
+// algorithm, key_id or signature.
+func ReadEnvelope(reader ion.Reader) (*Envelope, error) {
+	if !reader.Next() {
+		if reader.Err() != nil {
+			return nil, reader.Err()
+		}
+
+		return nil, &InvalidEnvelopeError{"there is no envelope"}
+	}
+
+	annotations, err := reader.Annotations()
+	if err != nil {
+		return nil, err
+	}
+
+	if reader.Type() != ion.StructType || reader.IsNull() || len(annotations) != 1 ||
+		annotations[0].Text == nil || *annotations[0].Text != envelopeAnnotation {
+		return nil, &InvalidEnvelopeError{"an envelope is a struct annotated with " + envelopeAnnotation}
+	}
+
+	err = reader.StepIn()
+	if err != nil {
+		return nil, err
+	}
+
+	envelope := &Envelope{}
+	seen := map[string]bool{}
+	for reader.Next() {
+		fieldName, err := reader.FieldName()
+		if err != nil {
+			return nil, err
+		}
+		if fieldName == nil || fieldName.Text == nil {
+			continue
+		}
+
+		switch *fieldName.Text {
+		case "value", "algorithm", "key_id", "signature":
+			if seen[*fieldName.Text] {
+				return nil, &InvalidEnvelopeError{fmt.Sprintf("an envelope has more than one %s", *fieldName.Text)}
+			}
+
+			seen[*fieldName.Text] = true
+		}
+
+		switch *fieldName.Text {
+		case "value":
+			var buf bytes.Buffer
+			writer := ion.NewBinaryWriter(&buf)
+			err = ionvalue.Copy(reader, writer)
+			if err != nil {
+				return nil, err
+			}
+
+			err = writer.Finish()
+			if err != nil {
+				return nil, err
+			}
+
+			envelope.Value = buf.Bytes()
+		case "algorithm":
+			text, err := readString(reader, "algorithm")
+			if err != nil {
+				return nil, err
+			}
+
+			envelope.Algorithm, err = ParseAlgorithm(text)
+			if err != nil {
+				return nil, err
+			}
+
+		case "key_id":
+			envelope.KeyID, err = readString(reader, "key_id")
+			if err != nil {
+				return nil, err
+			}
+
+		case "signature":
+			if reader.Type() != ion.BlobType || reader.IsNull() {
+				return nil, &InvalidEnvelopeError{"an envelope's signature is not a blob"}
+			}
+
+			envelope.Signature, err = reader.ByteValue()
+			if err != nil {
+				return nil, err
+			}
+
+		}
+	}
+
+	if reader.Err() != nil {
+		return nil, reader.Err()
+	}
+
+	err = reader.StepOut()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(seen) != 4 {
+		return nil, &InvalidEnvelopeError{"an envelope has a value, algorithm, key_id and signature"}
+	}
+
+	return envelope, nil
+}
+
+func readString(reader ion.Reader, fieldName string) (string, error) {
+	if reader.Type() != ion.StringType || reader.IsNull() {
+		return "", &InvalidEnvelopeError{fmt.Sprintf("an envelope's %s is not a string", fieldName)}
+	}
+
+	text, err := reader.StringValue()
+	if err != nil {
+		return "", err
+	}
+
+	return *text, nil
+}
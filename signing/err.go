@@ -0,0 +1,58 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package signing
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors that the errors returned by this package match with errors.Is.
+var (
+	ErrInvalidEnvelope  = errors.New("signing: invalid envelope")
+	ErrInvalidSignature = errors.New("signing: invalid signature")
+)
+
+// An InvalidEnvelopeError is returned when an envelope is malformed, or uses an algorithm that isn't
+// supported.
+type InvalidEnvelopeError struct {
+	Message string
+}
+
+func (e *InvalidEnvelopeError) Error() string {
+	return fmt.Sprintf("signing: Invalid envelope: %s", e.Message)
+}
+
+// Is reports whether target is ErrInvalidEnvelope.
+func (e *InvalidEnvelopeError) Is(target error) bool {
+	return target == ErrInvalidEnvelope
+}
+
+// An InvalidSignatureError is returned when an envelope's signature is not that of its value by the
+// key that it is verified with.
+type InvalidSignatureError struct {
+	KeyID   string
+	Message string
+}
+
+func (e *InvalidSignatureError) Error() string {
+	return fmt.Sprintf("signing: Invalid signature by key %q: %s", e.KeyID, e.Message)
+}
+
+// Is reports whether target is ErrInvalidSignature.
+func (e *InvalidSignatureError) Is(target error) bool {
+	return target == ErrInvalidSignature
+}
@@ -0,0 +1,159 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+// Package signing signs Ion values, using the Ion Hash of a value as the digest that is signed.
+// Because the Ion Hash of a value doesn't depend on its encoding, or on the order of the fields of
+// its structs, a signature still verifies after the value is re-encoded, e.g. as Ion text, or its
+// fields are reordered.
+package signing
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+
+	"github.com/amzn/ion-go/ion"
+	ionhash "github.com/amzn/ion-hash-go"
+	"github.com/amzn/ion-hash-go/internal/ionvalue"
+)
+
+// Sign signs the next top-level value of reader with signer, which must hold an Ed25519, ECDSA or
+// RSA key. The value is hashed with hash, and the digest is signed with Ed25519, ECDSA or RSA-PSS
+// according to the key. keyID identifies the key to those verifying the signature.
+func Sign(reader ion.Reader, signer crypto.Signer, keyID string, hash ionhash.Algorithm) (*Envelope, error) {
+	cryptoHash, ok := cryptoHashes[hash]
+	if !ok {
+		return nil, &ionhash.InvalidArgumentError{ArgumentName: "hash", ArgumentValue: hash}
+	}
+
+	algorithm := Algorithm{Hash: hash}
+	var opts crypto.SignerOpts
+	switch signer.Public().(type) {
+	case ed25519.PublicKey:
+		// Ed25519 signs the digest as a message, rather than signing a prehashed message.
+		algorithm.Signature = Ed25519
+		opts = crypto.Hash(0)
+	case *ecdsa.PublicKey:
+		algorithm.Signature = ECDSA
+		opts = cryptoHash
+	case *rsa.PublicKey:
+		algorithm.Signature = RSAPSS
+		opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: cryptoHash}
+	default:
+		return nil, &ionhash.InvalidArgumentError{ArgumentName: "signer", ArgumentValue: signer}
+	}
+
+	if !reader.Next() {
+		if reader.Err() != nil {
+			return nil, reader.Err()
+		}
+
+		return nil, &ionhash.InvalidOperationError{
+			StructName: "signing", MethodName: "Sign", Message: "There is no value to sign"}
+	}
+
+	var buf bytes.Buffer
+	writer := ion.NewBinaryWriter(&buf)
+	err := ionvalue.Copy(reader, writer)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writer.Finish()
+	if err != nil {
+		return nil, err
+	}
+
+	envelope := &Envelope{Value: buf.Bytes(), Algorithm: algorithm, KeyID: keyID}
+	digest, err := envelope.digest()
+	if err != nil {
+		return nil, err
+	}
+
+	envelope.Signature, err = signer.Sign(rand.Reader, digest, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return envelope, nil
+}
+
+// Verify recomputes the digest of envelope's value and verifies that envelope's signature is that
+// of the digest by publicKey, returning an InvalidSignatureError if it isn't.
+func Verify(envelope *Envelope, publicKey crypto.PublicKey) error {
+	algorithm, err := ParseAlgorithm(envelope.Algorithm.String())
+	if err != nil {
+		return err
+	}
+
+	digest, err := envelope.digest()
+	if err != nil {
+		return err
+	}
+
+	var verified bool
+	switch key := publicKey.(type) {
+	case ed25519.PublicKey:
+		if algorithm.Signature != Ed25519 {
+			break
+		}
+
+		if len(key) != ed25519.PublicKeySize {
+			// ed25519.Verify panics given a key of the wrong size.
+			return &InvalidSignatureError{envelope.KeyID, fmt.Sprintf("an Ed25519 key has %d bytes rather than %d", len(key), ed25519.PublicKeySize)}
+		}
+
+		verified = ed25519.Verify(key, digest, envelope.Signature)
+	case *ecdsa.PublicKey:
+		if algorithm.Signature != ECDSA {
+			break
+		}
+
+		verified = ecdsa.VerifyASN1(key, digest, envelope.Signature)
+	case *rsa.PublicKey:
+		if algorithm.Signature != RSAPSS {
+			break
+		}
+
+		opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto, Hash: cryptoHashes[algorithm.Hash]}
+		verified = rsa.VerifyPSS(key, opts.Hash, digest, envelope.Signature, opts) == nil
+	default:
+		return &InvalidSignatureError{envelope.KeyID, fmt.Sprintf("keys of type %T aren't supported", publicKey)}
+	}
+
+	if !verified {
+		return &InvalidSignatureError{envelope.KeyID, fmt.Sprintf("the signature isn't that of the value with %s", algorithm)}
+	}
+
+	return nil
+}
+
+// digest returns the Ion Hash of e's value, which must be exactly one value.
+func (e *Envelope) digest() ([]byte, error) {
+	digests, err := ionhash.HashBinary(e.Value, ionhash.NewCryptoHasherProvider(e.Algorithm.Hash))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(digests) != 1 {
+		return nil, &InvalidEnvelopeError{fmt.Sprintf("an envelope holds one value, not %d", len(digests))}
+	}
+
+	return digests[0], nil
+}
@@ -0,0 +1,212 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package signing
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/amzn/ion-go/ion"
+	ionhash "github.com/amzn/ion-hash-go"
+	"github.com/amzn/ion-hash-go/internal/ionvalue"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testKeys returns a signer for each kind of key supported.
+func testKeys(t *testing.T) map[SignatureAlgorithm]crypto.Signer {
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	return map[SignatureAlgorithm]crypto.Signer{Ed25519: ed25519Key, ECDSA: ecdsaKey, RSAPSS: rsaKey}
+}
+
+// sign returns the envelope of the value of text, after it is written as Ion text and read again.
+func sign(t *testing.T, text string, signer crypto.Signer, hash ionhash.Algorithm) *Envelope {
+	envelope, err := Sign(ion.NewReaderString(text), signer, "k1", hash)
+	require.NoError(t, err, "Something went wrong executing Sign()")
+
+	var buf strings.Builder
+	writer := ion.NewTextWriter(&buf)
+	require.NoError(t, envelope.Write(writer))
+	require.NoError(t, writer.Finish())
+
+	read, err := ReadEnvelope(ion.NewReaderString(buf.String()))
+	require.NoError(t, err, "Something went wrong executing ReadEnvelope() on %s", buf.String())
+	assert.Equal(t, envelope.Algorithm, read.Algorithm)
+	assert.Equal(t, envelope.KeyID, read.KeyID)
+	assert.Equal(t, envelope.Signature, read.Signature)
+	return read
+}
+
+func TestSignAndVerify(t *testing.T) {
+	texts := []string{
+		`{id:1, name:"x", tags:[a, b], at:2020-01-01T}`,
+		`a::b::(c {d:1.5} {{aGVsbG8=}} {{"clob"}} null.int null 2e0 true)`,
+		`"just a string"`,
+	}
+
+	for algorithm, signer := range testKeys(t) {
+		hashes := []ionhash.Algorithm{ionhash.SHA256}
+		if algorithm != Ed25519 {
+			hashes = append(hashes, ionhash.SHA512, ionhash.SHA3s256, ionhash.BLAKE2b512)
+		}
+
+		for _, hash := range hashes {
+			for _, text := range texts {
+				t.Run(string(algorithm)+"/"+string(hash)+" "+text, func(t *testing.T) {
+					envelope := sign(t, text, signer, hash)
+					assert.Equal(t, Algorithm{algorithm, hash}, envelope.Algorithm)
+					assert.NoError(t, Verify(envelope, signer.Public()))
+				})
+			}
+		}
+	}
+}
+
+func TestVerifyReorderedFields(t *testing.T) {
+	signer := testKeys(t)[ECDSA]
+	envelope := sign(t, `{id:1, name:"x", nested:{a:1, b:2}}`, signer, ionhash.SHA256)
+
+	reordered, err := Sign(ion.NewReaderString(`{nested:{b:2, a:1}, name:"x", id:1}`), signer, "k1", ionhash.SHA256)
+	require.NoError(t, err, "Something went wrong executing Sign()")
+
+	// The reordered value has the same digest, so the signature of the original value is also its.
+	reordered.Signature = envelope.Signature
+	assert.NoError(t, Verify(reordered, signer.Public()))
+}
+
+func TestVerifyFails(t *testing.T) {
+	keys := testKeys(t)
+	for algorithm, signer := range keys {
+		t.Run(string(algorithm), func(t *testing.T) {
+			envelope := sign(t, `{amount:100, to:"alice"}`, signer, ionhash.SHA256)
+
+			tampered := *envelope
+			tampered.Value = toBinary(t, `{amount:1000, to:"alice"}`)
+			err := Verify(&tampered, signer.Public())
+			assert.True(t, errors.Is(err, ErrInvalidSignature), "Expected an InvalidSignatureError, got %v", err)
+
+			tampered = *envelope
+			tampered.Signature = append([]byte(nil), envelope.Signature...)
+			tampered.Signature[0] ^= 1
+			err = Verify(&tampered, signer.Public())
+			assert.True(t, errors.Is(err, ErrInvalidSignature), "Expected an InvalidSignatureError, got %v", err)
+
+			for other, otherSigner := range keys {
+				if other != algorithm {
+					err = Verify(envelope, otherSigner.Public())
+					var invalidSignature *InvalidSignatureError
+					require.True(t, errors.As(err, &invalidSignature), "Expected an InvalidSignatureError, got %v", err)
+					assert.Equal(t, "k1", invalidSignature.KeyID)
+				}
+			}
+
+			tampered = *envelope
+			tampered.Value = toBinary(t, `1 2`)
+			err = Verify(&tampered, signer.Public())
+			assert.True(t, errors.Is(err, ErrInvalidEnvelope), "Expected an InvalidEnvelopeError, got %v", err)
+		})
+	}
+}
+
+func TestVerifyEd25519KeySize(t *testing.T) {
+	signer := testKeys(t)[Ed25519]
+	envelope := sign(t, `{id:1}`, signer, ionhash.SHA256)
+	key := signer.Public().(ed25519.PublicKey)
+
+	for _, wrongSize := range []ed25519.PublicKey{nil, key[:ed25519.PublicKeySize-1], append(append(ed25519.PublicKey(nil), key...), 0)} {
+		err := Verify(envelope, wrongSize)
+		var invalidSignature *InvalidSignatureError
+		require.True(t, errors.As(err, &invalidSignature), "Expected an InvalidSignatureError for a key of %d bytes, got %v", len(wrongSize), err)
+		assert.Equal(t, "k1", invalidSignature.KeyID)
+	}
+}
+
+func TestSignErrors(t *testing.T) {
+	signer := testKeys(t)[Ed25519]
+
+	for _, hash := range []ionhash.Algorithm{ionhash.MD5, ionhash.SHA1, ionhash.SHA384, "SHA0"} {
+		_, err := Sign(ion.NewReaderString(`1`), signer, "k1", hash)
+		assert.True(t, errors.Is(err, ionhash.ErrInvalidArgument), "Expected an InvalidArgumentError for %s, got %v", hash, err)
+	}
+
+	_, err := Sign(ion.NewReaderString(``), signer, "k1", ionhash.SHA256)
+	assert.True(t, errors.Is(err, ionhash.ErrInvalidOperation), "Expected an InvalidOperationError, got %v", err)
+}
+
+func TestReadEnvelopeInvalid(t *testing.T) {
+	testCases := []string{
+		``,
+		`1`,
+		`{value:1, algorithm:"ECDSA/SHA256", key_id:"k1", signature:{{}}}`,
+		`ion_hash_signed::[1]`,
+		`ion_hash_signed::{algorithm:"ECDSA/SHA256", key_id:"k1", signature:{{}}}`,
+		`ion_hash_signed::{value:1, algorithm:"ECDSA/MD5", key_id:"k1", signature:{{}}}`,
+		`ion_hash_signed::{value:1, algorithm:"DSA/SHA256", key_id:"k1", signature:{{}}}`,
+		`ion_hash_signed::{value:1, algorithm:'ECDSA/SHA256', key_id:"k1", signature:{{}}}`,
+		`ion_hash_signed::{value:1, algorithm:"ECDSA/SHA256", key_id:null.string, signature:{{}}}`,
+		`ion_hash_signed::{value:1, algorithm:"ECDSA/SHA256", key_id:"k1", signature:"x"}`,
+		`ion_hash_signed::{value:1, value:2, algorithm:"ECDSA/SHA256", key_id:"k1", signature:{{}}}`,
+		`ion_hash_signed::{value:1, algorithm:"ECDSA/SHA256", algorithm:"ECDSA/SHA512", key_id:"k1", signature:{{}}}`,
+		`ion_hash_signed::{value:1, algorithm:"ECDSA/SHA256", key_id:"k1", key_id:"k2", signature:{{}}}`,
+		`ion_hash_signed::{value:1, algorithm:"ECDSA/SHA256", key_id:"k1", signature:{{}}, signature:{{}}}`,
+	}
+
+	for _, tc := range testCases {
+		_, err := ReadEnvelope(ion.NewReaderString(tc))
+		assert.True(t, errors.Is(err, ErrInvalidEnvelope), "Expected an InvalidEnvelopeError reading %s, got %v", tc, err)
+	}
+}
+
+func TestParseAlgorithm(t *testing.T) {
+	algorithm, err := ParseAlgorithm("RSA-PSS/SHA3_256")
+	require.NoError(t, err)
+	assert.Equal(t, Algorithm{RSAPSS, ionhash.SHA3s256}, algorithm)
+	assert.Equal(t, "RSA-PSS/SHA3_256", algorithm.String())
+
+	for _, text := range []string{"", "Ed25519", "Ed25519/", "/SHA256", "ECDSA/SHA1", "ecdsa/SHA256"} {
+		_, err = ParseAlgorithm(text)
+		assert.True(t, errors.Is(err, ErrInvalidEnvelope), "Expected an InvalidEnvelopeError parsing %q, got %v", text, err)
+	}
+}
+
+// toBinary returns the values of text encoded as Ion binary.
+func toBinary(t *testing.T, text string) []byte {
+	var buf bytes.Buffer
+	writer := ion.NewBinaryWriter(&buf)
+	reader := ion.NewReaderString(text)
+	for reader.Next() {
+		require.NoError(t, ionvalue.Copy(reader, writer))
+	}
+	require.NoError(t, reader.Err())
+	require.NoError(t, writer.Finish())
+	return buf.Bytes()
+}
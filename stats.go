@@ -0,0 +1,156 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/amzn/ion-go/ion"
+)
+
+// Stats collects statistics about hashing from the HashReaders, HashWriters and BinaryHashReaders
+// given it with WithStats. It is safe for concurrent use, so one Stats can be shared by many of them,
+// e.g.,
+//
+//	stats := &ionhash.Stats{}
+//	hr, err := ionhash.NewHashReader(reader, hasherProvider, ionhash.WithStats(stats))
+//	// ...
+//	snapshot := stats.Snapshot()
+//	fmt.Println(snapshot.BytesHashed, snapshot.Values[ion.StructType])
+type Stats struct {
+	bytesHashed     atomic.Int64
+	values          [ion.StructType + 1]atomic.Int64
+	containers      atomic.Int64
+	maxDepth        atomic.Int64
+	structFields    atomic.Int64
+	maxStructFields atomic.Int64
+	duration        atomic.Int64
+}
+
+// A StatsSnapshot holds the statistics collected by a Stats at a point in time.
+type StatsSnapshot struct {
+	// BytesHashed is the number of bytes written to the IonHashers.
+	BytesHashed int64
+
+	// Values is the number of values hashed of each type, including values nested in containers.
+	Values map[ion.Type]int64
+
+	// Containers is the number of non-null lists, sexps and structs hashed.
+	Containers int64
+
+	// MaxDepth is the deepest that containers have been nested, where a top-level container
+	// has a depth of one.
+	MaxDepth int
+
+	// StructFields is the number of fields in the structs hashed, and MaxStructFields is the
+	// most fields that any one of them had.
+	StructFields    int64
+	MaxStructFields int
+
+	// Duration is the time spent hashing values once they have been reached. The scalars of a
+	// HashReader or BinaryHashReader are decoded as they are hashed, so their decoding is included,
+	// but the time spent moving from one value to the next, or writing values, isn't.
+	Duration time.Duration
+}
+
+// Snapshot returns the statistics collected so far.
+func (s *Stats) Snapshot() StatsSnapshot {
+	values := make(map[ion.Type]int64)
+	for ionType := range s.values {
+		if count := s.values[ionType].Load(); count > 0 {
+			values[ion.Type(ionType)] = count
+		}
+	}
+
+	return StatsSnapshot{
+		BytesHashed:     s.bytesHashed.Load(),
+		Values:          values,
+		Containers:      s.containers.Load(),
+		MaxDepth:        int(s.maxDepth.Load()),
+		StructFields:    s.structFields.Load(),
+		MaxStructFields: int(s.maxStructFields.Load()),
+		Duration:        time.Duration(s.duration.Load()),
+	}
+}
+
+// Reset sets all of the statistics back to zero.
+func (s *Stats) Reset() {
+	s.bytesHashed.Store(0)
+	for i := range s.values {
+		s.values[i].Store(0)
+	}
+	s.containers.Store(0)
+	s.maxDepth.Store(0)
+	s.structFields.Store(0)
+	s.maxStructFields.Store(0)
+	s.duration.Store(0)
+}
+
+func (s *Stats) value(ionType ion.Type) {
+	if ionType >= 0 && int(ionType) < len(s.values) {
+		s.values[ionType].Add(1)
+	}
+}
+
+func (s *Stats) container(depth int) {
+	s.containers.Add(1)
+	storeMax(&s.maxDepth, int64(depth))
+}
+
+func (s *Stats) structEnd(fields int) {
+	s.structFields.Add(int64(fields))
+	storeMax(&s.maxStructFields, int64(fields))
+}
+
+func (s *Stats) since(start time.Time) {
+	s.duration.Add(int64(time.Since(start)))
+}
+
+// storeMax stores val in max if it is greater than the value already there.
+func storeMax(max *atomic.Int64, val int64) {
+	for {
+		current := max.Load()
+		if val <= current || max.CompareAndSwap(current, val) {
+			return
+		}
+	}
+}
+
+// statsHasherProvider provides IonHashers that count the bytes written to them.
+type statsHasherProvider struct {
+	hasherProvider IonHasherProvider
+	stats          *Stats
+}
+
+func (shp *statsHasherProvider) NewHasher() (IonHasher, error) {
+	ionHasher, err := shp.hasherProvider.NewHasher()
+	if err != nil {
+		return nil, err
+	}
+
+	return &statsHasher{ionHasher, shp.stats}, nil
+}
+
+type statsHasher struct {
+	IonHasher
+	stats *Stats
+}
+
+func (sh *statsHasher) Write(b []byte) (int, error) {
+	sh.stats.bytesHashed.Add(int64(len(b)))
+	return sh.IonHasher.Write(b)
+}
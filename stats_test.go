@@ -0,0 +1,125 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/amzn/ion-go/ion"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const statsText = `{a:1, b:[2, "three", {c:4e0, d:null.list}], e:(f g)} 5`
+
+func assertStats(t *testing.T, snapshot StatsSnapshot) {
+	expectedValues := map[ion.Type]int64{
+		ion.IntType:    3,
+		ion.FloatType:  1,
+		ion.StringType: 1,
+		ion.SymbolType: 2,
+		ion.ListType:   2,
+		ion.SexpType:   1,
+		ion.StructType: 2,
+	}
+
+	assert.Equal(t, expectedValues, snapshot.Values)
+	assert.Equal(t, int64(4), snapshot.Containers)
+	assert.Equal(t, 3, snapshot.MaxDepth)
+	assert.Equal(t, int64(5), snapshot.StructFields)
+	assert.Equal(t, 3, snapshot.MaxStructFields)
+	assert.True(t, snapshot.Duration > 0, "Expected the time spent hashing to be recorded")
+}
+
+func TestStatsHashReader(t *testing.T) {
+	stats := &Stats{}
+	tihp := newTestIonHasherProvider("identity")
+	ionHashReader, err := NewHashReader(ion.NewReaderString(statsText), tihp.getInstance(), WithStats(stats))
+	require.NoError(t, err, "Expected NewHashReader() to successfully create a HashReader")
+
+	bytesHashed := 0
+	for ionHashReader.Next() {
+		sum, err := ionHashReader.Sum(nil)
+		require.NoError(t, err, "Something went wrong executing ionHashReader.Sum(nil)")
+		bytesHashed += len(sum)
+	}
+	require.NoError(t, ionHashReader.Err(), "Something went wrong executing ionHashReader.Next()")
+
+	// The last value's sum is only available after the final call to Next.
+	sum, err := ionHashReader.Sum(nil)
+	require.NoError(t, err, "Something went wrong executing ionHashReader.Sum(nil)")
+	bytesHashed += len(sum)
+
+	snapshot := stats.Snapshot()
+	assertStats(t, snapshot)
+
+	// The identity hasher's sum is everything written to it, but struct fields are hashed
+	// by their own hashers before being written to their struct's hasher.
+	assert.True(t, snapshot.BytesHashed > int64(bytesHashed),
+		"Expected BytesHashed to include the bytes hashed for struct fields")
+}
+
+func TestStatsHashWriter(t *testing.T) {
+	stats := &Stats{}
+	ionHashWriter, err := NewHashWriter(ion.NewTextWriter(&bytes.Buffer{}), NewCryptoHasherProvider(SHA256),
+		WithStats(stats))
+	require.NoError(t, err, "Expected NewHashWriter() to successfully create a HashWriter")
+
+	reader := ion.NewReaderString(statsText)
+	for reader.Next() {
+		require.NoError(t, writeValue(reader, ionHashWriter))
+	}
+	require.NoError(t, reader.Err())
+
+	snapshot := stats.Snapshot()
+	assertStats(t, snapshot)
+	assert.True(t, snapshot.BytesHashed > 0, "Expected BytesHashed to be recorded")
+}
+
+func TestStatsBinaryHashReader(t *testing.T) {
+	stats := &Stats{}
+	_, err := HashBinary(toBinary(t, statsText), NewCryptoHasherProvider(SHA256), WithStats(stats))
+	require.NoError(t, err, "Expected HashBinary() to succeed")
+
+	snapshot := stats.Snapshot()
+	assertStats(t, snapshot)
+	assert.True(t, snapshot.BytesHashed > 0, "Expected BytesHashed to be recorded")
+}
+
+func TestStatsShared(t *testing.T) {
+	stats := &Stats{}
+	data := toBinary(t, statsText)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := HashBinary(data, NewCryptoHasherProvider(SHA256), WithStats(stats))
+			assert.NoError(t, err, "Expected HashBinary() to succeed")
+		}()
+	}
+	wg.Wait()
+
+	snapshot := stats.Snapshot()
+	assert.Equal(t, int64(16), snapshot.Containers)
+	assert.Equal(t, 3, snapshot.MaxDepth)
+
+	stats.Reset()
+	assert.Equal(t, StatsSnapshot{Values: map[ion.Type]int64{}}, stats.Snapshot())
+}
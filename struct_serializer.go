@@ -19,22 +19,31 @@ import (
 	"sort"
 )
 
+// structSerializer hashes each value in a container separately and serializes the container as
+// the sorted digests of its values, so that its hash doesn't depend on the order of its values.
+// It serializes structs, and lists and sexps that are hashed as unordered.
 type structSerializer struct {
 	baseSerializer
 
 	scalarSerializer serializer
 	fieldHashes      [][]byte
+
+	// ordered, if set, serializes the digests in the order that the values were hashed rather than
+	// sorted. This is outside the Ion Hash specification.
+	ordered bool
 }
 
-func newStructSerializer(hashFunction IonHasher, depth int, hashFunctionProvider IonHasherProvider) (serializer, error) {
+func newStructSerializer(hashFunction IonHasher, depth int, hashFunctionProvider IonHasherProvider,
+	limiter *limiter, ordered bool) (serializer, error) {
 	newHasher, err := hashFunctionProvider.NewHasher()
 	if err != nil {
 		return nil, err
 	}
 
 	return &structSerializer{
-		baseSerializer:   baseSerializer{hashFunction: hashFunction, depth: depth},
-		scalarSerializer: newScalarSerializer(newHasher, depth+1)}, nil
+		baseSerializer:   baseSerializer{hashFunction: hashFunction, depth: depth, limiter: limiter},
+		scalarSerializer: newScalarSerializer(newHasher, depth+1, limiter),
+		ordered:          ordered}, nil
 }
 
 func (ss *structSerializer) scalar(ionValue hashValue) error {
@@ -55,11 +64,13 @@ func (ss *structSerializer) scalar(ionValue hashValue) error {
 }
 
 func (ss *structSerializer) stepOut() error {
-	// Sort fieldHashes using the sortableBytes sorting interface.
-	sort.Sort(sortableBytes(ss.fieldHashes))
+	if !ss.ordered {
+		// Sort fieldHashes using the sortableBytes sorting interface.
+		sort.Sort(sortableBytes(ss.fieldHashes))
+	}
 
 	for _, digest := range ss.fieldHashes {
-		err := ss.write(escape(digest))
+		err := ss.writeEscaped(digest)
 		if err != nil {
 			return err
 		}
@@ -76,3 +87,8 @@ func (ss *structSerializer) handleAnnotationsBegin(ionValue hashValue) error {
 func (ss *structSerializer) appendFieldHash(sum []byte) {
 	ss.fieldHashes = append(ss.fieldHashes, sum)
 }
+
+// appendRedacted appends the digest of a redacted field, which is held by its placeholder.
+func (ss *structSerializer) appendRedacted(digest []byte) {
+	ss.appendFieldHash(append([]byte(nil), digest...))
+}
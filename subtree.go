@@ -0,0 +1,198 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+// A SubtreeDigest is the digest of a value within a top-level value whose path matches one of the
+// paths given with WithSubtrees. The digest is the value's Ion Hash as though it were a top-level
+// value, so it doesn't include the value's field name.
+type SubtreeDigest struct {
+	// Path is the path of the value within the top-level value, e.g. payload.body or items[3].
+	Path string
+
+	// Digest is the value's digest.
+	Digest []byte
+}
+
+// subtree is a value whose digest is being computed by a hasher of its own, which is given the same
+// values as the hasher of the top-level value that it is in.
+type subtree struct {
+	hasher *hasher
+	root   subtreeRoot
+
+	// level is the depth of the top-level value's hasher within the subtree, and digest is the
+	// index of the subtree's SubtreeDigest.
+	level  int
+	digest int
+}
+
+// subtreeRoot is the value at the root of a subtree, which is hashed without its field name.
+type subtreeRoot struct {
+	hashValue
+}
+
+func (sr *subtreeRoot) IsInStruct() bool {
+	return false
+}
+
+// subtreeOptions returns the options of the hashers of subtrees, which hash values as opts do but
+// neither enforce limits, collect statistics nor transform scalars, as the hasher of the top-level
+// value does all three.
+func subtreeOptions(opts options) options {
+	opts.limits = Limits{}
+	opts.transformers = nil
+	opts.ctx = nil
+	opts.stats = nil
+	opts.keySpecs = nil
+	opts.subtreePaths = nil
+	return opts
+}
+
+// beginSubtrees forgets the subtrees of the previous top-level value.
+func (h *hasher) beginSubtrees() {
+	// Subtrees that are still being hashed were left behind by an error, so aren't reused.
+	h.subtrees = h.subtrees[:0]
+	h.subtreeSums = nil
+}
+
+// scalarSubtrees gives the scalar that has just been hashed to the hashers of the subtrees that it
+// is in, and computes its digest if it is a subtree itself.
+func (h *hasher) scalarSubtrees(ionValue hashValue) error {
+	for _, st := range h.subtrees {
+		err := st.hasher.scalar(ionValue)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !matchesAny(h.subtreePatterns, h.path) {
+		return nil
+	}
+
+	excluded, err := h.isExcluded(ionValue)
+	if err != nil || excluded {
+		return err
+	}
+
+	st, err := h.newSubtree(ionValue, len(h.path))
+	if err != nil {
+		return err
+	}
+
+	err = st.hasher.scalar(&st.root)
+	if err != nil {
+		return err
+	}
+
+	return h.endSubtree(st)
+}
+
+// stepInSubtrees gives the container that has just been stepped in to to the hashers of the
+// subtrees that it is in, and begins a subtree if it is one.
+func (h *hasher) stepInSubtrees(ionValue hashValue) error {
+	for _, st := range h.subtrees {
+		err := st.hasher.stepIn(ionValue)
+		if err != nil {
+			return err
+		}
+	}
+
+	if h.excluding > 0 || !matchesAny(h.subtreePatterns, h.path[:len(h.path)-1]) {
+		return nil
+	}
+
+	st, err := h.newSubtree(ionValue, len(h.path)-1)
+	if err != nil {
+		return err
+	}
+
+	err = st.hasher.stepIn(&st.root)
+	if err != nil {
+		return err
+	}
+
+	st.level = len(h.path)
+	h.subtrees = append(h.subtrees, st)
+	return nil
+}
+
+// stepOutSubtrees steps the hashers of the subtrees that the container that has just been stepped
+// out of is in out of it, and computes the digest of the subtree that it is, if any.
+func (h *hasher) stepOutSubtrees() error {
+	for _, st := range h.subtrees {
+		err := st.hasher.stepOut()
+		if err != nil {
+			return err
+		}
+	}
+
+	if n := len(h.subtrees); n > 0 && h.subtrees[n-1].level > len(h.path) {
+		st := h.subtrees[n-1]
+		h.subtrees[n-1] = nil
+		h.subtrees = h.subtrees[:n-1]
+		return h.endSubtree(st)
+	}
+
+	return nil
+}
+
+// newSubtree returns a subtree for ionValue, whose position is the given number of levels of the path.
+func (h *hasher) newSubtree(ionValue hashValue, levels int) (*subtree, error) {
+	var st *subtree
+	if n := len(h.freeSubtrees); n > 0 {
+		st = h.freeSubtrees[n-1]
+		h.freeSubtrees = h.freeSubtrees[:n-1]
+	} else {
+		subtreeHasher, err := newHasher(h.subtreeProvider, h.subtreeOptions)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(h.transformers) > 0 {
+			subtreeHasher.transformedBy = h
+		}
+
+		st = &subtree{hasher: subtreeHasher}
+	}
+
+	st.hasher.pathPrefix = append(st.hasher.pathPrefix[:0], h.path[:levels]...)
+	st.root.hashValue = ionValue
+	st.digest = len(h.subtreeSums)
+	h.subtreeSums = append(h.subtreeSums, SubtreeDigest{Path: h.pathString(levels)})
+	return st, nil
+}
+
+// endSubtree records the digest of a subtree that has been hashed.
+func (h *hasher) endSubtree(st *subtree) error {
+	digest, err := st.hasher.sum(nil)
+	if err != nil {
+		return err
+	}
+
+	h.subtreeSums[st.digest].Digest = digest
+	st.root.hashValue = nil
+	h.freeSubtrees = append(h.freeSubtrees, st)
+	return nil
+}
+
+// subtreeDigests returns the digests of the subtrees of the last top-level value hashed.
+func (h *hasher) subtreeDigests() ([]SubtreeDigest, error) {
+	if h.depth() != 0 {
+		return nil, &InvalidOperationError{
+			"hasher", "subtreeDigests", "Subtree digests may only be provided at the same depth hashing started"}
+	}
+
+	return h.subtreeSums, nil
+}
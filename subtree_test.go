@@ -0,0 +1,181 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/amzn/ion-go/ion"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// subtreeDigests returns the subtree digests of each top-level value in text, failing unless a
+// HashReader, a HashWriter and a BinaryHashReader agree on them.
+func subtreeDigests(t *testing.T, text string, opts ...Option) [][]SubtreeDigest {
+	hasherProvider := NewCryptoHasherProvider(SHA256)
+
+	ionHashReader, err := NewHashReader(ion.NewReaderString(text), hasherProvider, opts...)
+	require.NoError(t, err, "Expected NewHashReader() to successfully create a HashReader")
+
+	var readerDigests [][]SubtreeDigest
+	for ionHashReader.Next() {
+		digests, err := ionHashReader.SubtreeDigests()
+		require.NoError(t, err, "Something went wrong executing ionHashReader.SubtreeDigests()")
+		readerDigests = append(readerDigests, digests)
+	}
+	require.NoError(t, ionHashReader.Err(), "Something went wrong executing ionHashReader.Next()")
+
+	// A HashReader's subtree digests are those of the value before the current one.
+	digests, err := ionHashReader.SubtreeDigests()
+	require.NoError(t, err, "Something went wrong executing ionHashReader.SubtreeDigests()")
+	readerDigests = append(readerDigests[1:], digests)
+
+	ionHashWriter, err := NewHashWriter(ion.NewBinaryWriter(&bytes.Buffer{}), hasherProvider, opts...)
+	require.NoError(t, err, "Expected NewHashWriter() to successfully create a HashWriter")
+
+	binaryHashReader, err := NewBinaryHashReader(toBinary(t, text), hasherProvider, opts...)
+	require.NoError(t, err, "Expected NewBinaryHashReader() to successfully create a BinaryHashReader")
+
+	reader := ion.NewReaderString(text)
+	for i := 0; reader.Next(); i++ {
+		require.NoError(t, writeValue(reader, ionHashWriter))
+		digests, err := ionHashWriter.SubtreeDigests()
+		require.NoError(t, err, "Something went wrong executing ionHashWriter.SubtreeDigests()")
+		assert.Equal(t, readerDigests[i], digests, "HashWriter subtree digests did not match HashReader subtree digests")
+
+		require.True(t, binaryHashReader.Next(), binaryHashReader.Err())
+		digests, err = binaryHashReader.SubtreeDigests()
+		require.NoError(t, err, "Something went wrong executing binaryHashReader.SubtreeDigests()")
+		assert.Equal(t, readerDigests[i], digests,
+			"BinaryHashReader subtree digests did not match HashReader subtree digests")
+	}
+	require.NoError(t, reader.Err())
+
+	return readerDigests
+}
+
+func TestSubtreeDigests(t *testing.T) {
+	text := `{header:{id:1}, payload:{kind:a::"k", body:{x:[1, 2], y:"z"}}}
+		{payload:{body:null}}
+		{payload:[{body:1}]}
+		{header:{id:2}}`
+
+	digests := subtreeDigests(t, text, WithSubtrees("payload.body"))
+	assert.Equal(t, [][]SubtreeDigest{
+		{{"payload.body", sha256Sum(t, `{x:[1, 2], y:"z"}`)}},
+		{{"payload.body", sha256Sum(t, `null`)}},
+		nil,
+		nil,
+	}, digests)
+}
+
+func TestSubtreeDigestsOfMatchingPaths(t *testing.T) {
+	testCases := []struct {
+		text     string
+		paths    []string
+		expected []SubtreeDigest
+	}{
+		{`a::[1, {b:2}]`, []string{""}, []SubtreeDigest{{"", sha256Sum(t, `a::[1, {b:2}]`)}}},
+		{`{items:[{sku:"a"}, 3, (4)]}`, []string{"items[*]"}, []SubtreeDigest{
+			{"items[0]", sha256Sum(t, `{sku:"a"}`)},
+			{"items[1]", sha256Sum(t, `3`)},
+			{"items[2]", sha256Sum(t, `(4)`)},
+		}},
+		{`{items:[{sku:"a"}, {sku:"b"}]}`, []string{"items[1].sku"}, []SubtreeDigest{{"items[1].sku", sha256Sum(t, `"b"`)}}},
+		{`{a:1, 'first name':"n"}`, []string{"*"}, []SubtreeDigest{
+			{"a", sha256Sum(t, `1`)},
+			{"'first name'", sha256Sum(t, `"n"`)},
+		}},
+		{`{a:{b:{c:1}}}`, []string{"a.b.c", "a", "a.b"}, []SubtreeDigest{
+			{"a", sha256Sum(t, `{b:{c:1}}`)},
+			{"a.b", sha256Sum(t, `{c:1}`)},
+			{"a.b.c", sha256Sum(t, `1`)},
+		}},
+		{`{a:1, a:[2]}`, []string{"a"}, []SubtreeDigest{{"a", sha256Sum(t, `1`)}, {"a", sha256Sum(t, `[2]`)}}},
+	}
+
+	for _, tc := range testCases {
+		digests := subtreeDigests(t, tc.text, WithSubtrees(tc.paths...))
+		assert.Equal(t, [][]SubtreeDigest{tc.expected}, digests, "Subtree digests of %s did not match", tc.text)
+	}
+}
+
+func TestSubtreeDigestsWithOptions(t *testing.T) {
+	// Paths in the options are matched against paths from the top-level value.
+	opts := []Option{WithExcludedPaths("payload.body.x"), WithUnorderedPaths("payload.body.y")}
+	digests := subtreeDigests(t, `{payload:{body:{x:1, y:[1, 2]}}}`, append(opts, WithSubtrees("payload.body"))...)
+	assert.Equal(t, [][]SubtreeDigest{{{"payload.body", hashValues(t, `{y:[2, 1]}`, WithUnorderedPaths("y"))[0]}}},
+		digests)
+
+	// An excluded value has no digest.
+	digests = subtreeDigests(t, `{a:{b:1}, c:2}`, WithExcludedFields("a", "c"), WithSubtrees("a", "c"))
+	assert.Equal(t, [][]SubtreeDigest{nil}, digests)
+
+	// The digests of subtrees don't count towards the limits twice.
+	digests = subtreeDigests(t, `{a:[[1]]}`, WithLimits(Limits{MaxDepth: 3, MaxValueBytes: 2}), WithSubtrees("a", "a[0]"))
+	assert.Equal(t, [][]SubtreeDigest{{{"a", sha256Sum(t, `[[1]]`)}, {"a[0]", sha256Sum(t, `[1]`)}}}, digests)
+}
+
+func TestSubtreeDigestsWithTransformers(t *testing.T) {
+	// A transformer that isn't idempotent shows whether scalars are transformed more than once.
+	var calls int
+	exclaim := func(path string, ionType ion.Type, value interface{}) (ion.Type, interface{}, bool, error) {
+		calls++
+		if s, ok := value.(string); ok {
+			return ionType, s + "!", false, nil
+		}
+		return ionType, value, path == "a.b[1]", nil
+	}
+
+	hr, err := NewHashReader(ion.NewReaderString(`{a:{b:["x", 1, "y"]}, c:"z"}`), NewCryptoHasherProvider(SHA256),
+		WithTransformer(exclaim), WithSubtrees("a", "a.b", "a.b[0]"))
+	require.NoError(t, err, "Expected NewHashReader() to successfully create a HashReader")
+	require.True(t, hr.Next())
+	require.False(t, hr.Next())
+	require.NoError(t, hr.Err(), "Something went wrong executing hr.Next()")
+	assert.Equal(t, 4, calls)
+
+	digests, err := hr.SubtreeDigests()
+	require.NoError(t, err, "Something went wrong executing hr.SubtreeDigests()")
+	require.Len(t, digests, 3)
+	assert.Equal(t, SubtreeDigest{"a", hashValues(t, `{b:["x!", "y!"]}`)[0]}, digests[0])
+	assert.Equal(t, SubtreeDigest{"a.b", hashValues(t, `["x!", "y!"]`)[0]}, digests[1])
+	assert.Equal(t, SubtreeDigest{"a.b[0]", hashValues(t, `"x!"`)[0]}, digests[2])
+}
+
+func TestSubtreeDigestsInvalid(t *testing.T) {
+	_, err := NewHashReader(ion.NewReaderString("{}"), NewCryptoHasherProvider(SHA256), WithSubtrees("a..b"))
+	assert.True(t, errors.Is(err, ErrInvalidArgument), "Expected an InvalidArgumentError")
+}
+
+func TestSubtreeDigestsState(t *testing.T) {
+	ionHashReader, err := NewHashReader(ion.NewReaderString(`{a:1}`), NewCryptoHasherProvider(SHA256),
+		WithSubtrees("a"))
+	require.NoError(t, err, "Expected NewHashReader() to successfully create a HashReader")
+
+	digests, err := ionHashReader.SubtreeDigests()
+	require.NoError(t, err)
+	assert.Nil(t, digests)
+
+	require.True(t, ionHashReader.Next())
+	require.NoError(t, ionHashReader.StepIn())
+	_, err = ionHashReader.SubtreeDigests()
+	assert.IsType(t, &InvalidOperationError{}, err)
+	require.NoError(t, ionHashReader.StepOut())
+}
@@ -401,7 +401,7 @@ func writeFromReaderToWriter(t *testing.T, reader ion.Reader, writer ion.Writer,
 	}
 }
 
-func writeToWriters(t *testing.T, reader ion.Reader, writers ...ion.Writer) {
+func writeToWriters(t testing.TB, reader ion.Reader, writers ...ion.Writer) {
 	ionType := reader.Type()
 
 	annotations, err := reader.Annotations()
@@ -567,7 +567,7 @@ func writeToWriters(t *testing.T, reader ion.Reader, writers ...ion.Writer) {
 	}
 }
 
-func readSexpAndAppendToList(t *testing.T, reader ion.Reader) []byte {
+func readSexpAndAppendToList(t testing.TB, reader ion.Reader) []byte {
 	require.NoError(t, reader.StepIn())
 	updateBytes := []byte{}
 	for reader.Next() {
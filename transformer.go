@@ -0,0 +1,155 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+import (
+	"math"
+	"math/big"
+
+	"github.com/amzn/ion-go/ion"
+)
+
+// A Transformer returns the scalar that is hashed in place of a scalar, given the path of the
+// scalar, written like the paths of HashErrors, and its type and value. It returns ionType and value
+// if the scalar is not changed, or drop if the scalar is hashed as though it were absent.
+//
+// The value of a null is nil, and otherwise it is a bool, an int64 or a *big.Int, a float64, an
+// *ion.Decimal, an ion.Timestamp, a string, an ion.SymbolToken, or a []byte for blobs and clobs.
+// A returned value may be any of these, or nil for a null of the returned type, but it must suit the
+// returned type.
+type Transformer func(path string, ionType ion.Type, value interface{}) (
+	newType ion.Type, newValue interface{}, drop bool, err error)
+
+// transformedValue is a hashValue whose type and value are replaced with those returned by
+// Transformers. It is reused between values.
+type transformedValue struct {
+	hashValue
+	ionType ion.Type
+	val     interface{}
+}
+
+func (tv *transformedValue) Type() ion.Type {
+	return tv.ionType
+}
+
+func (tv *transformedValue) IsNull() bool {
+	return tv.val == nil || tv.ionType == ion.NullType
+}
+
+func (tv *transformedValue) value() (interface{}, error) {
+	return tv.val, nil
+}
+
+// transform returns ionValue as the hasher's Transformers change it, and whether they drop it.
+func (h *hasher) transform(ionValue hashValue) (hashValue, bool, error) {
+	if h.transformedBy != nil {
+		// The scalar has just been transformed by the hasher of the top-level value.
+		if h.transformedBy.dropped {
+			return nil, true, nil
+		}
+
+		h.transformed.hashValue = ionValue
+		h.transformed.ionType = h.transformedBy.transformed.ionType
+		h.transformed.val = h.transformedBy.transformed.val
+		return &h.transformed, false, nil
+	}
+
+	if len(h.transformers) == 0 {
+		return ionValue, false, nil
+	}
+
+	ionType := ionValue.Type()
+	var val interface{}
+	if !ionValue.IsNull() {
+		var err error
+		val, err = ionValue.value()
+		if err != nil {
+			return nil, false, err
+		}
+
+		val = plainValue(ionType, val)
+	}
+
+	path := h.pathString(len(h.path))
+	for _, transformer := range h.transformers {
+		var drop bool
+		var err error
+		ionType, val, drop, err = transformer(path, ionType, val)
+		if err != nil || drop {
+			h.dropped = drop
+			return nil, drop, err
+		}
+	}
+
+	h.dropped = false
+	h.transformed.hashValue = ionValue
+	h.transformed.ionType = ionType
+	h.transformed.val = val
+	return &h.transformed, false, nil
+}
+
+// plainValue returns the value of a scalar of the given type as it is given to Transformers, rather
+// than any of the other forms that appendScalar accepts.
+func plainValue(ionType ion.Type, val interface{}) interface{} {
+	switch v := val.(type) {
+	case *bool:
+		return *v
+	case int:
+		return int64(v)
+	case *int:
+		return int64(*v)
+	case *int64:
+		return *v
+	case int32:
+		return int64(v)
+	case *int32:
+		return int64(*v)
+	case uint32:
+		return int64(v)
+	case *uint32:
+		return int64(*v)
+	case uint64:
+		return plainUint(v)
+	case *uint64:
+		return plainUint(*v)
+	case *float64:
+		return *v
+	case float32:
+		return float64(v)
+	case *float32:
+		return float64(*v)
+	case *ion.Timestamp:
+		return *v
+	case *string:
+		return plainValue(ionType, *v)
+	case string:
+		if ionType == ion.SymbolType {
+			return ion.NewSymbolTokenFromString(v)
+		}
+	case *ion.SymbolToken:
+		return *v
+	}
+
+	return val
+}
+
+func plainUint(v uint64) interface{} {
+	if v > math.MaxInt64 {
+		return new(big.Int).SetUint64(v)
+	}
+
+	return int64(v)
+}
@@ -0,0 +1,199 @@
+/*
+ * Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License").
+ * You may not use this file except in compliance with the License.
+ * A copy of the License is located at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * or in the "license" file accompanying this file. This file is distributed
+ * on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+ * express or implied. See the License for the specific language governing
+ * permissions and limitations under the License.
+ */
+
+package ionhash
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/amzn/ion-go/ion"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// assertTransformedEqual asserts that text hashes with opts as expected hashes without them.
+func assertTransformedEqual(t *testing.T, expected, text string, opts ...Option) {
+	assert.Equal(t, hashTextValues(t, expected), hashTextValues(t, text, opts...),
+		"Expected %s to hash as %s", text, expected)
+}
+
+func TestWithTransformer(t *testing.T) {
+	trim := WithTransformer(func(path string, ionType ion.Type, value interface{}) (ion.Type, interface{}, bool, error) {
+		if s, ok := value.(string); ok && ionType == ion.StringType {
+			return ionType, strings.TrimSpace(s), false, nil
+		}
+		return ionType, value, false, nil
+	})
+	assertTransformedEqual(t, `"a" ["b", c] {d:"e"}`, `"  a" [" b ", c] {d:"e\n"}`, trim)
+
+	lowerEmails := WithTransformer(func(path string, ionType ion.Type, value interface{}) (ion.Type, interface{}, bool, error) {
+		if path == "customer.email" && value != nil {
+			return ionType, strings.ToLower(value.(string)), false, nil
+		}
+		return ionType, value, false, nil
+	})
+	assertTransformedEqual(t, `{customer:{email:"a@b.com"}, email:"C@D.com"}`,
+		`{customer:{email:"A@B.com"}, email:"C@D.com"}`, lowerEmails)
+
+	roundPrices := WithTransformer(func(path string, ionType ion.Type, value interface{}) (ion.Type, interface{}, bool, error) {
+		if strings.HasPrefix(path, "lines[") && strings.HasSuffix(path, "].price") {
+			return ionType, math.Round(value.(float64)*100) / 100, false, nil
+		}
+		return ionType, value, false, nil
+	})
+	assertTransformedEqual(t, `{lines:[{price:1.25e0}, {price:2e0}], total:3.2501e0}`,
+		`{lines:[{price:1.2500001e0}, {price:1.999999e0}], total:3.2501e0}`, roundPrices)
+}
+
+func TestWithTransformerChangesTypes(t *testing.T) {
+	toString := WithTransformer(func(path string, ionType ion.Type, value interface{}) (ion.Type, interface{}, bool, error) {
+		switch v := value.(type) {
+		case int64:
+			return ion.StringType, big.NewInt(v).String(), false, nil
+		case *big.Int:
+			return ion.StringType, v.String(), false, nil
+		}
+		return ionType, value, false, nil
+	})
+	assertTransformedEqual(t, `"5" ["-7", "123456789012345678901234567890"] {a:"1"}`,
+		`5 [-7, 123456789012345678901234567890] {a:1}`, toString)
+
+	toNull := WithTransformer(func(path string, ionType ion.Type, value interface{}) (ion.Type, interface{}, bool, error) {
+		if ionType == ion.SymbolType && value.(ion.SymbolToken).Text != nil && *value.(ion.SymbolToken).Text == "none" {
+			return ion.NullType, nil, false, nil
+		}
+		return ionType, value, false, nil
+	})
+	assertTransformedEqual(t, `null [a, null] {b:null}`, `none [a, none] {b:none}`, toNull)
+
+	nullToZero := WithTransformer(func(path string, ionType ion.Type, value interface{}) (ion.Type, interface{}, bool, error) {
+		if value == nil {
+			return ion.IntType, int64(0), false, nil
+		}
+		return ionType, value, false, nil
+	})
+	assertTransformedEqual(t, `0 [0, 1] {a:0}`, `null [null.int, 1] {a:null.string}`, nullToZero)
+}
+
+func TestWithTransformerDropsValues(t *testing.T) {
+	dropTemporary := WithTransformer(func(path string, ionType ion.Type, value interface{}) (ion.Type, interface{}, bool, error) {
+		return ionType, value, strings.HasSuffix(path, "tmp"), nil
+	})
+	assertTransformedEqual(t, `{a:1} [{b:2}] {c:[]}`, `{a:1, tmp:2} [{b:2, tmp:null}] {c:[], tmp:"x"}`, dropTemporary)
+
+	// A container of values that are all dropped is still hashed.
+	dropInts := WithTransformer(func(path string, ionType ion.Type, value interface{}) (ion.Type, interface{}, bool, error) {
+		return ionType, value, ionType == ion.IntType, nil
+	})
+	assertTransformedEqual(t, `[] {} [a]`, `[1, 2] {a:1} [a, 3]`, dropInts)
+}
+
+func TestWithTransformerValues(t *testing.T) {
+	text := `true 1 123456789012345678901234567890 1.5e0 1.50 2020-01-01T "s" sym {{Y2xvYg==}} {{ "clob" }} null.int`
+
+	var values []interface{}
+	record := WithTransformer(func(path string, ionType ion.Type, value interface{}) (ion.Type, interface{}, bool, error) {
+		values = append(values, value)
+		return ionType, value, false, nil
+	})
+
+	bigInt, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	expected := []interface{}{
+		true, int64(1), bigInt, 1.5, ion.MustParseDecimal("1.50"), ion.MustParseTimestamp("2020-01-01T"), "s",
+		ion.NewSymbolTokenFromString("sym"), []byte("clob"), []byte("clob"), nil,
+	}
+
+	assert.Len(t, hashTextValues(t, text, record), len(expected))
+
+	// Both the HashReader and the HashWriter give the transformer the same values.
+	require.Len(t, values, 2*len(expected))
+	for i, value := range values {
+		expectedValue := expected[i%len(expected)]
+		switch v := value.(type) {
+		case *big.Int:
+			assert.Equal(t, 0, v.Cmp(expectedValue.(*big.Int)))
+		case *ion.Decimal:
+			assert.True(t, v.Equal(expectedValue.(*ion.Decimal)), "Expected %v to equal %v", v, expectedValue)
+		case ion.Timestamp:
+			assert.True(t, v.Equal(expectedValue.(ion.Timestamp)), "Expected %v to equal %v", v, expectedValue)
+		case ion.SymbolToken:
+			assert.Equal(t, *expectedValue.(ion.SymbolToken).Text, *v.Text)
+		default:
+			assert.Equal(t, expectedValue, value)
+		}
+	}
+}
+
+func TestWithTransformerSeesOriginalData(t *testing.T) {
+	upper := WithTransformer(func(path string, ionType ion.Type, value interface{}) (ion.Type, interface{}, bool, error) {
+		if s, ok := value.(string); ok {
+			return ionType, strings.ToUpper(s), false, nil
+		}
+		return ionType, value, false, nil
+	})
+
+	ionHashReader, err := NewHashReader(ion.NewReaderString(`"abc"`), NewCryptoHasherProvider(SHA256), upper)
+	require.NoError(t, err, "Expected NewHashReader() to successfully create a HashReader")
+	require.True(t, ionHashReader.Next())
+
+	val, err := ionHashReader.StringValue()
+	require.NoError(t, err)
+	assert.Equal(t, "abc", *val)
+
+	var buf strings.Builder
+	ionHashWriter, err := NewHashWriter(ion.NewTextWriter(&buf), NewCryptoHasherProvider(SHA256), upper)
+	require.NoError(t, err, "Expected NewHashWriter() to successfully create a HashWriter")
+	require.NoError(t, ionHashWriter.WriteString("abc"))
+	require.NoError(t, ionHashWriter.Finish())
+	assert.Equal(t, `"abc"`, strings.TrimSpace(buf.String()))
+}
+
+func TestWithTransformerErrors(t *testing.T) {
+	errBad := errors.New("bad value")
+	failing := WithTransformer(func(path string, ionType ion.Type, value interface{}) (ion.Type, interface{}, bool, error) {
+		if path == "a[1]" {
+			return ionType, value, false, errBad
+		}
+		return ionType, value, false, nil
+	})
+
+	ionHashReader, err := NewHashReader(ion.NewReaderString(`{a:[1, 2]}`), NewCryptoHasherProvider(SHA256), failing)
+	require.NoError(t, err, "Expected NewHashReader() to successfully create a HashReader")
+	for ionHashReader.Next() {
+	}
+
+	var hashError *HashError
+	require.True(t, errors.As(ionHashReader.Err(), &hashError), "Expected a HashError")
+	assert.Equal(t, "a[1]", hashError.Path)
+	assert.True(t, errors.Is(ionHashReader.Err(), errBad))
+
+	// A transformer must return a value that suits its type.
+	mismatched := WithTransformer(func(path string, ionType ion.Type, value interface{}) (ion.Type, interface{}, bool, error) {
+		return ion.IntType, "1", false, nil
+	})
+	ionHashReader, err = NewHashReader(ion.NewReaderString(`"1"`), NewCryptoHasherProvider(SHA256), mismatched)
+	require.NoError(t, err, "Expected NewHashReader() to successfully create a HashReader")
+	for ionHashReader.Next() {
+	}
+	assert.True(t, errors.Is(ionHashReader.Err(), ErrInvalidArgument), "Expected an InvalidArgumentError")
+
+	// A BinaryHashReader doesn't support transformers.
+	_, err = HashBinary(toBinary(t, `1`), NewCryptoHasherProvider(SHA256), failing)
+	assert.True(t, errors.Is(err, ErrInvalidArgument), "Expected an InvalidArgumentError")
+}